@@ -6,12 +6,18 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/redhat-developer/devconsole-api/pkg/apis"
 	"github.com/redhat-developer/devconsole-git/pkg/controller"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository/generic"
+	"github.com/redhat-developer/devconsole-git/pkg/push"
+	"github.com/redhat-developer/devconsole-git/pkg/webhook"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	"github.com/operator-framework/operator-sdk/pkg/leader"
@@ -19,6 +25,7 @@ import (
 	"github.com/operator-framework/operator-sdk/pkg/metrics"
 	sdkVersion "github.com/operator-framework/operator-sdk/version"
 	"github.com/spf13/pflag"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
@@ -32,6 +39,22 @@ var (
 )
 var log = logf.Log.WithName("cmd")
 
+var (
+	webhookPort     = pflag.Int32("webhook-port", 9876, "port the admission webhook server listens on")
+	webhookCertDir  = pflag.String("webhook-cert-dir", "/tmp/git-operator-webhook-certs", "directory the admission webhook certificates are stored in")
+	disableWebhooks = pflag.Bool("disable-webhooks", false, "disables the admission webhook server")
+
+	pushReceiverPort    = pflag.Int32("push-receiver-port", 8686, "port the receiver of push events sent by repository webhooks listens on")
+	disablePushReceiver = pflag.Bool("disable-push-receiver", false, "disables the receiver of push events")
+
+	cloneCacheDir     = pflag.String("clone-cache-dir", "/tmp/git-operator-clone-cache", "directory the repositories fetched by the operator are cached in")
+	cloneCacheSizeMB  = pflag.Int64("clone-cache-size-mb", 1024, "maximal size of the cache of repositories in megabytes")
+	disableCloneCache = pflag.Bool("disable-clone-cache", false, "fetches the repositories into memory instead of the cache")
+
+	disabledGitProviders = pflag.StringSlice("disabled-git-providers", nil, "names of the git service providers that should not be used (e.g. bitbucket,gitea)")
+	gitProviderHosts     = pflag.StringSlice("git-provider-hosts", nil, "hosts of self-hosted git services mapped to flavors of their providers (e.g. git.example.com=gitlab)")
+)
+
 func printVersion() {
 	log.Info(fmt.Sprintf("Go Version: %s", runtime.Version()))
 	log.Info(fmt.Sprintf("Go OS/Arch: %s/%s", runtime.GOOS, runtime.GOARCH))
@@ -101,12 +124,59 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup the reader of the secrets holding refreshable OAuth tokens - they have to be read bypassing the cache
+	apiReader, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+	git.UseAPIReader(apiReader)
+
+	// Setup the cache of repositories shared by all reconciles
+	if !*disableCloneCache {
+		cache, err := generic.NewCloneCache(*cloneCacheDir, *cloneCacheSizeMB*1024*1024)
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+		generic.UseCloneCache(cache)
+	}
+
+	// Setup the providers of git services used for the repositories
+	if err := configureGitProviders(repository.DefaultRegistry()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	// Setup admission webhooks - they can run only inside of the cluster as the api server has to reach them
+	if !*disableWebhooks {
+		operatorNamespace, err := k8sutil.GetOperatorNamespace()
+		if err != nil {
+			log.Info("Skipping admission webhooks as the operator namespace is not available", "error", err.Error())
+		} else if err := webhook.AddToManager(mgr, webhook.Options{
+			Port:      *webhookPort,
+			CertDir:   *webhookCertDir,
+			Namespace: operatorNamespace,
+		}); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	// Setup receiver of push events sent by webhooks registered in the repositories
+	if !*disablePushReceiver {
+		if err := push.AddToManager(mgr, push.Options{Port: *pushReceiverPort}); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Create Service object to expose the metrics port.
 	_, err = metrics.ExposeMetricsPort(ctx, metricsPort)
 	if err != nil {
@@ -121,3 +191,20 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// configureGitProviders disables the providers and maps the hosts to the flavors as set by the flags
+func configureGitProviders(registry *repository.Registry) error {
+	if err := registry.Disable(*disabledGitProviders...); err != nil {
+		return err
+	}
+	for _, mapping := range *gitProviderHosts {
+		hostAndFlavor := strings.SplitN(mapping, "=", 2)
+		if len(hostAndFlavor) != 2 || hostAndFlavor[0] == "" {
+			return fmt.Errorf("invalid mapping %q of a host to a flavor, expected <host>=<flavor>", mapping)
+		}
+		if err := registry.AddHost(hostAndFlavor[0], hostAndFlavor[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
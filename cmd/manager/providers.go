@@ -0,0 +1,7 @@
+package main
+
+import (
+	// registers the git service providers built into the operator - downstream builds can register additional
+	// providers by importing their packages from another file of this package
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
+)
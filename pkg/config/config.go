@@ -0,0 +1,132 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConfigMapName is a name of the ConfigMap that holds the cluster-wide configuration of the operator.
+	// The ConfigMap is expected to be in the namespace the operator is running in.
+	ConfigMapName = "git-operator-config"
+	// OperatorNamespaceEnvVar is a name of the env var containing the namespace the operator is running in
+	OperatorNamespaceEnvVar = "OPERATOR_NAMESPACE"
+
+	// KnownHostsKey is a key of known_hosts entries used for verification of ssh host keys. The same key is used
+	// in the secrets of GitSources.
+	KnownHostsKey = "known_hosts"
+	// CABundleKey is a key of PEM encoded certificates of authorities trusted in addition to the system ones.
+	// The same key is used in the secrets of GitSources.
+	CABundleKey = "ca.crt"
+	// InsecureSkipTLSVerifyKey is a key that turns off verification of TLS certificates when set to "true".
+	// The same key is used in the secrets of GitSources.
+	InsecureSkipTLSVerifyKey = "insecure-skip-tls-verify"
+	// HTTPProxyKey is a key of URL of the proxy used for http connections
+	HTTPProxyKey = "http-proxy"
+	// HTTPSProxyKey is a key of URL of the proxy used for https and ssh connections
+	HTTPSProxyKey = "https-proxy"
+	// NoProxyKey is a key of comma-separated list of hosts, domains and CIDRs that should be accessed directly
+	NoProxyKey = "no-proxy"
+	// OperationTimeoutKey is a key of the maximal duration (eg. "30s") of an operation talking to a git server
+	OperationTimeoutKey = "operation-timeout"
+	// WebhookURLKey is a key of the URL the push webhooks registered for GitSources send the events to
+	WebhookURLKey = "webhook-url"
+)
+
+// Cluster holds the cluster-wide configuration of the operator
+type Cluster struct {
+	data map[string]string
+}
+
+// NewCluster returns an instance of Cluster configuration containing the given data
+func NewCluster(data map[string]string) *Cluster {
+	return &Cluster{data: data}
+}
+
+// LoadCluster retrieves the cluster-wide configuration using the given client. If the operator namespace is not known
+// or the ConfigMap doesn't exist, then an empty configuration is returned.
+func LoadCluster(cl client.Client) (*Cluster, error) {
+	namespace := os.Getenv(OperatorNamespaceEnvVar)
+	if namespace == "" {
+		return NewCluster(nil), nil
+	}
+	configMap := &corev1.ConfigMap{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: ConfigMapName}, configMap)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return NewCluster(nil), nil
+		}
+		return nil, err
+	}
+	return NewCluster(configMap.Data), nil
+}
+
+// KnownHosts returns cluster-wide known_hosts entries
+func (c *Cluster) KnownHosts() []byte {
+	return []byte(c.data[KnownHostsKey])
+}
+
+// CABundle returns cluster-wide PEM encoded certificates of trusted authorities
+func (c *Cluster) CABundle() []byte {
+	return []byte(c.data[CABundleKey])
+}
+
+// InsecureSkipTLSVerify returns true if the verification of TLS certificates should be turned off for all connections
+func (c *Cluster) InsecureSkipTLSVerify() bool {
+	return c.data[InsecureSkipTLSVerifyKey] == "true"
+}
+
+// HTTPProxy returns URL of the proxy used for http connections. If not set in the ConfigMap,
+// then the value of HTTP_PROXY env var is returned.
+func (c *Cluster) HTTPProxy() string {
+	return c.valueOrEnv(HTTPProxyKey, "HTTP_PROXY")
+}
+
+// HTTPSProxy returns URL of the proxy used for https and ssh connections. If not set in the ConfigMap,
+// then the value of HTTPS_PROXY env var is returned.
+func (c *Cluster) HTTPSProxy() string {
+	return c.valueOrEnv(HTTPSProxyKey, "HTTPS_PROXY")
+}
+
+// NoProxy returns comma-separated list of hosts that should be accessed directly. If not set in the ConfigMap,
+// then the value of NO_PROXY env var is returned.
+func (c *Cluster) NoProxy() string {
+	return c.valueOrEnv(NoProxyKey, "NO_PROXY")
+}
+
+// OperationTimeout returns the maximal duration of an operation talking to a git server (such as a validation
+// of a GitSource or an analysis). Returns zero if not set.
+func (c *Cluster) OperationTimeout() (time.Duration, error) {
+	value := strings.TrimSpace(c.data[OperationTimeoutKey])
+	if value == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value of %s: %s", OperationTimeoutKey, err)
+	}
+	return timeout, nil
+}
+
+// WebhookURL returns the URL the push webhooks registered for GitSources send the events to
+func (c *Cluster) WebhookURL() string {
+	return strings.TrimSpace(c.data[WebhookURLKey])
+}
+
+func (c *Cluster) valueOrEnv(key, envVar string) string {
+	if value, ok := c.data[key]; ok {
+		return value
+	}
+	if value := os.Getenv(envVar); value != "" {
+		return value
+	}
+	return os.Getenv(strings.ToLower(envVar))
+}
@@ -0,0 +1,120 @@
+package config_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/config"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLoadClusterFromConfigMap(t *testing.T) {
+	// given
+	defer setOperatorNamespace(test.Namespace)()
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: config.ConfigMapName, Namespace: test.Namespace},
+		Data: map[string]string{
+			config.KnownHostsKey:            "github.com ssh-rsa AAAA",
+			config.CABundleKey:              "-----BEGIN CERTIFICATE-----",
+			config.InsecureSkipTLSVerifyKey: "true",
+		},
+	}
+	cl, _ := test.PrepareClient(test.RegisterGvkObject(corev1.SchemeGroupVersion, configMap))
+
+	// when
+	cluster, err := config.LoadCluster(cl)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "github.com ssh-rsa AAAA", string(cluster.KnownHosts()))
+	assert.Equal(t, "-----BEGIN CERTIFICATE-----", string(cluster.CABundle()))
+	assert.True(t, cluster.InsecureSkipTLSVerify())
+}
+
+func TestLoadClusterWhenConfigMapIsMissing(t *testing.T) {
+	// given
+	defer setOperatorNamespace(test.Namespace)()
+	cl, _ := test.PrepareClient()
+
+	// when
+	cluster, err := config.LoadCluster(cl)
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, cluster.KnownHosts())
+	assert.Empty(t, cluster.CABundle())
+	assert.False(t, cluster.InsecureSkipTLSVerify())
+}
+
+func TestLoadClusterWhenNamespaceIsNotSet(t *testing.T) {
+	// given
+	defer setOperatorNamespace("")()
+	cl, _ := test.PrepareClient()
+
+	// when
+	cluster, err := config.LoadCluster(cl)
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, cluster.KnownHosts())
+	assert.Empty(t, cluster.CABundle())
+	assert.False(t, cluster.InsecureSkipTLSVerify())
+}
+
+func TestClusterProxyFallsBackToEnv(t *testing.T) {
+	// given
+	defer setEnv("HTTPS_PROXY", "http://env-proxy:3128")()
+	defer setEnv("NO_PROXY", "internal.example.com")()
+
+	// when
+	fromEnv := config.NewCluster(nil)
+	fromConfigMap := config.NewCluster(map[string]string{
+		config.HTTPSProxyKey: "http://cluster-proxy:3128",
+		config.NoProxyKey:    ""})
+
+	// then
+	assert.Equal(t, "http://env-proxy:3128", fromEnv.HTTPSProxy())
+	assert.Equal(t, "internal.example.com", fromEnv.NoProxy())
+	assert.Equal(t, "http://cluster-proxy:3128", fromConfigMap.HTTPSProxy())
+	assert.Empty(t, fromConfigMap.NoProxy())
+}
+
+func TestClusterOperationTimeout(t *testing.T) {
+	// given
+	notSet := config.NewCluster(nil)
+	valid := config.NewCluster(map[string]string{config.OperationTimeoutKey: "30s"})
+	invalid := config.NewCluster(map[string]string{config.OperationTimeoutKey: "thirty seconds"})
+
+	// when
+	notSetTimeout, notSetErr := notSet.OperationTimeout()
+	validTimeout, validErr := valid.OperationTimeout()
+	_, invalidErr := invalid.OperationTimeout()
+
+	// then
+	require.NoError(t, notSetErr)
+	assert.Zero(t, notSetTimeout)
+	require.NoError(t, validErr)
+	assert.Equal(t, 30*time.Second, validTimeout)
+	assert.Error(t, invalidErr)
+}
+
+func setOperatorNamespace(namespace string) func() {
+	return setEnv(config.OperatorNamespaceEnvVar, namespace)
+}
+
+func setEnv(envVar, value string) func() {
+	original, present := os.LookupEnv(envVar)
+	os.Setenv(envVar, value)
+	return func() {
+		if present {
+			os.Setenv(envVar, original)
+		} else {
+			os.Unsetenv(envVar)
+		}
+	}
+}
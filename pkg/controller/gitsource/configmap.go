@@ -0,0 +1,47 @@
+package gitsource
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// applyConfigMap creates the config map with the given name and data owned by the GitSource,
+// or replaces the data of the config map if it already exists. It fails if the existing config map
+// is not controlled by the GitSource - e.g. an unrelated config map that happens to have the same name.
+func applyConfigMap(cl client.Client, scheme *runtime.Scheme, gitSource *v1alpha1.GitSource, name string,
+	data map[string]string) error {
+
+	configMap := &corev1.ConfigMap{}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: name}
+	err := cl.Get(context.TODO(), key, configMap)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+			},
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(gitSource, configMap, scheme); err != nil {
+			return err
+		}
+		return cl.Create(context.TODO(), configMap)
+	}
+	if !metav1.IsControlledBy(configMap, gitSource) {
+		return fmt.Errorf("the config map %s is not controlled by the GitSource %s", name, gitSource.Name)
+	}
+	configMap.Data = data
+	return cl.Update(context.TODO(), configMap)
+}
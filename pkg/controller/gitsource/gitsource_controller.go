@@ -3,11 +3,14 @@ package gitsource
 import (
 	"context"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/controller/status"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/connection"
 	gslog "github.com/redhat-developer/devconsole-git/pkg/log"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -15,10 +18,22 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sync"
+	"time"
 )
 
 var log = logf.Log.WithName("controller_gitsource")
 
+const (
+	// minRetryAfter is the minimal delay of a validation repeated because of an exhausted rate limit
+	minRetryAfter = 5 * time.Second
+	// resyncPeriod is the period the published branches, tags and metadata of a valid GitSource are refreshed in -
+	// the repository changes without any change of the GitSource
+	resyncPeriod = 10 * time.Minute
+	// publishRetryAfter is the delay of publishing repeated because of a failure
+	publishRetryAfter = time.Minute
+)
+
 // Add creates a new GitSource Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -55,6 +70,18 @@ type ReconcileGitSource struct {
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
 	scheme *runtime.Scheme
+
+	// publications holds the last successful publication of each GitSource, so the reconciles triggered
+	// by the updates of the GitSource itself don't publish again before the resync period elapses
+	mutex        sync.Mutex
+	publications map[types.NamespacedName]publication
+}
+
+// publication is the time the branches, tags and metadata were published at for the given spec and secret
+type publication struct {
+	spec       v1alpha1.GitSourceSpec
+	secretName string
+	at         time.Time
 }
 
 // Reconcile reads that state of the cluster for a GitSource object and makes changes based on the state read
@@ -75,6 +102,7 @@ func (r *ReconcileGitSource) Reconcile(request reconcile.Request) (reconcile.Res
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			r.forgetPublication(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -82,10 +110,16 @@ func (r *ReconcileGitSource) Reconcile(request reconcile.Request) (reconcile.Res
 	}
 	gitSourceLogger := gslog.LogWithGSValues(reqLogger, gitSource)
 
-	isDirty := updateStatus(gitSourceLogger, r.client, request.Namespace, gitSource)
+	// the secret provider is created once, so all steps use the same (matched) secret
+	secretProvider, secretErr := git.NewGitSecretProvider(r.client, request.Namespace, gitSource)
+	isDirty, requeueAfter := updateStatus(gitSourceLogger, r.client, request.Namespace, gitSource, secretProvider, secretErr)
 
 	if isDirty {
-		err = r.client.Update(context.TODO(), gitSource)
+		computedStatus := gitSource.Status
+		err = status.UpdateWithRetry(r.client, request.NamespacedName, gitSource, status.Status, func(obj runtime.Object) error {
+			obj.(*v1alpha1.GitSource).Status = computedStatus
+			return nil
+		})
 		if err != nil {
 			gitSourceLogger.Error(err, "Error updating GitSource object")
 			if errors.IsNotFound(err) {
@@ -98,38 +132,164 @@ func (r *ReconcileGitSource) Reconcile(request reconcile.Request) (reconcile.Res
 			return reconcile.Result{}, err
 		}
 	}
+	if requeueAfter > 0 {
+		gitSourceLogger.Info("Rate limit of the git server exceeded, the validation will be repeated", "after", requeueAfter)
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if gitSource.Status.Connection.State == v1alpha1.OK {
+		if secretErr != nil {
+			gitSourceLogger.Error(secretErr, "Unable to publish branches, tags and metadata of the repository")
+			return reconcile.Result{RequeueAfter: publishRetryAfter}, nil
+		}
+		return r.publish(gitSourceLogger, gitSource, secretProvider), nil
+	}
 	return reconcile.Result{}, nil
 }
 
-func updateStatus(log *gslog.GitSourceLogger, client client.Client, namespace string, gitSource *v1alpha1.GitSource) (isDirty bool) {
+// publish publishes the branches, tags and metadata of the repository of a valid GitSource and requeues
+// the GitSource so they are refreshed. They are only offered to the user, so a failure doesn't change the status
+// of the GitSource - publishing is just repeated sooner. They are not published again before the resync period
+// elapses unless the spec of the GitSource or the used secret changes.
+func (r *ReconcileGitSource) publish(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) reconcile.Result {
 
-	if gitSource.Status.Connection.State != "" {
-		return false
+	current := publication{spec: gitSource.Spec, secretName: secretProvider.SecretName(), at: time.Now()}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: gitSource.Name}
+	if wait := r.untilNextPublication(key, current); wait > 0 {
+		return reconcile.Result{RequeueAfter: wait}
 	}
-	if gitSource.Status.State == "" {
-		gitSource.Status.State = v1alpha1.Initializing
+
+	result := reconcile.Result{RequeueAfter: resyncPeriod}
+	if err := publishRefs(log, r.client, r.scheme, gitSource, secretProvider); err != nil {
+		log.Error(err, "Unable to publish branches and tags of the repository")
+		result.RequeueAfter = publishRetryAfter
 	}
+	if err := publishMetadata(log, r.client, r.scheme, gitSource, secretProvider); err != nil {
+		log.Error(err, "Unable to publish metadata of the repository")
+		result.RequeueAfter = publishRetryAfter
+	}
+	if result.RequeueAfter == resyncPeriod {
+		r.recordPublication(key, current)
+	}
+	return result
+}
 
-	gitSource.Status.Connection = getConnectionStatus(log, client, namespace, gitSource)
-	return true
+// untilNextPublication returns how long the publication of the GitSource should wait, zero if it should be
+// published now
+func (r *ReconcileGitSource) untilNextPublication(key types.NamespacedName, current publication) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	last, ok := r.publications[key]
+	if !ok || last.secretName != current.secretName || !reflect.DeepEqual(last.spec, current.spec) {
+		return 0
+	}
+	if wait := last.at.Add(resyncPeriod).Sub(current.at); wait > 0 {
+		return wait
+	}
+	return 0
 }
 
-func getConnectionStatus(log *gslog.GitSourceLogger, client client.Client, namespace string, gitSource *v1alpha1.GitSource) v1alpha1.Connection {
-	if gitSource.Spec.SecretRef == nil {
-		if validationError := connection.ValidateGitSource(log, gitSource); validationError != nil {
-			return NewFailedConnection(validationError)
+func (r *ReconcileGitSource) recordPublication(key types.NamespacedName, current publication) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.publications == nil {
+		r.publications = map[types.NamespacedName]publication{}
+	}
+	r.publications[key] = current
+}
+
+func (r *ReconcileGitSource) forgetPublication(key types.NamespacedName) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.publications, key)
+}
+
+// updateStatus validates the connection to the repository using the given secret provider (or reports the error
+// of its creation) unless the GitSource was already validated
+func updateStatus(log *gslog.GitSourceLogger, client client.Client, namespace string, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider, err error) (isDirty bool, requeueAfter time.Duration) {
+
+	// the annotation is recorded on every reconcile as the matching secrets can be created or removed later.
+	// It is recorded before the status is computed as the update of the GitSource overwrites its status
+	// with the stored one.
+	if err == nil {
+		if err := recordMatchedSecret(client, namespace, gitSource, secretProvider.SecretName()); err != nil {
+			log.Error(err, "Unable to record the secret matching the URL of the repository")
 		}
-		return NewConnection("", "", v1alpha1.OK)
 	}
-	secret, err := git.NewGitSecret(client, namespace, gitSource)
+
+	// the connection failed because of an exhausted rate limit is not final - it's validated again when requeued
+	if gitSource.Status.Connection.State != "" && gitSource.Status.Connection.Reason != connection.RateLimited {
+		return false, 0
+	}
+	if gitSource.Status.State == "" {
+		gitSource.Status.State = v1alpha1.Initializing
+	}
 	if err != nil {
-		return NewConnection(err.Error(), v1alpha1.BadCredentials, v1alpha1.Failed)
+		gitSource.Status.Connection = NewConnection(err.Error(), v1alpha1.BadCredentials, v1alpha1.Failed)
+		return true, 0
 	}
-	validationError := connection.ValidateGitSourceWithSecret(log, gitSource, secret)
+	gitSource.Status.Connection, requeueAfter = getConnectionStatus(log, gitSource, secretProvider)
+	return true, requeueAfter
+}
+
+// recordMatchedSecret stores the name of the secret matching the URL of the GitSource in its annotation.
+// The annotation is removed if no secret matches the URL anymore. Nothing is done for GitSources referencing
+// a secret. The whole GitSource is updated (there is no patch in this version of the client), so it's done only
+// if the annotation changes.
+func recordMatchedSecret(cl client.Client, namespace string, gitSource *v1alpha1.GitSource, secretName string) error {
+	if gitSource.Spec.SecretRef != nil || gitSource.Annotations[git.MatchedSecretAnnotation] == secretName {
+		return nil
+	}
+	key := types.NamespacedName{Namespace: namespace, Name: gitSource.Name}
+	return status.UpdateWithRetry(cl, key, gitSource, status.Object, func(obj runtime.Object) error {
+		gs := obj.(*v1alpha1.GitSource)
+		if secretName == "" {
+			delete(gs.Annotations, git.MatchedSecretAnnotation)
+		} else {
+			if gs.Annotations == nil {
+				gs.Annotations = map[string]string{}
+			}
+			gs.Annotations[git.MatchedSecretAnnotation] = secretName
+		}
+		return nil
+	})
+}
+
+func getConnectionStatus(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) (v1alpha1.Connection, time.Duration) {
+
+	ctx, cancel := context.WithTimeout(context.TODO(), secretProvider.TransportSettings().OperationTimeout())
+	defer cancel()
+
+	if secretProvider.SecretName() == "" {
+		validationError := connection.ValidateGitSourceWithSettings(ctx, log, gitSource, secretProvider.TransportSettings())
+		if validationError != nil {
+			return NewFailedConnection(validationError), retryAfter(validationError)
+		}
+		return NewConnection("", "", v1alpha1.OK), 0
+	}
+	validationError := connection.ValidateGitSourceWithSecretProvider(ctx, log, gitSource, secretProvider)
 	if validationError != nil {
-		return NewFailedConnection(validationError)
+		if connection.IsWarning(validationError) {
+			// the connection works, the missing permissions are reported in the status so the user can fix the secret
+			return NewConnection(validationError.Error(), validationError.Reason(), v1alpha1.OK), 0
+		}
+		return NewFailedConnection(validationError), retryAfter(validationError)
+	}
+	return NewConnection("", "", v1alpha1.OK), 0
+}
+
+// retryAfter returns the duration after which the failed validation should be repeated, zero if it shouldn't
+func retryAfter(validationError connection.ValidationError) time.Duration {
+	retryAt, limited := connection.RetryAt(validationError)
+	if !limited {
+		return 0
+	}
+	if wait := retryAt.Sub(time.Now()); wait > minRetryAfter {
+		return wait
 	}
-	return NewConnection("", "", v1alpha1.OK)
+	return minRetryAfter
 }
 
 func NewFailedConnection(validationError connection.ValidationError) v1alpha1.Connection {
@@ -4,16 +4,22 @@ import (
 	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/connection"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/h2non/gock.v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
 	"testing"
+	"time"
 )
 
 const (
@@ -27,14 +33,7 @@ func TestReconcileGitSourceConnectionOK(t *testing.T) {
 	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
 	reconciler, request, client := PrepareClient(test.GitSourceName,
 		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
-
-	gock.New("https://github.com").
-		Get("/some-org/some-repo.git/info/refs").
-		MatchParam("service", "git-upload-pack").
-		Reply(200).
-		BodyString(`004a8d501bc8f3a77129c17a7120bac2d4d70f4d9291 refs/heads/master
-003f8c48499a598266ed7ef609070b84d2c8707fb1dd refs/heads/dev
-0000`)
+	mockGitHubInfoRefs()
 
 	//when
 	_, err := reconciler.Reconcile(request)
@@ -46,17 +45,21 @@ func TestReconcileGitSourceConnectionOK(t *testing.T) {
 
 func TestReconcileGitSourceConnectionFail(t *testing.T) {
 	//given
+	defer gock.OffAll()
 	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
 	gs.Status.State = v1alpha1.Ready
 	reconciler, request, client := PrepareClient(test.GitSourceName,
 		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	gock.New("https://github.com").
+		Get("/some-org/some-repo/info/refs").
+		Reply(401)
 
 	//when
 	_, err := reconciler.Reconcile(request)
 
 	//then
 	require.NoError(t, err)
-	assertGitSource(t, client, v1alpha1.Ready, v1alpha1.Failed, v1alpha1.RepoNotReachable)
+	assertGitSource(t, client, v1alpha1.Ready, v1alpha1.Failed, connection.RepoNotFound)
 }
 
 func TestReconcileGitSourceConnectionSkip(t *testing.T) {
@@ -76,6 +79,297 @@ func TestReconcileGitSourceConnectionSkip(t *testing.T) {
 	assertGitSource(t, client, "", v1alpha1.OK, v1alpha1.ConnectionInternalFailure)
 }
 
+func TestReconcileGitSourcePublishesBranchesAndTags(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	mockGitHubInfoRefs()
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s/branches", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "dev"}, {"name": "master"}]`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s/tags", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "v1.0.0"}]`)
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.OK, "")
+	configMap := &corev1.ConfigMap{}
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-refs"), configMap)
+	require.NoError(t, err)
+	assert.Equal(t, "dev\nmaster", configMap.Data["branches"])
+	assert.Equal(t, "v1.0.0", configMap.Data["tags"])
+	assert.Equal(t, "false", configMap.Data["truncated"])
+	require.Len(t, configMap.OwnerReferences, 1)
+	assert.Equal(t, test.GitSourceName, configMap.OwnerReferences[0].Name)
+}
+
+func TestReconcileGitSourcePublishesMetadata(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	mockGitHubInfoRefs()
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s$", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"description": "Some repo", "default_branch": "master", "private": false, "archived": true,
+			"fork": false, "size": 1, "html_url": "https://github.com/some-org/some-repo"}`)
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.OK, "")
+	configMap := &corev1.ConfigMap{}
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-metadata"), configMap)
+	require.NoError(t, err)
+	assert.Equal(t, "Some repo", configMap.Data["description"])
+	assert.Equal(t, "master", configMap.Data["defaultBranch"])
+	assert.Equal(t, "public", configMap.Data["visibility"])
+	assert.Equal(t, "true", configMap.Data["archived"])
+	assert.Equal(t, "false", configMap.Data["fork"])
+	assert.Equal(t, "1024", configMap.Data["size"])
+	assert.Equal(t, "https://github.com/some-org/some-repo", configMap.Data["webURL"])
+	require.Len(t, configMap.OwnerReferences, 1)
+	assert.Equal(t, test.GitSourceName, configMap.OwnerReferences[0].Name)
+}
+
+func TestReconcileGitSourceRepublishesRefsAndMetadataOfValidGitSource(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gs.Status.Connection.State = v1alpha1.OK
+	refsConfigMap := newConfigMap(test.GitSourceName+"-refs", map[string]string{"branches": "master"})
+	refsConfigMap.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(gs, v1alpha1.SchemeGroupVersion.WithKind("GitSource")),
+	}
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, refsConfigMap))
+	mockGitHubRefsAndMetadata()
+
+	//when
+	result, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, resyncPeriod, result.RequeueAfter)
+	assert.True(t, gock.IsDone())
+	configMap := &corev1.ConfigMap{}
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-refs"), configMap)
+	require.NoError(t, err)
+	assert.Equal(t, "dev\nmaster", configMap.Data["branches"])
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-metadata"), configMap)
+	require.NoError(t, err)
+	assert.Equal(t, "Some repo", configMap.Data["description"])
+}
+
+func TestReconcileGitSourceDoesNotRepublishWithinResyncPeriod(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gs.Status.Connection.State = v1alpha1.OK
+	reconciler, request, _ := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	mockGitHubRefsAndMetadata()
+	_, err := reconciler.Reconcile(request)
+	require.NoError(t, err)
+	require.True(t, gock.IsDone())
+
+	//when
+	result, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.True(t, result.RequeueAfter > publishRetryAfter)
+	assert.True(t, result.RequeueAfter <= resyncPeriod)
+	assert.False(t, gock.HasUnmatchedRequest())
+}
+
+func TestReconcileGitSourceRepublishesWhenSpecChangesWithinResyncPeriod(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gs.Status.Connection.State = v1alpha1.OK
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	mockGitHubRefsAndMetadata()
+	_, err := reconciler.Reconcile(request)
+	require.NoError(t, err)
+	require.True(t, gock.IsDone())
+	gitSource := &v1alpha1.GitSource{}
+	require.NoError(t, client.Get(context.TODO(), request.NamespacedName, gitSource))
+	gitSource.Spec.Ref = "dev"
+	require.NoError(t, client.Update(context.TODO(), gitSource))
+	mockGitHubRefsAndMetadata()
+
+	//when
+	result, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, resyncPeriod, result.RequeueAfter)
+	assert.True(t, gock.IsDone())
+}
+
+func TestReconcileGitSourceDoesNotOverwriteConfigMapNotControlledByGitSource(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gs.Status.Connection.State = v1alpha1.OK
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion,
+			newConfigMap(test.GitSourceName+"-refs", map[string]string{"some-key": "some-value"})))
+	mockGitHubRefsAndMetadata()
+
+	//when
+	result, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, publishRetryAfter, result.RequeueAfter)
+	configMap := &corev1.ConfigMap{}
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-refs"), configMap)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"some-key": "some-value"}, configMap.Data)
+	assert.Empty(t, configMap.OwnerReferences)
+}
+
+func newConfigMap(name string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: test.Namespace},
+		Data:       data,
+	}
+}
+
+func mockGitHubRefsAndMetadata() {
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s/branches", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "dev"}, {"name": "master"}]`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s/tags", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "v1.0.0"}]`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s$", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"description": "Some repo", "default_branch": "master"}`)
+}
+
+func TestReconcileGitSourceDoesNotPublishRefsWhenConnectionFails(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	gock.New("https://github.com").
+		Get("/some-org/some-repo/info/refs").
+		Reply(404)
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	err = client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName+"-refs"), &corev1.ConfigMap{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestReconcileGitSourceKeepsSpecChangedDuringConflict(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, cl := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	conflictingClient := &test.ConflictingClient{
+		Client:    cl,
+		Conflicts: 1,
+		OnConflict: func(cl client.Client) {
+			changed := &v1alpha1.GitSource{}
+			require.NoError(t, cl.Get(context.TODO(), request.NamespacedName, changed))
+			changed.Spec.Ref = "dev"
+			require.NoError(t, cl.Update(context.TODO(), changed))
+		},
+	}
+	reconciler.client = conflictingClient
+	mockGitHubInfoRefs()
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, 2, conflictingClient.StatusUpdates)
+	assertGitSource(t, cl, v1alpha1.Initializing, v1alpha1.OK, "")
+	gitSource := &v1alpha1.GitSource{}
+	require.NoError(t, cl.Get(context.TODO(), request.NamespacedName, gitSource))
+	assert.Equal(t, "dev", gitSource.Spec.Ref)
+}
+
+func TestReconcileGitSourceFailsWhenConflictsPersist(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, cl := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	reconciler.client = &test.ConflictingClient{Client: cl, Conflicts: 100}
+	mockGitHubInfoRefs()
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.Error(t, err)
+	assert.True(t, errors.IsConflict(err))
+	gitSource := &v1alpha1.GitSource{}
+	require.NoError(t, cl.Get(context.TODO(), request.NamespacedName, gitSource))
+	assert.Empty(t, gitSource.Status.Connection.State)
+}
+
+func TestReconcileGitSourceRequeuesWhenRateLimited(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+	gock.New("https://github.com").
+		Get("/some-org/some-repo/info/refs").
+		Reply(403).
+		SetHeader("X-RateLimit-Remaining", "0").
+		SetHeader("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+
+	//when
+	result, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.True(t, result.RequeueAfter > 50*time.Minute)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, connection.RateLimited)
+
+	// and given
+	mockGitHubInfoRefs()
+
+	// when
+	result, err = reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, result.RequeueAfter <= resyncPeriod)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.OK, "")
+}
+
 func TestValidateGitHubInvalidSecret(t *testing.T) {
 	// given
 	defer gock.OffAll()
@@ -98,6 +392,54 @@ func TestValidateGitHubInvalidSecret(t *testing.T) {
 	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, v1alpha1.BadCredentials)
 }
 
+func TestValidateGitHubWithSecretMatchingURL(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://api.github.com").
+		Get("/user").
+		Reply(401)
+
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})
+	secret.Annotations = map[string]string{"build.openshift.io/source-secret-match-uri-1": "https://github.com/some-org/*"}
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, v1alpha1.BadCredentials)
+	gitSource := &v1alpha1.GitSource{}
+	require.NoError(t, client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName), gitSource))
+	assert.Equal(t, test.SecretName, gitSource.Annotations["devconsole.openshift.io/matched-source-secret"])
+}
+
+func TestReconcileGitSourceRecordsSecretMatchingURLOfValidGitSource(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})
+	secret.Annotations = map[string]string{"build.openshift.io/source-secret-match-uri-1": "https://github.com/some-org/*"}
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gs.Status.Connection.State = v1alpha1.OK
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+	mockGitHubRefsAndMetadata()
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	gitSource := &v1alpha1.GitSource{}
+	require.NoError(t, client.Get(context.TODO(), newNsdName(test.Namespace, test.GitSourceName), gitSource))
+	assert.Equal(t, test.SecretName, gitSource.Annotations["devconsole.openshift.io/matched-source-secret"])
+	assert.Equal(t, v1alpha1.OK, gitSource.Status.Connection.State)
+}
+
 func TestValidateGitLabSecretAndUnavailableRepo(t *testing.T) {
 	// given
 	defer gock.OffAll()
@@ -121,7 +463,7 @@ func TestValidateGitLabSecretAndUnavailableRepo(t *testing.T) {
 
 	// then
 	require.NoError(t, err)
-	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, v1alpha1.RepoNotReachable)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, connection.RepoNotFound)
 }
 
 func TestValidateGitHubSecretAndAvailableRepoWithWrongBranch(t *testing.T) {
@@ -154,6 +496,36 @@ func TestValidateGitHubSecretAndAvailableRepoWithWrongBranch(t *testing.T) {
 	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, v1alpha1.BranchNotFound)
 }
 
+func TestValidateGitHubTokenNotAllowingToCreateWebhooks(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://api.github.com").
+		Get("/user").
+		Reply(200)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "read:user").
+		BodyString(`{"private": false, "permissions": {"admin": true, "push": true, "pull": true}}`)
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("repos/%s/branches/master", repoIdentifier)).
+		Reply(200)
+
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.OK, connection.LimitedPermissions)
+}
+
 func TestValidateBitBucketWithCorrectData(t *testing.T) {
 	// given
 	defer gock.OffAll()
@@ -184,7 +556,57 @@ func TestValidateGenericGit(t *testing.T) {
 
 	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
 	dummyRepo.Commit("main.go")
-	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("super-secret")})
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"password":    []byte("super-secret"),
+		"known_hosts": test.KnownHosts()})
+	gs := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.OK, "")
+}
+
+func TestValidateGenericGitWithMismatchingHostKey(t *testing.T) {
+	//given
+	reset := test.RunBasicSshServer(t, "super-secret")
+	defer reset()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"password":    []byte("super-secret"),
+		"known_hosts": test.MismatchingKnownHosts()})
+	gs := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	reconciler, request, client := PrepareClient(test.GitSourceName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assertGitSource(t, client, v1alpha1.Initializing, v1alpha1.Failed, connection.HostKeyMismatch)
+}
+
+func TestValidateGenericGitWithInsecureHostKeyMode(t *testing.T) {
+	//given
+	reset := test.RunBasicSshServer(t, "super-secret")
+	defer reset()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"password":                 []byte("super-secret"),
+		"insecure-ignore-host-key": []byte("true")})
 	gs := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
 	reconciler, request, client := PrepareClient(test.GitSourceName,
@@ -229,6 +651,15 @@ func assertGitSource(t *testing.T, client client.Client, gsState v1alpha1.State,
 	assert.Equal(t, gsState, gitSource.Status.State)
 }
 
+func mockGitHubInfoRefs() {
+	gock.New("https://github.com").
+		Get("/some-org/some-repo/info/refs").
+		MatchParam("service", "git-upload-pack").
+		Reply(200).
+		SetHeader("Content-Type", "application/x-git-upload-pack-advertisement").
+		BodyString(test.SmartInfoRefs("HEAD", "refs/heads/master", "refs/heads/dev"))
+}
+
 func newNsdName(namespace, name string) types.NamespacedName {
 	return types.NamespacedName{Namespace: namespace, Name: name}
 }
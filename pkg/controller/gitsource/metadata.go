@@ -0,0 +1,28 @@
+package gitsource
+
+import (
+	"context"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/metadata"
+	gslog "github.com/redhat-developer/devconsole-git/pkg/log"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// publishMetadata reads the metadata of the repository and stores them in a config map owned by the GitSource,
+// so the console can show a repository card and warn about archived repositories and forks. Nothing is published
+// if the git server doesn't provide an API for reading the metadata.
+func publishMetadata(log *gslog.GitSourceLogger, cl client.Client, scheme *runtime.Scheme, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) error {
+
+	ctx, cancel := context.WithTimeout(context.TODO(), secretProvider.TransportSettings().OperationTimeout())
+	defer cancel()
+
+	repoMetadata, err := metadata.GetMetadata(ctx, log, gitSource, secretProvider)
+	if err != nil || repoMetadata == nil {
+		return err
+	}
+	return applyConfigMap(cl, scheme, gitSource, metadata.ConfigMapName(gitSource), metadata.ToData(repoMetadata))
+}
@@ -0,0 +1,33 @@
+package gitsource
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/refs"
+	gslog "github.com/redhat-developer/devconsole-git/pkg/log"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// publishRefs lists the branches and tags of the repository and stores them in a config map owned by the GitSource,
+// so the console can offer them in a branch picker. The v1alpha1 API doesn't contain a status field for them yet.
+func publishRefs(log *gslog.GitSourceLogger, cl client.Client, scheme *runtime.Scheme, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) error {
+
+	ctx, cancel := context.WithTimeout(context.TODO(), secretProvider.TransportSettings().OperationTimeout())
+	defer cancel()
+
+	repoRefs, err := refs.ListRefs(ctx, log, gitSource, secretProvider)
+	if err != nil {
+		return err
+	}
+	return applyConfigMap(cl, scheme, gitSource, refs.ConfigMapName(gitSource), map[string]string{
+		refs.BranchesKey:  strings.Join(repoRefs.Branches, "\n"),
+		refs.TagsKey:      strings.Join(repoRefs.Tags, "\n"),
+		refs.TruncatedKey: strconv.FormatBool(repoRefs.Truncated),
+	})
+}
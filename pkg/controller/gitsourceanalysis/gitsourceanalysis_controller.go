@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-logr/logr"
+	"github.com/redhat-developer/devconsole-git/pkg/controller/status"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"k8s.io/apimachinery/pkg/types"
+	"time"
 
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -24,6 +26,18 @@ import (
 
 var controllerLogger = logf.Log.WithName("controller_gitsourceanalysis")
 
+const (
+	// AnalysisTimeout is a reason used when the git server didn't respond within the operation deadline.
+	// The reason is not part of the v1alpha1 API yet.
+	AnalysisTimeout v1alpha1.AnalysisFailureReason = "Timeout"
+	// AnalysisRateLimited is a reason used when the rate limit of the git server is exhausted. The analysis
+	// is repeated after the limit is reset. The reason is not part of the v1alpha1 API yet.
+	AnalysisRateLimited v1alpha1.AnalysisFailureReason = "RateLimited"
+
+	// minRetryAfter is the minimal delay of an analysis repeated because of an exhausted rate limit
+	minRetryAfter = 5 * time.Second
+)
+
 // Add creates a new GitSourceAnalysis Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
@@ -93,15 +107,22 @@ func (r *ReconcileGitSourceAnalysis) Reconcile(request reconcile.Request) (recon
 	}
 
 	buildEnvStats, analysisError := analyze(reqLogger, r.client, gsAnalysis, request.Namespace)
+	var requeueAfter time.Duration
 	if analysisError != nil {
 		gsAnalysis.Status.Error = analysisError.message
 		gsAnalysis.Status.Reason = analysisError.reason
+		requeueAfter = analysisError.retryAfter()
 	} else {
 		gsAnalysis.Status.BuildEnvStatistics = *buildEnvStats
 	}
 
-	gsAnalysis.Status.Analyzed = true
-	err = r.client.Update(context.TODO(), gsAnalysis)
+	// the analysis failed because of an exhausted rate limit is not final - it's repeated when requeued
+	gsAnalysis.Status.Analyzed = requeueAfter == 0
+	computedStatus := gsAnalysis.Status
+	err = status.UpdateWithRetry(r.client, request.NamespacedName, gsAnalysis, status.Status, func(obj runtime.Object) error {
+		obj.(*v1alpha1.GitSourceAnalysis).Status = computedStatus
+		return nil
+	})
 	if err != nil {
 		reqLogger.WithValues("git-source", gsAnalysis.Spec.GitSourceRef).
 			Error(err, "Error updating GitSourceAnalysis object")
@@ -114,6 +135,10 @@ func (r *ReconcileGitSourceAnalysis) Reconcile(request reconcile.Request) (recon
 		// Error updating the object - requeue the request.
 		return reconcile.Result{}, err
 	}
+	if requeueAfter > 0 {
+		reqLogger.Info("Rate limit of the git server exceeded, the analysis will be repeated", "after", requeueAfter)
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
 	return reconcile.Result{}, nil
 }
 
@@ -135,15 +160,26 @@ func analyzeGitSource(logger *log.GitSourceLogger, client client.Client, gitSour
 	// Fetch the GitSource secret
 	gitSecretProvider, err := git.NewGitSecretProvider(client, namespace, gitSource)
 	if err != nil {
-		logger.WithValues("secret", gitSource.Spec.SecretRef.Name).
-			Error(err, "Error reading the secret object")
+		logger.Error(err, "Error reading the secret object", "secret-ref", gitSource.Spec.SecretRef)
 		return nil,
 			newAnalysisErrorf(v1alpha1.AnalysisInternalFailure, "error reading the secret object: %s", err)
 
 	} else {
-		buildEnvStats, err := detector.DetectBuildEnvironments(logger, gitSource, gitSecretProvider)
+		ctx, cancel := context.WithTimeout(context.TODO(), gitSecretProvider.TransportSettings().OperationTimeout())
+		defer cancel()
+
+		buildEnvStats, err := detector.DetectBuildEnvironments(ctx, logger, gitSource, gitSecretProvider)
 		if err != nil {
 			logger.Error(err, "Error detecting build types")
+			if rateLimitErr := git.AsRateLimitError(err); rateLimitErr != nil {
+				analysisErr := newAnalysisErrorf(AnalysisRateLimited, "error detecting build types: %s", err)
+				analysisErr.retryAt = rateLimitErr.Reset
+				return buildEnvStats, analysisErr
+			}
+			if git.IsTimeout(ctx, err) {
+				return buildEnvStats,
+					newAnalysisErrorf(AnalysisTimeout, "the git server didn't respond in time: %s", err)
+			}
 			return buildEnvStats,
 				newAnalysisErrorf(v1alpha1.DetectionFailed, "error detecting build types: %s", err)
 		} else if buildEnvStats == nil {
@@ -161,12 +197,24 @@ func newNamespacedName(namespace, name string) types.NamespacedName {
 type analysisError struct {
 	message string
 	reason  v1alpha1.AnalysisFailureReason
+	retryAt time.Time
 }
 
 func (e analysisError) Error() string {
 	return fmt.Sprintf("message: %s, reason: %s", e.message, e.reason)
 }
 
+// retryAfter returns the duration after which the failed analysis should be repeated, zero if it shouldn't
+func (e analysisError) retryAfter() time.Duration {
+	if e.reason != AnalysisRateLimited {
+		return 0
+	}
+	if wait := e.retryAt.Sub(time.Now()); wait > minRetryAfter {
+		return wait
+	}
+	return minRetryAfter
+}
+
 func newAnalysisErrorf(reason v1alpha1.AnalysisFailureReason, message string, args ...interface{}) *analysisError {
 	return &analysisError{message: fmt.Sprintf(message, args...), reason: reason}
 }
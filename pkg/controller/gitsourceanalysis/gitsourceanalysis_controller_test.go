@@ -42,6 +42,26 @@ func TestReconcileGitSourceAnalysisFromGitHubWithDefaultCredentials(t *testing.T
 	assertGitSourceAnalysis(t, client, "", test.S(), buildType(build.Maven, "pom.xml"))
 }
 
+func TestReconcileGitSourceAnalysisRetriesStatusUpdateOnConflict(t *testing.T) {
+	//given
+	defer gock.OffAll()
+	gs := test.NewGitSource(test.WithURL(repoGitHubURL))
+	gsa := test.NewGitSourceAnalysis(test.GitSourceName)
+	reconciler, request, cl := PrepareClient(test.GitSourceAnalysisName,
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs, gsa))
+	conflictingClient := &test.ConflictingClient{Client: cl, Conflicts: 2}
+	reconciler.client = conflictingClient
+	test.MockGHHeadCalls(repoIdentifier, "master", test.S("pom.xml", "mvnw"), matchBasicAuth("anonymous:"))
+
+	//when
+	_, err := reconciler.Reconcile(request)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, 3, conflictingClient.StatusUpdates)
+	assertGitSourceAnalysis(t, cl, "", test.S(), buildType(build.Maven, "pom.xml"))
+}
+
 func TestReconcileGitSourceAnalysisFromGitHubWithGivenBasicCredentials(t *testing.T) {
 	//given
 	defer gock.OffAll()
@@ -193,13 +213,16 @@ func TestReconcileGitSourceAnalysisFromGitHubWithWrongToken(t *testing.T) {
 func TestReconcileGitSourceAnalysisFromCustomGit(t *testing.T) {
 	//given
 	defer gock.OffAll()
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("pom.xml", "mvnw", "src/main/java/Any.java", "pkg/main.go")
+
 	token := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})
 	basic := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
 		"username": []byte("username"),
 		"password": []byte("password")})
 	for _, secret := range []*corev1.Secret{token, basic} {
 
-		gs := test.NewGitSource(test.WithURL("https://my.git.com/owner/name"))
+		gs := test.NewGitSource(test.WithURL(dummyRepo.Path))
 		gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
 		gsa := test.NewGitSourceAnalysis(test.GitSourceName)
 		reconciler, request, client := PrepareClient(test.GitSourceAnalysisName,
@@ -211,7 +234,7 @@ func TestReconcileGitSourceAnalysisFromCustomGit(t *testing.T) {
 
 		//then
 		require.NoError(t, err)
-		assertGitSourceAnalysis(t, client, v1alpha1.NotSupportedType, test.S())
+		assertGitSourceAnalysis(t, client, "", test.S("XML", "Java", "Go"), buildType(build.Maven, "pom.xml"))
 	}
 }
 
@@ -227,7 +250,8 @@ func TestReconcileGitSourceAnalysisFromLocalRepoWithSshKey(t *testing.T) {
 
 	for _, secretType := range []corev1.SecretType{corev1.SecretTypeOpaque, corev1.SecretTypeSSHAuth} {
 		secret := test.NewSecret(secretType, map[string][]byte{
-			"ssh-privatekey": test.PrivateWithoutPassphrase(t, pathToTestDir)})
+			"ssh-privatekey": test.PrivateWithoutPassphrase(t, pathToTestDir),
+			"known_hosts":    test.KnownHosts()})
 
 		gs := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 		gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
@@ -241,7 +265,7 @@ func TestReconcileGitSourceAnalysisFromLocalRepoWithSshKey(t *testing.T) {
 
 		//then
 		require.NoError(t, err)
-		assertGitSourceAnalysis(t, client, v1alpha1.NotSupportedType, test.S())
+		assertGitSourceAnalysis(t, client, "", test.S("XML", "Java", "Go"), buildType(build.Maven, "pom.xml"))
 	}
 }
 
@@ -258,7 +282,8 @@ func TestReconcileGitSourceAnalysisFromLocalRepoWithSshKeyWithPassphrase(t *test
 	for _, secretType := range []corev1.SecretType{corev1.SecretTypeOpaque, corev1.SecretTypeSSHAuth} {
 		secret := test.NewSecret(secretType, map[string][]byte{
 			"ssh-privatekey": test.PrivateWithPassphrase(t, pathToTestDir),
-			"passphrase":     []byte("secret")})
+			"passphrase":     []byte("secret"),
+			"known_hosts":    test.KnownHosts()})
 
 		gs := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 		gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
@@ -272,7 +297,7 @@ func TestReconcileGitSourceAnalysisFromLocalRepoWithSshKeyWithPassphrase(t *test
 
 		//then
 		require.NoError(t, err)
-		assertGitSourceAnalysis(t, client, v1alpha1.NotSupportedType, test.S())
+		assertGitSourceAnalysis(t, client, "", test.S("XML", "Java", "Go"), buildType(build.Maven, "pom.xml"))
 	}
 }
 
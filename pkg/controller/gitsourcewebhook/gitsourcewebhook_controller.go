@@ -0,0 +1,376 @@
+package gitsourcewebhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/config"
+	"github.com/redhat-developer/devconsole-git/pkg/controller/status"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
+	gslog "github.com/redhat-developer/devconsole-git/pkg/log"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_gitsourcewebhook")
+
+// Add creates a new GitSource webhook Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileGitSourceWebhook{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("gitsourcewebhook-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource GitSource
+	err = c.Watch(&source.Kind{Type: &v1alpha1.GitSource{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the webhook secrets owned by GitSources
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &v1alpha1.GitSource{},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+var _ reconcile.Reconciler = &ReconcileGitSourceWebhook{}
+
+// ReconcileGitSourceWebhook registers push webhooks for GitSources that request them and removes the webhooks
+// when the GitSources are deleted
+type ReconcileGitSourceWebhook struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile reads that state of the cluster for a GitSource object and makes sure that the push webhook is registered
+// in the repository if the GitSource requests it, or removed if the GitSource is being deleted or doesn't request it anymore
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *ReconcileGitSourceWebhook) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	// Fetch the GitSource instance
+	gitSource := &v1alpha1.GitSource{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, gitSource)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		reqLogger.Error(err, "Error getting GitSource object")
+		return reconcile.Result{}, err
+	}
+	gitSourceLogger := gslog.LogWithGSValues(reqLogger, gitSource)
+
+	if gitSource.DeletionTimestamp != nil || !git.IsWebhookRequested(gitSource) {
+		if !hasFinalizer(gitSource) {
+			return reconcile.Result{}, nil
+		}
+		gitSourceLogger.Info("Removing the webhook")
+		if err := r.removeWebhook(gitSourceLogger, gitSource); err != nil {
+			gitSourceLogger.Error(err, "Error removing the webhook")
+			return reconcile.Result{}, err
+		}
+		gitSource.Finalizers = removeFinalizer(gitSource.Finalizers)
+		return reconcile.Result{}, r.client.Update(context.TODO(), gitSource)
+	}
+
+	if !hasFinalizer(gitSource) {
+		// the finalizer is added before the webhook is created so it cannot be left behind
+		gitSource.Finalizers = append(gitSource.Finalizers, git.WebhookFinalizer)
+		if err := r.client.Update(context.TODO(), gitSource); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if err := r.ensureWebhook(gitSourceLogger, gitSource); err != nil {
+		gitSourceLogger.Error(err, "Error registering the webhook")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// ensureWebhook registers the webhook if it is not registered yet, if it was removed from the repository
+// or if the target URL has changed. A webhook registered before its ID could be stored in the webhook secret
+// is adopted instead of registering another one.
+func (r *ReconcileGitSourceWebhook) ensureWebhook(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource) error {
+	clusterConfig, err := config.LoadCluster(r.client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the cluster configuration: %s", err)
+	}
+	url := clusterConfig.WebhookURL()
+	if value, ok := gitSource.Annotations[git.WebhookURLAnnotation]; ok {
+		url = value
+	}
+	if url == "" {
+		return fmt.Errorf("the URL of the webhook is not configured - set the %s annotation or the %s key of the %s ConfigMap",
+			git.WebhookURLAnnotation, config.WebhookURLKey, config.ConfigMapName)
+	}
+
+	webhookSecret, created, err := r.getOrCreateWebhookSecret(gitSource)
+	if err != nil {
+		return err
+	}
+	service, ctx, cancel, err := r.newWebhookService(log, gitSource)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	id := string(webhookSecret.Data[git.WebhookIDKey])
+	if id != "" {
+		if string(webhookSecret.Data[git.WebhookURLKey]) == url {
+			exists, err := service.WebhookExists(ctx, id)
+			if err != nil || exists {
+				return err
+			}
+			log.Info("The webhook was removed from the repository, registering it again", "id", id)
+		} else if err := service.DeleteWebhook(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	targetURL := git.WebhookTargetURL(url, gitSource)
+	registered, err := service.FindWebhook(ctx, targetURL)
+	if err != nil {
+		return err
+	}
+	if registered != "" {
+		if !created {
+			log.Info("Adopting the webhook registered before", "id", registered, "url", url)
+			return r.storeWebhook(gitSource, webhookSecret, registered, url)
+		}
+		// the webhook secret was deleted, so the events of the webhook cannot be verified anymore
+		if err := service.DeleteWebhook(ctx, registered); err != nil {
+			return err
+		}
+	}
+
+	id, err = service.CreateWebhook(ctx, targetURL, string(webhookSecret.Data[git.WebhookSecretKey]))
+	if err != nil {
+		return err
+	}
+	log.Info("Webhook registered", "id", id, "url", url)
+	return r.storeWebhook(gitSource, webhookSecret, id, url)
+}
+
+// storeWebhook stores the ID and the URL of the registered webhook in the webhook secret. The conflicts are retried,
+// otherwise the webhook would be registered again by the next reconcile.
+func (r *ReconcileGitSourceWebhook) storeWebhook(gitSource *v1alpha1.GitSource, webhookSecret *corev1.Secret, id, url string) error {
+	key := types.NamespacedName{Namespace: webhookSecret.Namespace, Name: webhookSecret.Name}
+	return status.UpdateWithRetry(r.client, key, webhookSecret, status.Object, func(obj runtime.Object) error {
+		secret := obj.(*corev1.Secret)
+		// the refetched secret could have been replaced by one not controlled by the GitSource
+		if err := checkControlledBy(secret, gitSource); err != nil {
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[git.WebhookIDKey] = []byte(id)
+		secret.Data[git.WebhookURLKey] = []byte(url)
+		return nil
+	})
+}
+
+// removeWebhook removes the registered webhook from the repository and deletes the webhook secret. A secret
+// that is not controlled by the GitSource is left untouched - it doesn't block the deletion of the GitSource.
+func (r *ReconcileGitSourceWebhook) removeWebhook(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource) error {
+	webhookSecret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: git.WebhookSecretName(gitSource)}
+	if err := r.client.Get(context.TODO(), key, webhookSecret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if err := checkControlledBy(webhookSecret, gitSource); err != nil {
+		log.Error(err, "Keeping the secret, the webhook has to be removed manually")
+		return nil
+	}
+
+	if id := string(webhookSecret.Data[git.WebhookIDKey]); id != "" {
+		if err := r.deleteWebhook(log, gitSource, id); err != nil {
+			if !isAccessDenied(err) {
+				return err
+			}
+			// the webhook cannot be removed using the current secret (or the secret is gone) - it shouldn't
+			// block the deletion of the GitSource
+			log.Error(err, "Unable to remove the webhook, it has to be removed manually", "id", id)
+		}
+	}
+	err := r.client.Delete(context.TODO(), webhookSecret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *ReconcileGitSourceWebhook) deleteWebhook(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource, id string) error {
+	service, ctx, cancel, err := r.newWebhookService(log, gitSource)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	return service.DeleteWebhook(ctx, id)
+}
+
+// getOrCreateWebhookSecret returns the webhook secret of the GitSource and true if it has just been created.
+// It fails if there is a secret with the same name that is not controlled by the GitSource.
+func (r *ReconcileGitSourceWebhook) getOrCreateWebhookSecret(gitSource *v1alpha1.GitSource) (*corev1.Secret, bool, error) {
+	webhookSecret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: git.WebhookSecretName(gitSource)}
+	err := r.client.Get(context.TODO(), key, webhookSecret)
+	if err == nil {
+		if err := checkControlledBy(webhookSecret, gitSource); err != nil {
+			return nil, false, err
+		}
+		if webhookSecret.Data == nil {
+			webhookSecret.Data = map[string][]byte{}
+		}
+		return webhookSecret, false, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, false, err
+	}
+
+	secret, err := git.NewWebhookSecret()
+	if err != nil {
+		return nil, false, err
+	}
+	webhookSecret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      key.Name,
+			Namespace: key.Namespace,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			git.WebhookSecretKey: []byte(secret),
+		},
+	}
+	if err := controllerutil.SetControllerReference(gitSource, webhookSecret, r.scheme); err != nil {
+		return nil, false, err
+	}
+	if err := r.client.Create(context.TODO(), webhookSecret); err != nil {
+		return nil, false, err
+	}
+	return webhookSecret, true, nil
+}
+
+// checkControlledBy returns an error if the webhook secret is not controlled by the GitSource - e.g. an unrelated
+// secret that happens to have the same name
+func checkControlledBy(webhookSecret *corev1.Secret, gitSource *v1alpha1.GitSource) error {
+	if !metav1.IsControlledBy(webhookSecret, gitSource) {
+		return fmt.Errorf("the secret %s is not controlled by the GitSource %s", webhookSecret.Name, gitSource.Name)
+	}
+	return nil
+}
+
+// newWebhookService returns the service managing webhooks of the repository together with a context bound
+// to the operation timeout
+func (r *ReconcileGitSourceWebhook) newWebhookService(log *gslog.GitSourceLogger, gitSource *v1alpha1.GitSource) (
+	repository.WebhookService, context.Context, context.CancelFunc, error) {
+
+	secretProvider, err := git.NewGitSecretProvider(r.client, gitSource.Namespace, gitSource)
+	if err != nil {
+		if r.isSecretMissing(gitSource) {
+			return nil, nil, nil, &accessDeniedError{err}
+		}
+		return nil, nil, nil, err
+	}
+	service, err := repository.NewWebhookService(log, gitSource, secretProvider,
+		repository.DefaultRegistry().WebhookServiceCreators())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if service == nil {
+		return nil, nil, nil, &accessDeniedError{fmt.Errorf(
+			"webhooks cannot be managed for the git server %s using the given secret", gitSource.Spec.URL)}
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), secretProvider.TransportSettings().OperationTimeout())
+	return service, ctx, cancel, nil
+}
+
+// isSecretMissing returns true if the secret referenced by the GitSource doesn't exist
+func (r *ReconcileGitSourceWebhook) isSecretMissing(gitSource *v1alpha1.GitSource) bool {
+	if gitSource.Spec.SecretRef == nil {
+		return false
+	}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: gitSource.Spec.SecretRef.Name}
+	return errors.IsNotFound(r.client.Get(context.TODO(), key, &corev1.Secret{}))
+}
+
+// accessDeniedError is returned when the service managing the webhooks cannot be created as the secret referenced
+// by the GitSource doesn't exist or cannot be used for managing webhooks. The other failures (e.g. fetching
+// the cluster configuration) are transient.
+type accessDeniedError struct {
+	err error
+}
+
+func (e *accessDeniedError) Error() string {
+	return e.err.Error()
+}
+
+func isAccessDenied(err error) bool {
+	if _, ok := err.(*accessDeniedError); ok {
+		return true
+	}
+	apiErr := repository.AsAPIError(err)
+	return apiErr != nil && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden)
+}
+
+func hasFinalizer(gitSource *v1alpha1.GitSource) bool {
+	for _, finalizer := range gitSource.Finalizers {
+		if finalizer == git.WebhookFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func removeFinalizer(finalizers []string) []string {
+	var result []string
+	for _, finalizer := range finalizers {
+		if finalizer != git.WebhookFinalizer {
+			result = append(result, finalizer)
+		}
+	}
+	return result
+}
@@ -0,0 +1,363 @@
+package gitsourcewebhook
+
+import (
+	"context"
+	"fmt"
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+const (
+	repoIdentifier = "some-org/some-repo"
+	repoGitHubURL  = "https://github.com/" + repoIdentifier
+	ghApiHost      = "https://api.github.com"
+	targetURL      = "https://builds.example.com/hooks/some-build"
+)
+
+func TestReconcileRegistersRequestedWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks$", repoIdentifier)).
+		Reply(200).
+		BodyString(`[]`)
+	gock.New(ghApiHost).
+		Post(fmt.Sprintf("/repos/%s/hooks", repoIdentifier)).
+		MatchType("json").
+		BodyString(targetURL).
+		Reply(201).
+		BodyString(`{"id": 42}`)
+
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook())
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	gitSource := getGitSource(t, cl)
+	assert.Contains(t, gitSource.Finalizers, git.WebhookFinalizer)
+
+	webhookSecret := getWebhookSecret(t, cl)
+	assert.Equal(t, "42", string(webhookSecret.Data[git.WebhookIDKey]))
+	assert.Equal(t, targetURL, string(webhookSecret.Data[git.WebhookURLKey]))
+	assert.Len(t, webhookSecret.Data[git.WebhookSecretKey], 40)
+	require.Len(t, webhookSecret.OwnerReferences, 1)
+	assert.Equal(t, test.GitSourceName, webhookSecret.OwnerReferences[0].Name)
+}
+
+func TestReconcileKeepsExistingWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"id": 42}`)
+
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook(), newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	assert.Equal(t, "42", string(getWebhookSecret(t, cl).Data[git.WebhookIDKey]))
+}
+
+func TestReconcileRegistersWebhookAgainWhenRemovedFromRepository(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(404)
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks$", repoIdentifier)).
+		Reply(200).
+		BodyString(`[]`)
+	gock.New(ghApiHost).
+		Post(fmt.Sprintf("/repos/%s/hooks", repoIdentifier)).
+		Reply(201).
+		BodyString(`{"id": 43}`)
+
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook(), newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	webhookSecret := getWebhookSecret(t, cl)
+	assert.Equal(t, "43", string(webhookSecret.Data[git.WebhookIDKey]))
+	assert.Equal(t, "some-secret", string(webhookSecret.Data[git.WebhookSecretKey]))
+}
+
+func TestReconcileReplacesWebhookWhenURLChanges(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Delete(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(204)
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks$", repoIdentifier)).
+		Reply(200).
+		BodyString(`[]`)
+	gock.New(ghApiHost).
+		Post(fmt.Sprintf("/repos/%s/hooks", repoIdentifier)).
+		BodyString(targetURL).
+		Reply(201).
+		BodyString(`{"id": 43}`)
+
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook(),
+		newWebhookSecret("42", "https://old.example.com/hook"))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	webhookSecret := getWebhookSecret(t, cl)
+	assert.Equal(t, "43", string(webhookSecret.Data[git.WebhookIDKey]))
+	assert.Equal(t, targetURL, string(webhookSecret.Data[git.WebhookURLKey]))
+}
+
+func TestReconcileAdoptsWebhookRegisteredBefore(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("/repos/%s/hooks$", repoIdentifier)).
+		Reply(200).
+		BodyString(fmt.Sprintf(`[{"id": 41, "config": {"url": "%s"}}, {"id": 42, "config": {"url": "%s"}}]`,
+			targetURL, git.WebhookTargetURL(targetURL, newGitSourceRequestingWebhook())))
+
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook(), newWebhookSecret("", ""))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	webhookSecret := getWebhookSecret(t, cl)
+	assert.Equal(t, "42", string(webhookSecret.Data[git.WebhookIDKey]))
+	assert.Equal(t, targetURL, string(webhookSecret.Data[git.WebhookURLKey]))
+	assert.Equal(t, "some-secret", string(webhookSecret.Data[git.WebhookSecretKey]))
+}
+
+func TestReconcileFailsWhenWebhookSecretIsNotControlledByGitSource(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	secret := newWebhookSecret("", "")
+	secret.OwnerReferences = nil
+	reconciler, request, cl := prepareClient(newGitSourceRequestingWebhook(), secret)
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not controlled by the GitSource")
+	assert.Empty(t, getWebhookSecret(t, cl).Data[git.WebhookIDKey])
+}
+
+func TestReconcileKeepsSecretNotControlledByDeletedGitSource(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gitSource := newGitSourceRequestingWebhook()
+	gitSource.Finalizers = []string{git.WebhookFinalizer}
+	now := metav1.Now()
+	gitSource.DeletionTimestamp = &now
+	secret := newWebhookSecret("42", targetURL)
+	secret.OwnerReferences = nil
+	reconciler, request, cl := prepareClient(gitSource, secret)
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.NotContains(t, getGitSource(t, cl).Finalizers, git.WebhookFinalizer)
+	assert.Equal(t, "42", string(getWebhookSecret(t, cl).Data[git.WebhookIDKey]))
+}
+
+func TestReconcileRemovesWebhookWhenGitSourceIsDeleted(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Delete(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(204)
+
+	gitSource := newGitSourceRequestingWebhook()
+	gitSource.Finalizers = []string{git.WebhookFinalizer}
+	now := metav1.Now()
+	gitSource.DeletionTimestamp = &now
+	reconciler, request, cl := prepareClient(gitSource, newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	assert.NotContains(t, getGitSource(t, cl).Finalizers, git.WebhookFinalizer)
+	err = cl.Get(context.TODO(), webhookSecretName(), &corev1.Secret{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestReconcileRemovesFinalizerWhenWebhookCannotBeDeleted(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Delete(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(401)
+
+	gitSource := newGitSourceRequestingWebhook()
+	delete(gitSource.Annotations, git.WebhookAnnotation)
+	gitSource.Finalizers = []string{git.WebhookFinalizer}
+	reconciler, request, cl := prepareClient(gitSource, newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.NotContains(t, getGitSource(t, cl).Finalizers, git.WebhookFinalizer)
+}
+
+func TestReconcileRemovesFinalizerWhenSecretOfGitSourceIsMissing(t *testing.T) {
+	// given
+	gitSource := newGitSourceRequestingWebhook()
+	gitSource.Spec.SecretRef.Name = "missing-secret"
+	gitSource.Finalizers = []string{git.WebhookFinalizer}
+	now := metav1.Now()
+	gitSource.DeletionTimestamp = &now
+	reconciler, request, cl := prepareClient(gitSource, newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.NotContains(t, getGitSource(t, cl).Finalizers, git.WebhookFinalizer)
+}
+
+func TestReconcileKeepsFinalizerWhenWebhookDeletionFails(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Delete(fmt.Sprintf("/repos/%s/hooks/42", repoIdentifier)).
+		Reply(422)
+
+	gitSource := newGitSourceRequestingWebhook()
+	gitSource.Finalizers = []string{git.WebhookFinalizer}
+	now := metav1.Now()
+	gitSource.DeletionTimestamp = &now
+	reconciler, request, cl := prepareClient(gitSource, newWebhookSecret("42", targetURL))
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, getGitSource(t, cl).Finalizers, git.WebhookFinalizer)
+	assert.Equal(t, "42", string(getWebhookSecret(t, cl).Data[git.WebhookIDKey]))
+}
+
+func TestReconcileFailsWhenWebhookURLIsNotConfigured(t *testing.T) {
+	// given
+	gitSource := newGitSourceRequestingWebhook()
+	delete(gitSource.Annotations, git.WebhookURLAnnotation)
+	reconciler, request, _ := prepareClient(gitSource)
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the URL of the webhook is not configured")
+}
+
+func TestReconcileIgnoresGitSourceNotRequestingWebhook(t *testing.T) {
+	// given
+	gitSource := newGitSourceRequestingWebhook()
+	delete(gitSource.Annotations, git.WebhookAnnotation)
+	reconciler, request, cl := prepareClient(gitSource)
+
+	// when
+	_, err := reconciler.Reconcile(request)
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, getGitSource(t, cl).Finalizers)
+	err = cl.Get(context.TODO(), webhookSecretName(), &corev1.Secret{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func newGitSourceRequestingWebhook() *v1alpha1.GitSource {
+	gitSource := test.NewGitSource(test.WithURL(repoGitHubURL),
+		test.WithAnnotation(git.WebhookAnnotation, "true"),
+		test.WithAnnotation(git.WebhookURLAnnotation, targetURL))
+	gitSource.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	return gitSource
+}
+
+func newWebhookSecret(id, url string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      webhookSecretName().Name,
+			Namespace: test.Namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(newGitSourceRequestingWebhook(), v1alpha1.SchemeGroupVersion.WithKind("GitSource")),
+			},
+		},
+		Data: map[string][]byte{
+			git.WebhookSecretKey: []byte("some-secret"),
+			git.WebhookIDKey:     []byte(id),
+			git.WebhookURLKey:    []byte(url),
+		},
+	}
+}
+
+func prepareClient(gitSource *v1alpha1.GitSource, secrets ...*corev1.Secret) (*ReconcileGitSourceWebhook, reconcile.Request, client.Client) {
+	coreObjects := []runtime.Object{test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})}
+	for _, secret := range secrets {
+		coreObjects = append(coreObjects, secret)
+	}
+	cl, s := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gitSource),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, coreObjects...))
+
+	return &ReconcileGitSourceWebhook{client: cl, scheme: s}, test.NewReconcileRequest(gitSource.Name), cl
+}
+
+func getGitSource(t *testing.T, cl client.Client) *v1alpha1.GitSource {
+	gitSource := &v1alpha1.GitSource{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: test.Namespace, Name: test.GitSourceName}, gitSource)
+	require.NoError(t, err)
+	return gitSource
+}
+
+func getWebhookSecret(t *testing.T, cl client.Client) *corev1.Secret {
+	secret := &corev1.Secret{}
+	require.NoError(t, cl.Get(context.TODO(), webhookSecretName(), secret))
+	return secret
+}
+
+func webhookSecretName() types.NamespacedName {
+	return types.NamespacedName{Namespace: test.Namespace, Name: test.GitSourceName + "-webhook"}
+}
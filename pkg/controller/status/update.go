@@ -0,0 +1,46 @@
+package status
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Applier applies an already computed change to the given object. The object is not written if an error is returned.
+type Applier func(obj runtime.Object) error
+
+// Updater writes the given object using the client
+type Updater func(cl client.Client, obj runtime.Object) error
+
+// Status writes the object using the status subresource, so any spec changes made in the meantime are not overwritten
+func Status(cl client.Client, obj runtime.Object) error {
+	return cl.Status().Update(context.TODO(), obj)
+}
+
+// Object writes the whole object - it is used for the changes that cannot be written through the status subresource
+// (e.g. annotations or the data of a secret)
+func Object(cl client.Client, obj runtime.Object) error {
+	return cl.Update(context.TODO(), obj)
+}
+
+// UpdateWithRetry applies the change to the given object and writes it using the given updater. If the write fails
+// because of a conflict, then the latest version of the object is fetched, the same change is applied to it
+// and the write is retried.
+func UpdateWithRetry(cl client.Client, key types.NamespacedName, obj runtime.Object, update Updater, apply Applier) error {
+	refetch := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refetch {
+			if err := cl.Get(context.TODO(), key, obj); err != nil {
+				return err
+			}
+		}
+		refetch = true
+		if err := apply(obj); err != nil {
+			return err
+		}
+		return update(cl, obj)
+	})
+}
@@ -0,0 +1,93 @@
+package connection
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+)
+
+// smartAdvertisementContentType is the content type of the references advertised by servers supporting
+// the smart HTTP protocol
+const smartAdvertisementContentType = "application/x-git-upload-pack-advertisement"
+
+// parseAdvertisedRefs returns names of the references listed in the response of the info/refs endpoint.
+// The servers supporting the smart HTTP protocol send the references as pkt-lines, the dumb servers send
+// the content of the info/refs file.
+func parseAdvertisedRefs(contentType string, body []byte) (map[string]bool, error) {
+	if contentType != smartAdvertisementContentType && isDumbAdvertisement(body) {
+		return parseDumbRefs(body)
+	}
+	return parseSmartRefs(body)
+}
+
+// parseSmartRefs decodes the pkt-lines "<hash> SP <name>", the first of them followed by NUL and the capabilities.
+// The service announcement and the flush-pkts are skipped.
+func parseSmartRefs(body []byte) (map[string]bool, error) {
+	refs := map[string]bool{}
+	scanner := pktline.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		if bytes.HasPrefix(line, []byte("ERR ")) {
+			return nil, fmt.Errorf("the git server responded with an error: %s", strings.TrimSpace(string(line[4:])))
+		}
+		if nul := bytes.IndexByte(line, 0); nul >= 0 {
+			line = line[:nul]
+		}
+		name, err := parseRef(strings.TrimSuffix(string(line), "\n"), " ")
+		if err != nil {
+			return nil, err
+		}
+		refs[name] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("invalid pkt-line: %s", err)
+	}
+	return refs, nil
+}
+
+// parseDumbRefs reads the lines "<hash> TAB <name>" of the info/refs file
+func parseDumbRefs(body []byte) (map[string]bool, error) {
+	refs := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+		name, err := parseRef(scanner.Text(), "\t")
+		if err != nil {
+			return nil, err
+		}
+		refs[name] = true
+	}
+	return refs, scanner.Err()
+}
+
+func isDumbAdvertisement(body []byte) bool {
+	return len(body) > hashLength && body[hashLength] == '\t' && isHash(string(body[:hashLength]))
+}
+
+const hashLength = 40
+
+// parseRef returns the name of the reference from the line consisting of the hash and the name
+func parseRef(line, separator string) (string, error) {
+	hashAndName := strings.SplitN(line, separator, 2)
+	if len(hashAndName) != 2 || !isHash(hashAndName[0]) || hashAndName[1] == "" {
+		return "", fmt.Errorf("invalid reference line %q", line)
+	}
+	return hashAndName[1], nil
+}
+
+func isHash(value string) bool {
+	if len(value) != hashLength {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,148 @@
+package connection
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// classifyError returns a ValidationError with the most specific reason that can be derived from the given error.
+// If the error cannot be classified, then the default reason is used together with the given description
+// of the failed action.
+func classifyError(ctx context.Context, err error, defaultReason v1alpha1.ConnectionFailureReason,
+	action string) ValidationError {
+
+	if transientErr := newTransientError(ctx, err); transientErr != nil {
+		return transientErr
+	}
+	if git.IsHostKeyError(err) {
+		return newValidationErrorf(HostKeyMismatch,
+			"unable to verify the host key - check the known hosts of the secret or of the cluster: %s", err)
+	}
+	if apiErr := repository.AsAPIError(err); apiErr != nil {
+		return classifyAPIError(apiErr, defaultReason, action)
+	}
+
+	cause := rootCause(err)
+	message := err.Error()
+	if _, ok := cause.(*net.DNSError); ok || strings.Contains(message, "no such host") {
+		return newValidationErrorf(HostNotFound, "unable to resolve the host - check the URL: %s", err)
+	}
+	if isTLSError(cause) {
+		return newValidationErrorf(TLSVerificationFailed,
+			"unable to verify the certificate of the git server - check the CA bundle: %s", err)
+	}
+	switch cause {
+	case gittransport.ErrRepositoryNotFound:
+		return newValidationErrorf(notFoundReason(defaultReason),
+			"the repository doesn't exist or the credentials don't grant access to it - check the URL: %s", err)
+	case gittransport.ErrAuthenticationRequired:
+		return newValidationErrorf(v1alpha1.BadCredentials,
+			"the git server requires authentication - provide a secret with valid credentials: %s", err)
+	case gittransport.ErrAuthorizationFailed:
+		return newValidationErrorf(Forbidden,
+			"the credentials don't grant access to the repository - check permissions of the account: %s", err)
+	}
+	if strings.Contains(message, "ssh: unable to authenticate") {
+		return newValidationErrorf(v1alpha1.BadCredentials,
+			"the ssh key or the password was rejected by the git server - check the secret: %s", err)
+	}
+	if strings.Contains(message, "ssh: handshake failed") {
+		return newValidationErrorf(SshHandshakeFailed, "unable to establish the ssh connection: %s", err)
+	}
+	return newValidationErrorf(defaultReason, "%s: %s", action, err)
+}
+
+func classifyAPIError(apiErr *repository.APIError, defaultReason v1alpha1.ConnectionFailureReason,
+	action string) ValidationError {
+
+	switch {
+	case isInsufficientScope(apiErr):
+		return newValidationErrorf(InsufficientScope,
+			"the token doesn't have the scopes required to read the repository - issue a token with broader scopes: %s",
+			apiErr)
+	case apiErr.StatusCode == http.StatusUnauthorized:
+		return newValidationErrorf(v1alpha1.BadCredentials,
+			"the credentials were rejected by the git server - check that the token or the password is valid "+
+				"and hasn't expired: %s", apiErr)
+	case apiErr.StatusCode == http.StatusForbidden:
+		return newValidationErrorf(Forbidden,
+			"the credentials don't grant access to the repository - check permissions of the account: %s", apiErr)
+	case apiErr.StatusCode == http.StatusNotFound:
+		if defaultReason == v1alpha1.BranchNotFound {
+			return newValidationErrorf(v1alpha1.BranchNotFound, "%s: %s", action, apiErr)
+		}
+		return newValidationErrorf(RepoNotFound,
+			"the repository doesn't exist or the credentials don't grant access to it - check the URL: %s", apiErr)
+	}
+	return newValidationErrorf(defaultReason, "%s: %s", action, apiErr)
+}
+
+// isInsufficientScope returns true if the API says that the token doesn't have the required scopes.
+// GitHub sends the accepted and the granted scopes in headers, GitLab uses the WWW-Authenticate header
+// and Bitbucket describes it in the message.
+func isInsufficientScope(apiErr *repository.APIError) bool {
+	if apiErr.StatusCode != http.StatusForbidden && apiErr.StatusCode != http.StatusNotFound {
+		return false
+	}
+	if strings.Contains(apiErr.Header.Get("WWW-Authenticate"), "insufficient_scope") {
+		return true
+	}
+	if accepted := repository.ParseScopes(apiErr.Header.Get("X-Accepted-OAuth-Scopes")); len(accepted) > 0 {
+		granted, present := apiErr.Header["X-Oauth-Scopes"]
+		if !present {
+			// the request wasn't authenticated using an OAuth token
+			return false
+		}
+		for scope := range repository.ParseScopes(strings.Join(granted, ",")) {
+			if accepted[scope] {
+				return false
+			}
+		}
+		return true
+	}
+	return apiErr.StatusCode == http.StatusForbidden && strings.Contains(apiErr.Error(), "privilege scopes")
+}
+
+func notFoundReason(defaultReason v1alpha1.ConnectionFailureReason) v1alpha1.ConnectionFailureReason {
+	if defaultReason == v1alpha1.BranchNotFound {
+		return v1alpha1.BranchNotFound
+	}
+	return RepoNotFound
+}
+
+// rootCause unwraps the errors of the http client, of the network operations and of go-git
+func rootCause(err error) error {
+	for {
+		switch wrapper := err.(type) {
+		case *url.Error:
+			err = wrapper.Err
+		case *net.OpError:
+			err = wrapper.Err
+		case *plumbing.UnexpectedError:
+			err = wrapper.Err
+		case *plumbing.PermanentError:
+			err = wrapper.Err
+		default:
+			return err
+		}
+	}
+}
+
+func isTLSError(err error) bool {
+	switch err.(type) {
+	case x509.UnknownAuthorityError, x509.HostnameError, x509.CertificateInvalidError, tls.RecordHeaderError:
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "x509: ") || strings.HasPrefix(err.Error(), "tls: ")
+}
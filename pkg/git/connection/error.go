@@ -1,8 +1,41 @@
 package connection
 
 import (
+	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"time"
+)
+
+// The following reasons are not part of the v1alpha1 API yet.
+const (
+	// HostKeyMismatch is a reason used when the key presented by a ssh server doesn't match any of the known hosts.
+	HostKeyMismatch v1alpha1.ConnectionFailureReason = "HostKeyMismatch"
+	// Timeout is a reason used when the git server didn't respond within the operation deadline.
+	Timeout v1alpha1.ConnectionFailureReason = "Timeout"
+	// RateLimited is a reason used when the rate limit of the git server is exhausted. The validation should be
+	// repeated after the limit is reset.
+	RateLimited v1alpha1.ConnectionFailureReason = "RateLimited"
+	// HostNotFound is a reason used when the host of the git repository cannot be resolved.
+	HostNotFound v1alpha1.ConnectionFailureReason = "HostNotFound"
+	// TLSVerificationFailed is a reason used when the certificate of the git server cannot be verified.
+	TLSVerificationFailed v1alpha1.ConnectionFailureReason = "TLSVerificationFailed"
+	// RepoNotFound is a reason used when the git server says that the repository doesn't exist (or it is private
+	// and the credentials don't grant access to it).
+	RepoNotFound v1alpha1.ConnectionFailureReason = "RepoNotFound"
+	// Forbidden is a reason used when the credentials are valid but they don't grant access to the repository.
+	Forbidden v1alpha1.ConnectionFailureReason = "Forbidden"
+	// InsufficientScope is a reason used when the token is valid but it doesn't have the scopes required
+	// to read the repository.
+	InsufficientScope v1alpha1.ConnectionFailureReason = "InsufficientScope"
+	// SshHandshakeFailed is a reason used when the ssh connection cannot be established for other reason than
+	// rejected credentials or mismatching host key.
+	SshHandshakeFailed v1alpha1.ConnectionFailureReason = "SshHandshakeFailed"
+	// LimitedPermissions is a reason used when the repository can be read but the credentials don't grant some
+	// of the other permissions the operator uses (e.g. creating webhooks). It is reported by a warning so the connection
+	// doesn't fail.
+	LimitedPermissions v1alpha1.ConnectionFailureReason = "LimitedPermissions"
 )
 
 // ValidationError holds message and reason of an error that occurred during a connection validation
@@ -14,6 +47,8 @@ type ValidationError interface {
 type validationError struct {
 	message string
 	reason  v1alpha1.ConnectionFailureReason
+	retryAt time.Time
+	warning bool
 }
 
 func (e *validationError) Error() string {
@@ -27,3 +62,33 @@ func (e *validationError) Reason() v1alpha1.ConnectionFailureReason {
 func newValidationErrorf(reason v1alpha1.ConnectionFailureReason, message string, args ...interface{}) ValidationError {
 	return &validationError{message: fmt.Sprintf(message, args...), reason: reason}
 }
+
+func newValidationWarningf(reason v1alpha1.ConnectionFailureReason, message string, args ...interface{}) ValidationError {
+	return &validationError{message: fmt.Sprintf(message, args...), reason: reason, warning: true}
+}
+
+// IsWarning returns true if the given error doesn't make the connection fail, but it should be reported to the user
+func IsWarning(err ValidationError) bool {
+	validationErr, ok := err.(*validationError)
+	return ok && validationErr.warning
+}
+
+// RetryAt returns the time when the validation that failed because of an exhausted rate limit can be repeated
+func RetryAt(err ValidationError) (time.Time, bool) {
+	if validationErr, ok := err.(*validationError); ok && validationErr.reason == RateLimited {
+		return validationErr.retryAt, true
+	}
+	return time.Time{}, false
+}
+
+// newTransientError returns an error with the Timeout or RateLimited reason if the given error was caused by
+// exceeded deadline or by an exhausted rate limit, nil otherwise
+func newTransientError(ctx context.Context, err error) ValidationError {
+	if rateLimitErr := git.AsRateLimitError(err); rateLimitErr != nil {
+		return &validationError{message: rateLimitErr.Error(), reason: RateLimited, retryAt: rateLimitErr.Reset}
+	}
+	if git.IsTimeout(ctx, err) {
+		return newValidationErrorf(Timeout, "the git server didn't respond in time: %s", err.Error())
+	}
+	return nil
+}
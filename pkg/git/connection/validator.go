@@ -1,62 +1,85 @@
 package connection
 
 import (
-	"fmt"
+	"context"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/bitbucket"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/generic"
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/github"
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/gitlab"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"regexp"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
-var gitServiceCreators = []repository.ServiceCreator{
-	github.NewRepoServiceIfMatches(),
-	bitbucket.NewRepoServiceIfMatches(),
-	gitlab.NewRepoServiceIfMatches(),
-}
-
 // ValidateGitSource validates if a git repository defined by the given GitSource is reachable
 // and if it contains the defined branch (master if empty)
-func ValidateGitSource(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource) ValidationError {
-	endpoint, err := gittransport.NewEndpoint(gitSource.Spec.URL)
+func ValidateGitSource(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource) ValidationError {
+	return ValidateGitSourceWithSettings(ctx, log, gitSource, nil)
+}
+
+// ValidateGitSourceWithSettings validates if a git repository defined by the given GitSource is reachable
+// and if it contains the defined branch (master if empty). The connection respects the given transport settings.
+// The repositories with http(s) URLs are validated by reading the advertised references the same way as git clients
+// do, the other repositories (ssh, git daemon) by listing the references using the git protocol.
+func ValidateGitSourceWithSettings(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	settings *git.TransportSettings) ValidationError {
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
 	if err != nil {
 		return newValidationErrorf(v1alpha1.RepoNotReachable, "unable to parse the URL: %s", err.Error())
 	}
-	if endpoint.Host == "" {
+	if gitURL.Host == "" {
 		return newValidationErrorf(v1alpha1.RepoNotReachable, "the URL doesn't contain host")
 	}
-	client := &http.Client{}
-	path := endpoint.Path
-	if strings.HasSuffix(path, "/") {
-		path = path[:len(path)-1]
+	switch gitURL.Protocol {
+	case "http", "https":
+		return validateOverHTTP(ctx, log, gitSource.Spec.Ref, gitURL.Endpoint(), settings)
+	case "ssh", "git":
+		service, err := generic.NewRepositoryService(gitSource, git.NewSecretProviderWithSettings(nil, settings))
+		if err != nil {
+			return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
+		}
+		return validateUsingService(ctx, log, service)
 	}
-	if !strings.HasSuffix(path, ".git") {
-		path += ".git"
+	return newValidationErrorf(v1alpha1.RepoNotReachable, "unsupported protocol %s", gitURL.Protocol)
+}
+
+// validateOverHTTP requests the references from the info/refs endpoint of the repository. The protocol and the port
+// of the URL are kept.
+func validateOverHTTP(ctx context.Context, log *log.GitSourceLogger, branch string, endpoint *gittransport.Endpoint,
+	settings *git.TransportSettings) ValidationError {
+
+	infoRefsURL := &url.URL{
+		Scheme:   endpoint.Protocol,
+		Host:     endpoint.Host,
+		Path:     "/" + strings.Trim(endpoint.Path, "/") + "/info/refs",
+		RawQuery: "service=" + gittransport.UploadPackServiceName,
 	}
-	if !strings.HasPrefix(path, "/") {
-		path = "/" + path
+	if endpoint.Port > 0 {
+		infoRefsURL.Host = net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
 	}
-	url := fmt.Sprintf("https://%s%s/info/refs?service=git-upload-pack", endpoint.Host, path)
-	resp, err := client.Get(url)
+	req, err := http.NewRequest(http.MethodGet, infoRefsURL.String(), nil)
 	if err != nil {
 		return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
 	}
-	return validateBranch(log, gitSource.Spec.Ref, resp)
+	resp, err := settings.HTTPClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return classifyError(ctx, err, v1alpha1.RepoNotReachable, "unable to reach the URL")
+	}
+	return validateBranch(ctx, log, branch, resp)
 }
 
-func validateBranch(log *log.GitSourceLogger, branch string, resp *http.Response) ValidationError {
+func validateBranch(ctx context.Context, log *log.GitSourceLogger, branch string, resp *http.Response) ValidationError {
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Error(err, "error while reading body")
-		return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
+		return classifyError(ctx, err, v1alpha1.RepoNotReachable, "unable to read the response")
 	}
 	err = resp.Body.Close()
 	if err != nil {
@@ -64,19 +87,27 @@ func validateBranch(log *log.GitSourceLogger, branch string, resp *http.Response
 		return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusNotFound:
+		return newValidationErrorf(RepoNotFound, "server responded with %s - the repository doesn't exist "+
+			"or it is private and a secret with credentials is required", resp.Status)
+	case http.StatusForbidden:
+		return newValidationErrorf(Forbidden, "server responded with %s - the repository cannot be accessed "+
+			"anonymously, a secret with credentials is required", resp.Status)
+	default:
 		return newValidationErrorf(v1alpha1.RepoNotReachable, "server responded with %s", resp.Status)
 	}
+	refs, err := parseAdvertisedRefs(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		log.Error(err, "error while parsing the advertised references")
+		return newValidationErrorf(v1alpha1.RepoNotReachable,
+			"unable to read the references advertised by the server: %s", err.Error())
+	}
 	if branch == "" {
 		branch = repository.Master
 	}
-	branchRefRegexp := fmt.Sprintf(`\ refs\/heads\/%s\n`, branch)
-	compile, err := regexp.Compile(branchRefRegexp)
-	if err != nil {
-		log.Error(err, "error while parsing regexp of the given branch")
-		return newValidationErrorf(v1alpha1.BranchNotFound, "cannot parse the branch: %s", err.Error())
-	}
-	if compile.Find(body) != nil {
+	if refs["refs/heads/"+branch] {
 		return nil
 	}
 	return newValidationErrorf(v1alpha1.BranchNotFound, "cannot find the branch")
@@ -84,11 +115,21 @@ func validateBranch(log *log.GitSourceLogger, branch string, resp *http.Response
 
 // ValidateGitSourceWithSecret detects build tools and languages using the given secret in the git repository
 // defined by the given v1alpha1.GitSource
-func ValidateGitSourceWithSecret(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret git.Secret) ValidationError {
-	return validateGitSourceWithSecret(log, gitSource, git.NewSecretProvider(secret), gitServiceCreators)
+func ValidateGitSourceWithSecret(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secret git.Secret) ValidationError {
+	return validateGitSourceWithSecret(ctx, log, gitSource, git.NewSecretProvider(secret),
+		repository.DefaultRegistry().ServiceCreators())
+}
+
+// ValidateGitSourceWithSecretProvider validates the git repository defined by the given v1alpha1.GitSource
+// using the secret and the transport settings of the given provider
+func ValidateGitSourceWithSecretProvider(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) ValidationError {
+	return validateGitSourceWithSecret(ctx, log, gitSource, secretProvider, repository.DefaultRegistry().ServiceCreators())
 }
 
-func validateGitSourceWithSecret(log *log.GitSourceLogger,
+func validateGitSourceWithSecret(ctx context.Context,
+	log *log.GitSourceLogger,
 	gitSource *v1alpha1.GitSource,
 	secretProvider *git.SecretProvider,
 	serviceCreators []repository.ServiceCreator) ValidationError {
@@ -98,19 +139,55 @@ func validateGitSourceWithSecret(log *log.GitSourceLogger,
 		return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
 	}
 	if service == nil {
+		// ssh keys are used only by the git protocol - the references are listed over ssh
 		service, err = generic.NewRepositoryService(gitSource, secretProvider)
 		if err != nil {
 			return newValidationErrorf(v1alpha1.RepoNotReachable, err.Error())
 		}
 	}
-	if err := service.CheckCredentials(); err != nil {
-		return newValidationErrorf(v1alpha1.BadCredentials, "cannot get user information: %s", err.Error())
+	return validateUsingService(ctx, log, service)
+}
+
+// validateUsingService checks the credentials, the repository and the branch using the given service
+func validateUsingService(ctx context.Context, log *log.GitSourceLogger, service repository.GitService) ValidationError {
+	if err := service.CheckCredentials(ctx); err != nil {
+		return classifyError(ctx, err, v1alpha1.BadCredentials, "cannot get user information")
+	}
+	if err := service.CheckRepoAccessibility(ctx); err != nil {
+		return classifyError(ctx, err, v1alpha1.RepoNotReachable, "unable to reach the URL")
 	}
-	if err := service.CheckRepoAccessibility(); err != nil {
-		return newValidationErrorf(v1alpha1.RepoNotReachable, "unable to reach the URL: %s", err.Error())
+	if err := service.CheckBranch(ctx); err != nil {
+		return classifyError(ctx, err, v1alpha1.BranchNotFound, "unable to find the branch")
+	}
+	missing, err := service.CheckPermissions(ctx)
+	if err != nil {
+		// the repository is accessible so the connection shouldn't fail only because the permissions are unknown
+		log.Error(err, "unable to check permissions of the credentials")
+		return nil
+	}
+	return validatePermissions(missing)
+}
+
+// validatePermissions returns an error if the credentials don't allow to read the repository
+// or a warning if they don't grant the other permissions the operator uses
+func validatePermissions(missing []repository.MissingPermission) ValidationError {
+	if len(missing) == 0 {
+		return nil
+	}
+	var descriptions []string
+	fatal := false
+	for _, permission := range missing {
+		descriptions = append(descriptions, permission.String())
+		if permission.Permission != repository.CreateWebhooks {
+			fatal = true
+		}
 	}
-	if err := service.CheckBranch(); err != nil {
-		return newValidationErrorf(v1alpha1.BranchNotFound, "unable to find the branch: %s", err.Error())
+	if fatal {
+		return newValidationErrorf(InsufficientScope,
+			"the credentials don't grant the permissions required to read the repository - missing: %s",
+			strings.Join(descriptions, ", "))
 	}
-	return nil
+	return newValidationWarningf(LimitedPermissions,
+		"the repository can be read, but the credentials don't grant all permissions - missing: %s",
+		strings.Join(descriptions, ", "))
 }
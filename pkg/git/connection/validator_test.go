@@ -1,6 +1,8 @@
 package connection_test
 
 import (
+	"context"
+	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/connection"
@@ -9,9 +11,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/h2non/gock.v1"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 var (
@@ -19,6 +26,8 @@ var (
 	homeDir = os.Getenv("HOME")
 )
 
+const pathToTestDir = "../../test"
+
 //
 // Tests without secret
 //
@@ -30,7 +39,7 @@ func TestIsReachableRealGHRepoWithDevelopBranch(t *testing.T) {
 		test.WithRef("develop"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	assert.NoError(t, validationErr)
@@ -42,7 +51,7 @@ func TestIsReachableRealGHRepoUrlEndingWithSlash(t *testing.T) {
 		test.WithURL("https://github.com/fabric8-services/fabric8-tenant/"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	assert.NoError(t, validationErr)
@@ -53,7 +62,7 @@ func TestIsReachableRealGLRepoWithNoBranchSet(t *testing.T) {
 	gitSource := test.NewGitSource(test.WithURL("https://gitlab.com/matousjobanek/quarkus-knative"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	assert.NoError(t, validationErr)
@@ -64,7 +73,7 @@ func TestIsReachableRealBBRepoWithNoBranchSet(t *testing.T) {
 	gitSource := test.NewGitSource(test.WithURL("https://bitbucket.org/mjobanek-rh/quarkus-knative"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	assert.NoError(t, validationErr)
@@ -75,11 +84,11 @@ func TestIsReachableRealGHNonExistingRepo(t *testing.T) {
 	gitSource := test.NewGitSource(test.WithURL("https://github.com/no-owner/no-repo"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	require.Error(t, validationErr)
-	assert.Equal(t, v1alpha1.RepoNotReachable, validationErr.Reason())
+	assert.Equal(t, connection.RepoNotFound, validationErr.Reason())
 }
 
 func TestIsReachableRealGLRepoWithNonExistingBranch(t *testing.T) {
@@ -89,7 +98,7 @@ func TestIsReachableRealGLRepoWithNonExistingBranch(t *testing.T) {
 		test.WithRef("some-cool-branch"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	require.Error(t, validationErr)
@@ -105,21 +114,19 @@ func TestIsReachableRealRepoWithDevelopBranch(t *testing.T) {
 			"https://github.com/some-owner/some-repo.git",
 			"http://github.com/some-owner/some-repo",
 			"http://github.com/some-owner/some-repo.git",
-			"git@github.com:some-owner/some-repo",
-			"git@github.com:some-owner/some-repo.git",
 			"https://matousjobanek@github.com/some-owner/some-repo.git"} {
 
 			gitSource := test.NewGitSource(test.WithURL(url), test.WithRef(branch))
-			gock.New("https://github.com").
-				Get("/some-owner/some-repo.git/info/refs").
+			scheme := strings.SplitN(url, ":", 2)[0]
+			gock.New(scheme+"://github.com").
+				Get("/some-owner/some-repo(.git)?/info/refs").
 				MatchParam("service", "git-upload-pack").
 				Reply(200).
-				BodyString(`004a8d501bc8f3a77129c17a7120bac2d4d70f4d9291 refs/heads/master
-003f8c48499a598266ed7ef609070b84d2c8707fb1dd refs/heads/dev
-0000`)
+				SetHeader("Content-Type", "application/x-git-upload-pack-advertisement").
+				BodyString(test.SmartInfoRefs("HEAD", "refs/heads/master", "refs/heads/dev"))
 
 			// when
-			validationErr := connection.ValidateGitSource(logger, gitSource)
+			validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 			// then
 			assert.Nil(t, validationErr)
@@ -127,12 +134,132 @@ func TestIsReachableRealRepoWithDevelopBranch(t *testing.T) {
 	}
 }
 
+func TestIsReachableOverPlainHTTPWithPort(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, "master")
+	dummyRepo.Commit("main.go")
+	server, repoURL := test.RunGitServer(t, dummyRepo.Path)
+	defer server.Close()
+
+	for branch, reason := range map[string]v1alpha1.ConnectionFailureReason{
+		"master":  "",
+		"missing": v1alpha1.BranchNotFound} {
+
+		gitSource := test.NewGitSource(test.WithURL(repoURL), test.WithRef(branch))
+
+		// when
+		validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+		// then
+		if reason == "" {
+			assert.Nil(t, validationErr, branch)
+		} else {
+			require.Error(t, validationErr, branch)
+			assert.Equal(t, reason, validationErr.Reason(), branch)
+		}
+	}
+}
+
+func TestIsReachableOverDumbHTTP(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/some-owner/some-repo.git/info/refs" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "8d501bc8f3a77129c17a7120bac2d4d70f4d9291\trefs/heads/dev\n"+
+			"8c48499a598266ed7ef609070b84d2c8707fb1dd\trefs/heads/master\n")
+	}))
+	defer server.Close()
+
+	for branch, reason := range map[string]v1alpha1.ConnectionFailureReason{
+		"master":  "",
+		"dev":     "",
+		"missing": v1alpha1.BranchNotFound} {
+
+		gitSource := test.NewGitSource(test.WithURL(server.URL+"/some-owner/some-repo.git"), test.WithRef(branch))
+
+		// when
+		validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+		// then
+		if reason == "" {
+			assert.Nil(t, validationErr, branch)
+		} else {
+			require.Error(t, validationErr, branch)
+			assert.Equal(t, reason, validationErr.Reason(), branch)
+		}
+	}
+}
+
+func TestIsReachableWithInvalidAdvertisement(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html>some login page</html>")
+	}))
+	defer server.Close()
+	gitSource := test.NewGitSource(test.WithURL(server.URL + "/some-owner/some-repo.git"))
+
+	// when
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, v1alpha1.RepoNotReachable, validationErr.Reason())
+}
+
+func TestIsReachableOverGitProtocol(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, "master")
+	dummyRepo.Commit("main.go")
+	repoURL, stop := test.RunGitDaemon(t, dummyRepo.Path)
+	defer stop()
+
+	for url, reason := range map[string]v1alpha1.ConnectionFailureReason{
+		repoURL:              "",
+		repoURL + "-missing": connection.RepoNotFound} {
+
+		gitSource := test.NewGitSource(test.WithURL(url))
+
+		// when
+		validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+		// then
+		if reason == "" {
+			assert.Nil(t, validationErr, url)
+		} else {
+			require.Error(t, validationErr, url)
+			assert.Equal(t, reason, validationErr.Reason(), url)
+		}
+	}
+}
+
+func TestIsReachableWithSshURLRequiresSshKey(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	for _, url := range []string{
+		"git@github.com:some-owner/some-repo.git",
+		"ssh://git@github.com/some-owner/some-repo.git"} {
+
+		gitSource := test.NewGitSource(test.WithURL(url))
+
+		// when
+		validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+		// then
+		require.Error(t, validationErr)
+		assert.Equal(t, v1alpha1.BadCredentials, validationErr.Reason())
+		assert.False(t, gock.HasUnmatchedRequest())
+	}
+}
+
 func TestIsReachableForWrongURL(t *testing.T) {
 	// given
 	gitSource := test.NewGitSource(test.WithURL("some-wrong-url.com"))
 
 	// when
-	validationErr := connection.ValidateGitSource(logger, gitSource)
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
 
 	// then
 	require.Error(t, validationErr)
@@ -149,7 +276,7 @@ func TestValidateRealGitHubInvalidSecret(t *testing.T) {
 		test.WithURL("https://github.com/MatousJobanek/quarkus-knative"))
 
 	// when
-	validationErr := connection.ValidateGitSourceWithSecret(logger, glSource, git.NewOauthToken([]byte("some-token")))
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource, git.NewOauthToken([]byte("some-token")))
 
 	// then
 	require.Error(t, validationErr)
@@ -167,7 +294,7 @@ func TestValidateGitLabSecretAndAvailableRepo(t *testing.T) {
 		BodyString("{}")
 
 	// when
-	err := connection.ValidateGitSourceWithSecret(logger, glSource, git.NewOauthToken([]byte("")))
+	err := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource, git.NewOauthToken([]byte("")))
 
 	// then
 	require.NoError(t, err)
@@ -186,7 +313,7 @@ func TestValidateGitLabSecretAndAvailableRepoWithWrongBranch(t *testing.T) {
 		BodyString("{}")
 
 	// when
-	validationErr := connection.ValidateGitSourceWithSecret(logger, glSource, git.NewOauthToken([]byte("")))
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource, git.NewOauthToken([]byte("")))
 
 	// then
 	require.Error(t, validationErr)
@@ -199,13 +326,266 @@ func TestValidateBitBucketWrongRepo(t *testing.T) {
 	glSource := test.NewGitSource(test.WithURL("https://bitbucket.org/some-org/some-repo"))
 	gock.New("https://api.bitbucket.org/").
 		Get("/2.0/user").
-		SetMatcher(test.TurnOffAllGockWhenMatched()).
 		Reply(200)
+	gock.New("https://api.bitbucket.org/").
+		Get("/2.0/repositories/some-org/some-repo/").
+		Reply(404).
+		BodyString(`{"type": "error", "error": {"message": "Repository some-org/some-repo not found"}}`)
 
 	// when
-	validationErr := connection.ValidateGitSourceWithSecret(logger, glSource, git.NewOauthToken([]byte("")))
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource, git.NewOauthToken([]byte("")))
 
 	// then
 	require.Error(t, validationErr)
-	assert.Equal(t, v1alpha1.RepoNotReachable, validationErr.Reason())
+	assert.Equal(t, connection.RepoNotFound, validationErr.Reason())
+	assert.Contains(t, validationErr.Error(), "Repository some-org/some-repo not found")
+}
+
+func TestValidateSelfHostedGitLabWithCABundle(t *testing.T) {
+	// given
+	server := test.RunTLSServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, "{}")
+	})
+	defer server.Close()
+	glSource := test.NewGitSource(
+		test.WithURL(server.URL+"/matousjobanek/quarkus-knative"),
+		test.WithFlavor("gitlab"))
+	settings, err := git.NewTransportSettings().WithCABundle(test.CACert(server))
+	require.NoError(t, err)
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, glSource,
+		git.NewSecretProviderWithSettings(git.NewOauthToken([]byte("some-token")), settings))
+
+	// then
+	require.NoError(t, validationErr)
+}
+
+func TestValidateSelfHostedGitLabWithUnknownCA(t *testing.T) {
+	// given
+	server := test.RunTLSServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "{}")
+	})
+	defer server.Close()
+	glSource := test.NewGitSource(
+		test.WithURL(server.URL+"/matousjobanek/quarkus-knative"),
+		test.WithFlavor("gitlab"))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, glSource,
+		git.NewSecretProviderWithSettings(git.NewOauthToken([]byte("some-token")), git.NewTransportSettings()))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.TLSVerificationFailed, validationErr.Reason())
+	assert.Contains(t, validationErr.Error(), "certificate signed by unknown authority")
+}
+
+func TestValidateUnresponsiveServerTimesOut(t *testing.T) {
+	// given
+	server := test.RunUnresponsiveHTTPServer()
+	defer server.Close()
+	glSource := test.NewGitSource(
+		test.WithURL(server.URL+"/matousjobanek/quarkus-knative"),
+		test.WithFlavor("gitlab"))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecret(ctx, logger, glSource, git.NewOauthToken([]byte("some-token")))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.Timeout, validationErr.Reason())
+}
+
+func TestIsReachableUnresponsiveServerTimesOut(t *testing.T) {
+	// given
+	server := test.RunTLSServer(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	defer server.Close()
+	gitSource := test.NewGitSource(test.WithURL(server.URL + "/matousjobanek/quarkus-knative"))
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSettings(ctx, logger, gitSource,
+		git.NewTransportSettings().WithInsecureSkipTLSVerify())
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.Timeout, validationErr.Reason())
+}
+
+func TestValidateRateLimitedServer(t *testing.T) {
+	// given
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := test.RunTLSServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer server.Close()
+	glSource := test.NewGitSource(
+		test.WithURL(server.URL+"/matousjobanek/quarkus-knative"),
+		test.WithFlavor("gitlab"))
+	settings := git.NewTransportSettings().
+		WithInsecureSkipTLSVerify().
+		WithRetryPolicy(git.NewDefaultRetryPolicy())
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, glSource,
+		git.NewSecretProviderWithSettings(git.NewOauthToken([]byte("some-token")), settings))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.RateLimited, validationErr.Reason())
+	retryAt, limited := connection.RetryAt(validationErr)
+	assert.True(t, limited)
+	assert.True(t, reset.Equal(retryAt))
+}
+
+func TestValidateUnresolvableHost(t *testing.T) {
+	// given
+	gitSource := test.NewGitSource(test.WithURL("https://git.example.invalid/owner/repo"))
+
+	// when
+	validationErr := connection.ValidateGitSource(context.Background(), logger, gitSource)
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.HostNotFound, validationErr.Reason())
+}
+
+func TestValidateGitHubTokenWithInsufficientScope(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://api.github.com").
+		Get("/user").
+		Reply(200).
+		BodyString("{}")
+	gock.New("https://api.github.com").
+		Get(fmt.Sprintf("/repos/%s", "MatousJobanek/quarkus-knative")).
+		Reply(404).
+		SetHeader("X-Accepted-OAuth-Scopes", "repo").
+		SetHeader("X-OAuth-Scopes", "read:user").
+		BodyString(`{"message": "Not Found"}`)
+	ghSource := test.NewGitSource(test.WithURL("https://github.com/MatousJobanek/quarkus-knative"))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, ghSource,
+		git.NewOauthToken([]byte("some-token")))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.InsufficientScope, validationErr.Reason())
+}
+
+func TestValidateGitLabExpiredToken(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://gitlab.com/").
+		Get("/api/v4/user").
+		Reply(401).
+		SetHeader("WWW-Authenticate", `Bearer realm="", error="invalid_token", error_description="Token is expired."`).
+		BodyString(`{"error": "invalid_token", "error_description": "Token is expired."}`)
+	glSource := test.NewGitSource(test.WithURL("https://gitlab.com/matousjobanek/quarkus-knative"))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource,
+		git.NewOauthToken([]byte("some-token")))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, v1alpha1.BadCredentials, validationErr.Reason())
+	assert.Contains(t, validationErr.Error(), "hasn't expired")
+}
+
+func TestValidateGitLabForbiddenRepo(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://gitlab.com/").
+		Get("/api/v4/user").
+		Reply(200).
+		BodyString("{}")
+	gock.New("https://gitlab.com/").
+		Get("/api/v4/projects/matousjobanek/quarkus-knative").
+		Reply(403).
+		BodyString(`{"message": "403 Forbidden"}`)
+	glSource := test.NewGitSource(test.WithURL("https://gitlab.com/matousjobanek/quarkus-knative"))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecret(context.Background(), logger, glSource,
+		git.NewOauthToken([]byte("some-token")))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.Forbidden, validationErr.Reason())
+}
+
+func TestValidateGenericGitWithWrongPassword(t *testing.T) {
+	// given
+	reset := test.RunBasicSshServer(t, "super-secret")
+	defer reset()
+	dummyRepo := test.NewDummyGitRepo(t, "master")
+	dummyRepo.Commit("main.go")
+	gitSource := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	settings := git.NewTransportSettings().WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts()))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, gitSource,
+		git.NewSecretProviderWithSettings(git.NewUsernamePassword("git", "wrong"), settings))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, v1alpha1.BadCredentials, validationErr.Reason())
+}
+
+func TestValidateGenericGitWithSshKey(t *testing.T) {
+	// given
+	reset := test.RunKeySshServer(t, test.PublicWithoutPassphrase(t, pathToTestDir))
+	defer reset()
+	dummyRepo := test.NewDummyGitRepo(t, "master")
+	dummyRepo.Commit("main.go")
+	dummyRepo.CheckoutBranch("dev")
+	dummyRepo.Commit("pom.xml")
+	settings := git.NewTransportSettings().WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts()))
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+
+	for branch, reason := range map[string]v1alpha1.ConnectionFailureReason{
+		"master":  "",
+		"dev":     "",
+		"missing": v1alpha1.BranchNotFound} {
+
+		gitSource := test.NewGitSource(test.WithURL("ssh://git@localhost:2222"+dummyRepo.Path), test.WithRef(branch))
+
+		// when
+		validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, gitSource,
+			git.NewSecretProviderWithSettings(sshKey, settings))
+
+		// then
+		if reason == "" {
+			assert.Nil(t, validationErr, branch)
+		} else {
+			require.Error(t, validationErr, branch)
+			assert.Equal(t, reason, validationErr.Reason(), branch)
+		}
+	}
+}
+
+func TestValidateGenericGitWithMissingRepo(t *testing.T) {
+	// given
+	reset := test.RunBasicSshServer(t, "super-secret")
+	defer reset()
+	gitSource := test.NewGitSource(test.WithURL("ssh://git@localhost:2222/tmp/no-such-repo.git"))
+	settings := git.NewTransportSettings().WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts()))
+
+	// when
+	validationErr := connection.ValidateGitSourceWithSecretProvider(context.Background(), logger, gitSource,
+		git.NewSecretProviderWithSettings(git.NewUsernamePassword("git", "super-secret"), settings))
+
+	// then
+	require.Error(t, validationErr)
+	assert.Equal(t, connection.RepoNotFound, validationErr.Reason())
 }
@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"context"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/bitbucket"
@@ -8,6 +9,7 @@ import (
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/gitlab"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"testing"
@@ -24,7 +26,7 @@ func TestGenericGitUsingSshAccessingGitHub(t *testing.T) {
 	}
 
 	// when
-	validationError := validateGitSourceWithSecret(logger, ghSource, git.NewSecretProvider(nil), allButGh)
+	validationError := validateGitSourceWithSecret(context.Background(), logger, ghSource, git.NewSecretProvider(nil), allButGh)
 
 	// then
 	require.NoError(t, validationError)
@@ -39,8 +41,48 @@ func TestGenericGitUsingSshAccessingGitLab(t *testing.T) {
 	}
 
 	// when
-	validationError := validateGitSourceWithSecret(logger, ghSource, git.NewSecretProvider(nil), allButGl)
+	validationError := validateGitSourceWithSecret(context.Background(), logger, ghSource, git.NewSecretProvider(nil), allButGl)
 
 	// then
 	require.NoError(t, validationError)
 }
+
+func TestValidateCredentialsNotAllowingToCreateWebhooksReturnsWarning(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithURL("https://some.host/some-org/some-repo"), test.WithFlavor("dummy"))
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	service.MissingPermissions = []repository.MissingPermission{
+		{Permission: repository.CreateWebhooks, Hint: "the token needs the api scope"},
+	}
+
+	// when
+	validationError := validateGitSourceWithSecret(context.Background(), logger, source,
+		git.NewSecretProvider(git.NewOauthToken([]byte("some-token"))), []repository.ServiceCreator{service.Creator()})
+
+	// then
+	require.Error(t, validationError)
+	assert.True(t, IsWarning(validationError))
+	assert.Equal(t, LimitedPermissions, validationError.Reason())
+	assert.Contains(t, validationError.Error(), "create webhooks (the token needs the api scope)")
+}
+
+func TestValidateCredentialsNotAllowingToReadContentsFails(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithURL("https://some.host/some-org/some-repo"), test.WithFlavor("dummy"))
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	service.MissingPermissions = []repository.MissingPermission{
+		{Permission: repository.ReadContents, Hint: "the token needs the repo scope to read a private repository"},
+		{Permission: repository.CreateWebhooks, Hint: "the account needs admin access to the repository"},
+	}
+
+	// when
+	validationError := validateGitSourceWithSecret(context.Background(), logger, source,
+		git.NewSecretProvider(git.NewOauthToken([]byte("some-token"))), []repository.ServiceCreator{service.Creator()})
+
+	// then
+	require.Error(t, validationError)
+	assert.False(t, IsWarning(validationError))
+	assert.Equal(t, InsufficientScope, validationError.Reason())
+	assert.Contains(t, validationError.Error(), "read the contents (the token needs the repo scope")
+	assert.Contains(t, validationError.Error(), "create webhooks (the account needs admin access")
+}
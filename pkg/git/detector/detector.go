@@ -1,37 +1,36 @@
 package detector
 
 import (
+	"context"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/bitbucket"
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/gitlab"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository/generic"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"sync"
 
-	"github.com/redhat-developer/devconsole-git/pkg/git/repository/github"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
 )
 
-var gitServiceCreators = []repository.ServiceCreator{
-	github.NewRepoServiceIfMatches(),
-	bitbucket.NewRepoServiceIfMatches(),
-	gitlab.NewRepoServiceIfMatches(),
-}
-
 // DetectBuildEnvironmentsWithSecret detects build tools and languages using the given secret in the git repository
 // defined by the given v1alpha1.GitSource
-func DetectBuildEnvironmentsWithSecret(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret git.Secret) (*v1alpha1.BuildEnvStats, error) {
-	return DetectBuildEnvironments(log, gitSource, git.NewSecretProvider(secret))
+func DetectBuildEnvironmentsWithSecret(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secret git.Secret) (*v1alpha1.BuildEnvStats, error) {
+	return DetectBuildEnvironments(ctx, log, gitSource, git.NewSecretProvider(secret))
 }
 
 // DetectBuildEnvironments detects build tools and languages using the secret provided by the SecretProvider
-// in the git repository defined by the given v1alpha1.GitSource
-func DetectBuildEnvironments(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (*v1alpha1.BuildEnvStats, error) {
-	return detectBuildEnvs(log, gitSource, secretProvider, gitServiceCreators)
+// in the git repository defined by the given v1alpha1.GitSource. If no git server API matches the GitSource
+// (or an SSH key is used), then the repository is fetched using the git protocol.
+// All calls to the git server are bound to the context.
+func DetectBuildEnvironments(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) (*v1alpha1.BuildEnvStats, error) {
+	return detectBuildEnvs(ctx, log, gitSource, secretProvider, repository.DefaultRegistry().ServiceCreators())
 }
 
-func detectBuildEnvs(log *log.GitSourceLogger,
+func detectBuildEnvs(ctx context.Context,
+	log *log.GitSourceLogger,
 	gitSource *v1alpha1.GitSource,
 	secretProvider *git.SecretProvider,
 	serviceCreators []repository.ServiceCreator) (*v1alpha1.BuildEnvStats, error) {
@@ -41,22 +40,25 @@ func detectBuildEnvs(log *log.GitSourceLogger,
 		return nil, err
 	}
 	if service == nil {
-		return nil, nil
+		service, err = generic.NewRepositoryService(gitSource, secretProvider)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return detectBuildEnvsUsingService(service)
+	return detectBuildEnvsUsingService(ctx, service)
 }
 
-func detectBuildEnvsUsingService(service repository.GitService) (*v1alpha1.BuildEnvStats, error) {
+func detectBuildEnvsUsingService(ctx context.Context, service repository.GitService) (*v1alpha1.BuildEnvStats, error) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	detectedBuildTools := make(chan *v1alpha1.DetectedBuildType, len(build.Tools))
 	var detectionErr error
 	go func() {
 		defer wg.Done()
-		detectionErr = detectBuildTools(service, detectedBuildTools)
+		detectionErr = detectBuildTools(ctx, service, detectedBuildTools)
 	}()
 
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -78,11 +80,11 @@ func detectBuildEnvsUsingService(service repository.GitService) (*v1alpha1.Build
 	}, nil
 }
 
-func detectBuildTools(service repository.GitService, detectedBuildTools chan *v1alpha1.DetectedBuildType) error {
+func detectBuildTools(ctx context.Context, service repository.GitService, detectedBuildTools chan *v1alpha1.DetectedBuildType) error {
 	var wg sync.WaitGroup
 	wg.Add(len(build.Tools))
 
-	fileExistenceChecker, err := service.FileExistenceChecker()
+	fileExistenceChecker, err := service.FileExistenceChecker(ctx)
 	if err != nil {
 		return err
 	}
@@ -90,7 +92,7 @@ func detectBuildTools(service repository.GitService, detectedBuildTools chan *v1
 	for _, tool := range build.Tools {
 		go func(buildTool build.Tool) {
 			defer wg.Done()
-			detectedFiles := fileExistenceChecker.DetectFiles(buildTool)
+			detectedFiles := fileExistenceChecker.DetectFiles(ctx, buildTool)
 			if len(detectedFiles) > 0 {
 				detectedBuildTools <- build.NewDetectedBuildTool(buildTool.Language, buildTool.Name, detectedFiles)
 			}
@@ -99,5 +101,6 @@ func detectBuildTools(service repository.GitService, detectedBuildTools chan *v1
 
 	wg.Wait()
 	close(detectedBuildTools)
-	return nil
+	// the checker doesn't report failed requests - an exceeded deadline would look like no file was found
+	return ctx.Err()
 }
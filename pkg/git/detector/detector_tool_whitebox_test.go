@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
@@ -53,7 +54,7 @@ func TestDetectJavaMavenAndGradle(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -78,7 +79,7 @@ func TestDetectJavaMavenAndGo(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -104,7 +105,7 @@ func TestDetectRuby(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -128,7 +129,7 @@ func TestDetectPHP(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -152,7 +153,7 @@ func TestDetectNodeJS(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -176,7 +177,7 @@ func TestDetectPython(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -200,7 +201,7 @@ func TestDetectPerl(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -224,7 +225,7 @@ func TestDetectDotnet(t *testing.T) {
 		source := test.NewGitSource(test.WithFlavor(service.Flavor))
 
 		// when
-		buildEnvStats, err := detectBuildEnvs(logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
+		buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, allEnvServiceCreators(service.UseFilesChecker))
 
 		// then
 		require.NoError(t, err)
@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
@@ -38,11 +39,15 @@ func TestDetectBuildEnvsShouldUseGenericGitIfNotOtherMatches(t *testing.T) {
 	source := test.NewGitSource(test.WithURL(dummyRepo.Path), test.WithFlavor("not-existing"))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, source, git.NewSecretProvider(sshKey), allEnvServiceCreators(true))
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, git.NewSecretProvider(sshKey), allEnvServiceCreators(true))
 
 	// then
 	require.NoError(t, err)
-	assert.Nil(t, buildEnvStats)
+	require.Len(t, buildEnvStats.DetectedBuildTypes, 2)
+	assertContainsBuildTool(t, buildEnvStats.DetectedBuildTypes, build.Maven, "pom.xml")
+	assertContainsBuildTool(t, buildEnvStats.DetectedBuildTypes, build.NodeJS, "package.json")
+	assert.Contains(t, buildEnvStats.SortedLanguages, "Java")
+	assert.Contains(t, buildEnvStats.SortedLanguages, "Go")
 }
 
 func TestFailingCreator(t *testing.T) {
@@ -50,7 +55,7 @@ func TestFailingCreator(t *testing.T) {
 	source := test.NewGitSource(test.WithFlavor(failingService.Flavor))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, source, nil, append(allEnvServiceCreators(true), failingService.Creator()))
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, nil, append(allEnvServiceCreators(true), failingService.Creator()))
 
 	// then
 	require.Error(t, err)
@@ -65,16 +70,16 @@ func TestFailingGenericGitCreation(t *testing.T) {
 	source := test.NewGitSource(test.WithURL(dummyRepo.Path), test.WithFlavor("not-existing"))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, source, git.NewSecretProvider(sshKey), allEnvServiceCreators(true))
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, git.NewSecretProvider(sshKey), allEnvServiceCreators(true))
 
 	// then
-	require.NoError(t, err)
+	require.Error(t, err)
 	assert.Nil(t, buildEnvStats)
 }
 
 func TestFailingGetFileList(t *testing.T) {
 	// when
-	buildEnvStats, err := detectBuildEnvsUsingService(failingFilesService)
+	buildEnvStats, err := detectBuildEnvsUsingService(context.Background(), failingFilesService)
 
 	// then
 	require.Error(t, err)
@@ -84,7 +89,7 @@ func TestFailingGetFileList(t *testing.T) {
 
 func TestFailingGetLanguagesList(t *testing.T) {
 	// when
-	buildEnvStats, err := detectBuildEnvsUsingService(failingLanguagesService)
+	buildEnvStats, err := detectBuildEnvsUsingService(context.Background(), failingLanguagesService)
 
 	// then
 	require.Error(t, err)
@@ -97,7 +102,7 @@ func TestBitbucketDetectorWithDefault(t *testing.T) {
 	glSource := test.NewGitSource(test.WithURL("https://bitbucket.org/mjobanek-rh/quarkus-knative"))
 
 	// when
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, glSource, nil)
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, glSource, nil)
 
 	// then
 	require.NoError(t, err)
@@ -112,7 +117,7 @@ func TestGitLabDetectorWithDefault(t *testing.T) {
 	glSource := test.NewGitSource(test.WithURL("https://gitlab.com/matousjobanek/quarkus-knative"))
 
 	// when
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, glSource, nil)
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, glSource, nil)
 
 	// then
 	require.NoError(t, err)
@@ -127,7 +132,7 @@ func TestGitHubDetectorWithDefault(t *testing.T) {
 	glSource := test.NewGitSource(test.WithURL("https://github.com/MatousJobanek/quarkus-knative"))
 
 	// when
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, glSource, nil)
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, glSource, nil)
 
 	// then
 	require.NoError(t, err)
@@ -135,40 +140,47 @@ func TestGitHubDetectorWithDefault(t *testing.T) {
 	assertContainsBuildTool(t, buildEnvStats.DetectedBuildTypes, build.Maven, "pom.xml")
 }
 
-func TestGenericGitUsingSshAccessingGitLabWithDefaultCredentials(t *testing.T) {
+func TestGenericGitAccessingLocalRepositoryWithDefaultCredentials(t *testing.T) {
 	// given
-	glSource := test.NewGitSource(test.WithURL("https://gitlab.com/matousjobanek/quarkus-knative"))
+	dummyRepo := newDummyMavenRepo(t)
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, glSource, git.NewSecretProvider(nil), []repository.ServiceCreator{})
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, git.NewSecretProvider(nil), []repository.ServiceCreator{})
 
 	// then
 	require.NoError(t, err)
-	assert.Nil(t, buildEnvStats)
+	assertMavenDetected(t, buildEnvStats)
 }
 
-func TestGenericGitUsingSshAccessingBitbucketWithDefaultCredentials(t *testing.T) {
+func TestGenericGitUsingHttpWithDefaultCredentials(t *testing.T) {
 	// given
-	glSource := test.NewGitSource(test.WithURL("https://bitbucket.org/mjobanek-rh/quarkus-knative"))
+	dummyRepo := newDummyMavenRepo(t)
+	server, repoURL := test.RunGitServer(t, dummyRepo.Path)
+	defer server.Close()
+	source := test.NewGitSource(test.WithURL(repoURL))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, glSource, git.NewSecretProvider(nil), []repository.ServiceCreator{})
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, git.NewSecretProvider(nil), []repository.ServiceCreator{})
 
 	// then
 	require.NoError(t, err)
-	assert.Nil(t, buildEnvStats)
+	assertMavenDetected(t, buildEnvStats)
 }
 
-func TestGenericGitUsingSshAccessingGitHubWithDefaultCredentials(t *testing.T) {
+func TestGenericGitUsingGitDaemonWithDefaultCredentials(t *testing.T) {
 	// given
-	ghSource := test.NewGitSource(test.WithURL("https://github.com/MatousJobanek/quarkus-knative"))
+	dummyRepo := newDummyMavenRepo(t)
+	repoURL, stopDaemon := test.RunGitDaemon(t, dummyRepo.Path)
+	defer stopDaemon()
+	source := test.NewGitSource(test.WithURL(repoURL))
 
 	// when
-	buildEnvStats, err := detectBuildEnvs(logger, ghSource, git.NewSecretProvider(nil), []repository.ServiceCreator{})
+	buildEnvStats, err := detectBuildEnvs(context.Background(), logger, source, git.NewSecretProvider(nil), []repository.ServiceCreator{})
 
 	// then
 	require.NoError(t, err)
-	assert.Nil(t, buildEnvStats)
+	assertMavenDetected(t, buildEnvStats)
 }
 
 // ignored tests as they reach the real services or needs specific credentials
@@ -181,7 +193,7 @@ func TestGitHubDetectorWithToken(t *testing.T) {
 
 	ghSource := test.NewGitSource(test.WithURL("https://github.com/wildfly/wildfly"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, ghSource, git.NewOauthToken(token))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, ghSource, git.NewOauthToken(token))
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
 }
@@ -191,7 +203,7 @@ func TestGitHubDetectorWithUsernameAndPassword(t *testing.T) {
 
 	ghSource := test.NewGitSource(test.WithURL("https://github.com/wildfly/wildfly"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, ghSource, git.NewUsernamePassword("anonymous", ""))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, ghSource, git.NewUsernamePassword("anonymous", ""))
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
 }
@@ -204,7 +216,7 @@ func TestGenericGitUsingSshAccessingGitHub(t *testing.T) {
 
 	ghSource := test.NewGitSource(test.WithURL("git@github.com:wildfly/wildfly.git"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, ghSource, git.NewSshKey(buffer, []byte("passphrase")))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, ghSource, git.NewSshKey(buffer, []byte("passphrase")))
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
 }
@@ -217,7 +229,7 @@ func TestGenericGitUsingSshAccessingGitLab(t *testing.T) {
 
 	ghSource := test.NewGitSource(test.WithURL("git@gitlab.cee.redhat.com:mjobanek/housekeeping.git"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, ghSource, git.NewSshKey(buffer, []byte("passphrase")))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, ghSource, git.NewSshKey(buffer, []byte("passphrase")))
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
 }
@@ -227,7 +239,7 @@ func TestBitbucketDetectorWithUsernameAndPassword(t *testing.T) {
 
 	ghSource := test.NewGitSource(test.WithURL("https://bitbucket.org/atlassian/asap-java"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, ghSource, git.NewUsernamePassword("", ""))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, ghSource, git.NewUsernamePassword("", ""))
 
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
@@ -238,7 +250,7 @@ func TestGitLabDetectorWithUsernamePassword(t *testing.T) {
 
 	glSource := test.NewGitSource(test.WithURL("https://gitlab.com/gitlab-org/gitlab-qa"))
 
-	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(logger, glSource, git.NewUsernamePassword("", ""))
+	buildEnvStats, err := DetectBuildEnvironmentsWithSecret(context.Background(), logger, glSource, git.NewUsernamePassword("", ""))
 	require.NoError(t, err)
 	printBuildEnvStats(buildEnvStats)
 }
@@ -247,6 +259,19 @@ func assertContainsBuildTool(t *testing.T, detected []v1alpha1.DetectedBuildType
 	test.AssertContainsBuildTool(t, detected, buildTool.Name, buildTool.Language, files...)
 }
 
+func newDummyMavenRepo(t *testing.T) *test.DummyGitRepo {
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("pom.xml", "mvnw", "src/main/java/Any.java", "src/main/java/Another.java")
+	return dummyRepo
+}
+
+func assertMavenDetected(t *testing.T, buildEnvStats *v1alpha1.BuildEnvStats) {
+	require.NotNil(t, buildEnvStats)
+	require.Len(t, buildEnvStats.DetectedBuildTypes, 1)
+	assertContainsBuildTool(t, buildEnvStats.DetectedBuildTypes, build.Maven, "pom.xml")
+	assert.Contains(t, buildEnvStats.SortedLanguages, "Java")
+}
+
 func printBuildEnvStats(buildEnvStats *v1alpha1.BuildEnvStats) {
 	fmt.Println(buildEnvStats.SortedLanguages)
 	for _, build := range buildEnvStats.DetectedBuildTypes {
@@ -0,0 +1,284 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+const (
+	GitHubAppType = "GitHubApp"
+
+	// GitHubAppIDKey is a key in a secret holding the ID of a GitHub App
+	GitHubAppIDKey = "github-app-id"
+	// GitHubAppPrivateKeyKey is a key in a secret holding the PEM encoded private key of the GitHub App
+	GitHubAppPrivateKeyKey = "github-app-private-key"
+	// GitHubAppInstallationIDKey is an optional key in a secret holding the ID of the installation of the GitHub App.
+	// If it is not set, then the installation is looked up using the repository of the GitSource.
+	GitHubAppInstallationIDKey = "github-app-installation-id"
+	// GitHubAPIURLKey is an optional key in a secret holding the URL of the GitHub API (of a GitHub Enterprise server)
+	GitHubAPIURLKey = "github-api-url"
+
+	defaultGitHubAPIURL = "https://api.github.com/"
+	// the media type of the API endpoints of GitHub Apps
+	gitHubAppMediaType = "application/vnd.github.machine-man-preview+json"
+	// the user that has to be sent together with an installation token when a repository is cloned over https
+	gitHubAppGitUser = "x-access-token"
+	// GitHub refuses JWTs valid for more than 10 minutes
+	gitHubAppJWTValidity = 9 * time.Minute
+	// installation tokens are refreshed a minute before they expire, so they don't expire while being used
+	installationTokenExpiryDelta = time.Minute
+)
+
+// GitHubApp authenticates as an installation of a GitHub App. It signs a JWT using the private key of the app
+// and exchanges it for an installation token, which is cached until it expires. The installation tokens
+// are valid for one hour.
+type GitHubApp struct {
+	*commonSecretInfo
+	appID          int64
+	installationID int64
+	repository     string
+	apiURL         string
+}
+
+// installationTokenKey identifies an installation of a GitHub App - either by its ID or by the account
+// the app is installed on. The hash of the private key is a part of the key, so only the secrets holding
+// the private key of the app get the cached token - the IDs of the apps and of the installations are not secret.
+type installationTokenKey struct {
+	apiURL       string
+	appID        int64
+	installation string
+	privateKey   [sha256.Size]byte
+}
+
+// cachedInstallationToken is an installation token shared by all secrets authenticating as the same installation.
+// The mutex is held while the token is being refreshed, so only one token is requested at a time.
+type cachedInstallationToken struct {
+	mutex     sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// the installation tokens are cached for the whole process as the secrets are created for each reconcile
+var (
+	installationTokensMutex sync.Mutex
+	installationTokens      = map[installationTokenKey]*cachedInstallationToken{}
+)
+
+// NewGitHubApp returns a secret authenticating as the installation of the GitHub App with the given ID
+// on the account owning the given repository (in the form owner/name)
+func NewGitHubApp(appID int64, privateKey []byte, repository string) *GitHubApp {
+	return &GitHubApp{
+		commonSecretInfo: &commonSecretInfo{
+			secretType:    GitHubAppType,
+			secretContent: bytes.TrimSpace(privateKey),
+		},
+		appID:      appID,
+		repository: repository,
+		apiURL:     defaultGitHubAPIURL,
+	}
+}
+
+// WithInstallationID sets the ID of the installation so it doesn't have to be looked up
+func (a *GitHubApp) WithInstallationID(installationID int64) *GitHubApp {
+	a.installationID = installationID
+	return a
+}
+
+// WithAPIURL sets the URL of the GitHub API the installation tokens are requested from
+func (a *GitHubApp) WithAPIURL(apiURL string) *GitHubApp {
+	a.apiURL = strings.TrimSuffix(apiURL, "/") + "/"
+	return a
+}
+
+// APIURL returns the URL of the GitHub API the installation tokens are valid for, ending with a slash
+func (a *GitHubApp) APIURL() string {
+	return a.apiURL
+}
+
+// GitAuthMethod returns the installation token as a password of the http basic authentication - GitHub accepts
+// the installation tokens only for repositories cloned over https
+func (a *GitHubApp) GitAuthMethod() (transport.AuthMethod, error) {
+	token, _, err := a.installationToken()
+	if err != nil {
+		return nil, err
+	}
+	return &githttp.BasicAuth{
+		Username: gitHubAppGitUser,
+		Password: token,
+	}, nil
+}
+
+func (a *GitHubApp) Client() *http.Client {
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, a.settings.HTTPClient())
+	return oauth2.NewClient(ctx, &installationTokenSource{app: a})
+}
+
+// installationTokenSource provides the cached installation token to oauth2 clients. The token expires
+// for the clients at the same time it is refreshed in the cache.
+type installationTokenSource struct {
+	app *GitHubApp
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	token, expiresAt, err := s.app.installationToken()
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Token{
+		AccessToken: token,
+		TokenType:   "token",
+		Expiry:      expiresAt.Add(-installationTokenExpiryDelta),
+	}, nil
+}
+
+// installationToken returns the cached installation token together with its expiration or requests a new one
+// if the cached token is about to expire
+func (a *GitHubApp) installationToken() (string, time.Time, error) {
+	cached := a.cachedToken()
+	cached.mutex.Lock()
+	defer cached.mutex.Unlock()
+	if cached.token != "" && time.Now().Add(installationTokenExpiryDelta).Before(cached.expiresAt) {
+		return cached.token, cached.expiresAt, nil
+	}
+
+	jwt, err := a.signJWT(time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	installationID := a.installationID
+	if installationID == 0 {
+		installation := &struct {
+			ID int64 `json:"id"`
+		}{}
+		err := a.callAPI(http.MethodGet, fmt.Sprintf("repos/%s/installation", a.repository), jwt, installation)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to find the installation of the GitHub App %d "+
+				"for the repository %s: %s", a.appID, a.repository, err)
+		}
+		installationID = installation.ID
+	}
+
+	token := &struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{}
+	err = a.callAPI(http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", installationID), jwt, token)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create a token of the installation %d of the GitHub App %d: %s",
+			installationID, a.appID, err)
+	}
+	cached.token = token.Token
+	cached.expiresAt = token.ExpiresAt
+	return cached.token, cached.expiresAt, nil
+}
+
+// cachedToken returns the token cached for the installation of the app. If the ID of the installation is not known,
+// then the installation is identified by the owner of the repository as an app can be installed only once per account.
+func (a *GitHubApp) cachedToken() *cachedInstallationToken {
+	key := installationTokenKey{apiURL: a.apiURL, appID: a.appID, privateKey: sha256.Sum256(a.secretContent)}
+	if a.installationID != 0 {
+		key.installation = strconv.FormatInt(a.installationID, 10)
+	} else {
+		key.installation = "owner:" + strings.SplitN(a.repository, "/", 2)[0]
+	}
+
+	installationTokensMutex.Lock()
+	defer installationTokensMutex.Unlock()
+	cached, ok := installationTokens[key]
+	if !ok {
+		cached = &cachedInstallationToken{}
+		installationTokens[key] = cached
+	}
+	return cached
+}
+
+// callAPI sends a request authenticated as the GitHub App and decodes the JSON response into the given value
+func (a *GitHubApp) callAPI(method, path, jwt string, value interface{}) error {
+	req, err := http.NewRequest(method, a.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", gitHubAppMediaType)
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.settings.OperationTimeout())
+	defer cancel()
+	resp, err := a.settings.HTTPClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(value)
+}
+
+// signJWT returns a JWT signed by the private key of the app that is used to authenticate as the app.
+// It is issued a minute in the past to allow for a clock drift.
+func (a *GitHubApp) signJWT(now time.Time) (string, error) {
+	key, err := parseRSAPrivateKey(a.secretContent)
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(gitHubAppJWTValidity).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey parses a PEM encoded RSA key in either the PKCS#1 (used by GitHub) or the PKCS#8 form
+func parseRSAPrivateKey(privateKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKey)
+	if block == nil {
+		return nil, fmt.Errorf("the private key of the GitHub App is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key of the GitHub App: %s", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key of the GitHub App is not an RSA key")
+	}
+	return rsaKey, nil
+}
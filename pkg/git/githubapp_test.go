@@ -0,0 +1,278 @@
+package git_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gitHubAppServer fakes the GitHub API endpoints used to get installation tokens. It counts the created tokens,
+// each of them expires after the given duration. The handlers run in other goroutines, so they only assert.
+type gitHubAppServer struct {
+	*httptest.Server
+	createdTokens int32
+	validity      time.Duration
+}
+
+func newGitHubAppServer(t *testing.T, validity time.Duration) *gitHubAppServer {
+	server := &gitHubAppServer{validity: validity}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/some-org/some-repo/installation", func(w http.ResponseWriter, r *http.Request) {
+		assertAppJWT(t, r)
+		fmt.Fprint(w, `{"id": 42}`)
+	})
+	mux.HandleFunc("/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assertAppJWT(t, r)
+		created := atomic.AddInt32(&server.createdTokens, 1)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token-%d", "expires_at": "%s"}`,
+			created, time.Now().Add(server.validity).Format(time.RFC3339))
+	})
+	mux.HandleFunc("/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"authorization": "%s"}`, r.Header.Get("Authorization"))
+	})
+	server.Server = httptest.NewServer(mux)
+	return server
+}
+
+func assertAppJWT(t *testing.T, r *http.Request) {
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if !assert.Len(t, parts, 3) {
+		return
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	claims := map[string]int64{}
+	if !assert.NoError(t, err) || !assert.NoError(t, json.Unmarshal(payload, &claims)) {
+		return
+	}
+	assert.Equal(t, int64(1234), claims["iss"])
+	assert.True(t, claims["exp"] > time.Now().Unix())
+	assert.True(t, claims["iat"] < time.Now().Unix())
+}
+
+func newTestGitHubApp(t *testing.T, apiURL string) *git.GitHubApp {
+	return git.NewGitHubApp(1234, test.PrivateWithoutPassphrase(t, pathToTestDir), "some-org/some-repo").
+		WithAPIURL(apiURL)
+}
+
+func newRSAPrivateKey(t *testing.T) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestGitHubAppAuthMethodUsesInstallationToken(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	app := newTestGitHubApp(t, server.URL)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, &githttp.BasicAuth{Username: "x-access-token", Password: "token-1"}, authMethod)
+	assert.Equal(t, git.GitHubAppType, app.SecretType())
+}
+
+func TestGitHubAppClientUsesInstallationToken(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	app := newTestGitHubApp(t, server.URL).WithInstallationID(42)
+
+	// when
+	resp, err := app.Client().Get(server.URL + "/installation/repositories")
+
+	// then
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := map[string]string{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "token token-1", body["authorization"])
+}
+
+func TestGitHubAppCachesInstallationToken(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	app := newTestGitHubApp(t, server.URL)
+	_, err := app.GitAuthMethod()
+	require.NoError(t, err)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", authMethod.(*githttp.BasicAuth).Password)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.createdTokens))
+}
+
+func TestGitHubAppSharesInstallationTokenWithOtherSecretsOfTheSameInstallation(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	_, err := newTestGitHubApp(t, server.URL).GitAuthMethod()
+	require.NoError(t, err)
+	app := git.NewGitHubApp(1234, test.PrivateWithoutPassphrase(t, pathToTestDir), "some-org/another-repo").
+		WithAPIURL(server.URL)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", authMethod.(*githttp.BasicAuth).Password)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&server.createdTokens))
+}
+
+func TestGitHubAppDoesNotShareInstallationTokenWithSecretsHoldingOtherKey(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	_, err := newTestGitHubApp(t, server.URL).GitAuthMethod()
+	require.NoError(t, err)
+	app := git.NewGitHubApp(1234, newRSAPrivateKey(t), "some-org/some-repo").WithAPIURL(server.URL)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", authMethod.(*githttp.BasicAuth).Password)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.createdTokens))
+}
+
+func TestGitHubAppRefreshesExpiringInstallationToken(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, 30*time.Second)
+	defer server.Close()
+	app := newTestGitHubApp(t, server.URL)
+	_, err := app.GitAuthMethod()
+	require.NoError(t, err)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", authMethod.(*githttp.BasicAuth).Password)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&server.createdTokens))
+}
+
+func TestGitHubAppFailsWhenNotInstalled(t *testing.T) {
+	// given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	app := git.NewGitHubApp(1234, test.PrivateWithoutPassphrase(t, pathToTestDir), "other-org/some-repo").
+		WithAPIURL(server.URL)
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+	assert.Nil(t, authMethod)
+}
+
+func TestGitHubAppFailsWithInvalidPrivateKey(t *testing.T) {
+	// given
+	app := git.NewGitHubApp(1234, []byte("invalid"), "some-org/some-repo")
+
+	// when
+	authMethod, err := app.GitAuthMethod()
+
+	// then
+	assert.Error(t, err)
+	assert.Nil(t, authMethod)
+}
+
+func TestGetGitSecretWithGitHubApp(t *testing.T) {
+	//given
+	sec := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"github-app-id":              []byte("1234"),
+		"github-app-private-key":     test.PrivateWithoutPassphrase(t, pathToTestDir),
+		"github-app-installation-id": []byte("42"),
+		"github-api-url":             []byte("https://github.example.com/api/v3")})
+
+	gs := test.NewGitSource(test.WithURL("git@github.example.com:some-org/some-repo.git"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, git.GitHubAppType, secretProvider.SecretType())
+}
+
+func TestGetGitSecretWithGitHubAppLooksUpInstallationOfRepository(t *testing.T) {
+	//given
+	server := newGitHubAppServer(t, time.Hour)
+	defer server.Close()
+	sec := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"github-app-id":          []byte("1234"),
+		"github-app-private-key": test.PrivateWithoutPassphrase(t, pathToTestDir),
+		"github-api-url":         []byte(server.URL)})
+
+	gs := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo/tree/master"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+	require.NoError(t, err)
+
+	//when
+	authMethod, err := secretProvider.GetSecret(nil).GitAuthMethod()
+
+	//then
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", authMethod.(*githttp.BasicAuth).Password)
+}
+
+func TestGetGitSecretWithInvalidGitHubAppID(t *testing.T) {
+	//given
+	sec := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"github-app-id":          []byte("my-app"),
+		"github-app-private-key": test.PrivateWithoutPassphrase(t, pathToTestDir)})
+
+	gs := test.NewGitSource()
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	assert.Error(t, err)
+	assert.Nil(t, secretProvider)
+}
@@ -0,0 +1,174 @@
+package giturl
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const gitSuffix = ".git"
+
+// azureReposSegment separates the project from the repository name in the paths of Azure Repos (and TFS)
+// repositories: <organization>/<project>/_git/<repository>
+const azureReposSegment = "_git"
+
+var defaultPorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ssh":   22,
+	"git":   9418,
+}
+
+// URL is a canonical form of a URL of a git repository. All forms of URLs pointing to the same repository
+// (scp-like, ssh://, http(s) with credentials, with or without the default port, the trailing slashes
+// or the .git suffix) have the same host and path.
+type URL struct {
+	// Protocol is one of http, https, ssh, git or file
+	Protocol string
+	// User is the user of ssh URLs. The credentials of http(s) URLs are never kept.
+	User string
+	// Host is the lowercase host name, empty for local repositories
+	Host string
+	// Port is the port of the git server, 0 if the default port of the protocol is used
+	Port int
+	// Path is the path of the repository without the trailing slashes and the .git suffix. The leading slash
+	// is kept only for local repositories.
+	Path string
+	// withGitSuffix is true if the original path ends with .git - the suffix is kept in the clone URL
+	// as not every git server accepts both forms
+	withGitSuffix bool
+}
+
+// Parse parses any form of git URL supported by git clients into its canonical form
+func Parse(rawURL string) (*URL, error) {
+	endpoint, err := gittransport.NewEndpoint(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	gitURL := &URL{
+		Protocol: endpoint.Protocol,
+		Host:     strings.ToLower(endpoint.Host),
+		Port:     endpoint.Port,
+	}
+	if gitURL.Protocol == "ssh" {
+		gitURL.User = endpoint.User
+	}
+	if defaultPorts[gitURL.Protocol] == gitURL.Port {
+		gitURL.Port = 0
+	}
+
+	path := strings.TrimRight(endpoint.Path, "/")
+	if gitURL.Protocol != "file" {
+		path = strings.TrimLeft(path, "/")
+	}
+	gitURL.withGitSuffix = strings.HasSuffix(path, gitSuffix)
+	gitURL.Path = strings.TrimSuffix(path, gitSuffix)
+	return gitURL, nil
+}
+
+// Endpoint returns the go-git endpoint of the repository without any credentials
+func (u *URL) Endpoint() *gittransport.Endpoint {
+	path := u.Path
+	if u.Protocol != "file" {
+		path = "/" + path
+	}
+	if u.withGitSuffix {
+		path += gitSuffix
+	}
+	return &gittransport.Endpoint{
+		Protocol: u.Protocol,
+		User:     u.User,
+		Host:     u.Host,
+		Port:     u.Port,
+		Path:     path,
+	}
+}
+
+// BaseURL returns the root URL of the web UI (and of the API) of the git service hosting the repository, ending
+// with a slash. The http(s) URLs keep their protocol and port, https is used for the other ones.
+// Returns an empty string for local repositories.
+func (u *URL) BaseURL() string {
+	switch u.Protocol {
+	case "file":
+		return ""
+	case "http", "https":
+		return fmt.Sprintf("%s://%s/", u.Protocol, u.hostWithPort())
+	}
+	return fmt.Sprintf("https://%s/", u.Host)
+}
+
+// WebURL returns the URL of the web page of the repository. Returns an empty string for local repositories.
+func (u *URL) WebURL() string {
+	if u.Protocol == "file" {
+		return ""
+	}
+	return u.BaseURL() + u.Path
+}
+
+// HTTPCloneURL returns the http(s) URL the repository can be cloned from. Returns an empty string for local
+// repositories.
+func (u *URL) HTTPCloneURL() string {
+	if u.Protocol == "file" {
+		return ""
+	}
+	return u.WebURL() + gitSuffix
+}
+
+// SSHCloneURL returns the scp-like URL the repository can be cloned from over ssh (the user git is used
+// if the URL doesn't contain any). The ssh:// form is returned if the URL uses a non-default ssh port.
+// Returns an empty string for local repositories.
+func (u *URL) SSHCloneURL() string {
+	if u.Protocol == "file" {
+		return ""
+	}
+	user := u.User
+	if user == "" {
+		user = "git"
+	}
+	if u.Protocol == "ssh" && u.Port != 0 {
+		return fmt.Sprintf("ssh://%s@%s/%s%s", user, u.hostWithPort(), u.Path, gitSuffix)
+	}
+	return fmt.Sprintf("%s@%s:%s%s", user, u.Host, u.Path, gitSuffix)
+}
+
+// Key returns an identifier of the repository that is the same for all URLs of the repository regardless
+// of the protocol - the host followed by the path
+func (u *URL) Key() string {
+	return u.Host + "/" + strings.TrimLeft(u.Path, "/")
+}
+
+// Segments returns the non-empty segments of the path of the repository
+func (u *URL) Segments() []string {
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// OwnerAndName returns the owner and the name of the repository - the first two segments of the path, the rest
+// of the path (e.g. a page of the web UI) is ignored. For the <organization>/<project>/_git/<repository> paths
+// (Azure Repos and TFS) the owner consists of the organization and the project.
+func (u *URL) OwnerAndName() (string, string, error) {
+	segments := u.Segments()
+	if len(segments) < 2 {
+		return "", "", errors.New("url is invalid")
+	}
+	if len(segments) >= 4 && segments[2] == azureReposSegment {
+		return strings.Join(segments[:2], "/"), segments[3], nil
+	}
+	return segments[0], segments[1], nil
+}
+
+func (u *URL) hostWithPort() string {
+	if u.Port == 0 {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Host, strconv.Itoa(u.Port))
+}
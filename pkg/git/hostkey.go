@@ -0,0 +1,83 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/redhat-developer/devconsole-git/pkg/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// KnownHostsKey is a key in a secret holding known_hosts entries used for verification of ssh host keys
+	KnownHostsKey = config.KnownHostsKey
+	// InsecureIgnoreHostKeyKey is a key in a secret that turns off verification of ssh host keys when set to "true"
+	InsecureIgnoreHostKeyKey = "insecure-ignore-host-key"
+
+	hostKeyVerificationFailed = "host key verification failed"
+)
+
+// HostKeyPolicy defines how keys presented by ssh servers are verified
+type HostKeyPolicy struct {
+	knownHosts []byte
+	insecure   bool
+}
+
+// NewKnownHostsPolicy returns a policy accepting only the host keys listed in the given known_hosts content
+func NewKnownHostsPolicy(knownHosts []byte) *HostKeyPolicy {
+	return &HostKeyPolicy{knownHosts: bytes.TrimSpace(knownHosts)}
+}
+
+// NewInsecureHostKeyPolicy returns a policy accepting any host key. It should be used only when explicitly requested.
+func NewInsecureHostKeyPolicy() *HostKeyPolicy {
+	return &HostKeyPolicy{insecure: true}
+}
+
+// HostKeyCallback returns a callback verifying host keys according to the policy. If there are no known_hosts
+// entries and the insecure mode is not set, then all host keys are rejected.
+func (p *HostKeyPolicy) HostKeyCallback() (ssh.HostKeyCallback, error) {
+	if p != nil && p.insecure {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	if p == nil || len(p.knownHosts) == 0 {
+		return rejectAll, nil
+	}
+
+	// the knownhosts package is able to read the entries only from files
+	file, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(append(p.knownHosts, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	callback, err := knownhosts.New(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse known_hosts: %s", err)
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		if err := callback(hostname, remote, key); err != nil {
+			return fmt.Errorf("%s for %s: %s", hostKeyVerificationFailed, hostname, err)
+		}
+		return nil
+	}, nil
+}
+
+func rejectAll(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	return fmt.Errorf("%s for %s: there are no known_hosts entries provided", hostKeyVerificationFailed, hostname)
+}
+
+// IsHostKeyError returns true if the given error was caused by a failed verification of a ssh host key
+func IsHostKeyError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), hostKeyVerificationFailed)
+}
@@ -0,0 +1,93 @@
+package git_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+var testServerAddr = &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 2222}
+
+func TestKnownHostsPolicyAcceptsKnownKey(t *testing.T) {
+	// given
+	callback, err := git.NewKnownHostsPolicy(test.KnownHosts()).HostKeyCallback()
+	require.NoError(t, err)
+
+	// when
+	err = callback("localhost:2222", testServerAddr, testServerKey(t))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestKnownHostsPolicyRejectsMismatchingKey(t *testing.T) {
+	// given
+	callback, err := git.NewKnownHostsPolicy(test.MismatchingKnownHosts()).HostKeyCallback()
+	require.NoError(t, err)
+
+	// when
+	err = callback("localhost:2222", testServerAddr, testServerKey(t))
+
+	// then
+	require.Error(t, err)
+	assert.True(t, git.IsHostKeyError(err))
+}
+
+func TestKnownHostsPolicyRejectsUnknownHost(t *testing.T) {
+	// given
+	callback, err := git.NewKnownHostsPolicy(test.KnownHosts()).HostKeyCallback()
+	require.NoError(t, err)
+
+	// when
+	err = callback("github.com:22", &net.TCPAddr{IP: net.ParseIP("140.82.118.4"), Port: 22}, testServerKey(t))
+
+	// then
+	require.Error(t, err)
+	assert.True(t, git.IsHostKeyError(err))
+}
+
+func TestKnownHostsPolicyWithoutEntriesRejectsAll(t *testing.T) {
+	for _, policy := range []*git.HostKeyPolicy{git.NewKnownHostsPolicy([]byte(" ")), nil} {
+		// given
+		callback, err := policy.HostKeyCallback()
+		require.NoError(t, err)
+
+		// when
+		err = callback("localhost:2222", testServerAddr, testServerKey(t))
+
+		// then
+		require.Error(t, err)
+		assert.True(t, git.IsHostKeyError(err))
+	}
+}
+
+func TestKnownHostsPolicyWithInvalidEntries(t *testing.T) {
+	// when
+	_, err := git.NewKnownHostsPolicy([]byte("localhost ssh-rsa not-a-key")).HostKeyCallback()
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestInsecureHostKeyPolicyAcceptsAll(t *testing.T) {
+	// given
+	callback, err := git.NewInsecureHostKeyPolicy().HostKeyCallback()
+	require.NoError(t, err)
+
+	// when
+	err = callback("github.com:22", &net.TCPAddr{IP: net.ParseIP("140.82.118.4"), Port: 22}, testServerKey(t))
+
+	// then
+	assert.NoError(t, err)
+}
+
+func testServerKey(t *testing.T) ssh.PublicKey {
+	_, _, key, _, _, err := ssh.ParseKnownHosts(test.KnownHosts())
+	require.NoError(t, err)
+	return key
+}
@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+const (
+	// DescriptionKey is a key of the metadata config map holding the description of the repository
+	DescriptionKey = "description"
+	// DefaultBranchKey is a key of the metadata config map holding name of the default branch
+	DefaultBranchKey = "defaultBranch"
+	// VisibilityKey is a key of the metadata config map holding the visibility: public, internal or private
+	VisibilityKey = "visibility"
+	// ArchivedKey is a key of the metadata config map set to "true" when the repository is archived
+	ArchivedKey = "archived"
+	// ForkKey is a key of the metadata config map set to "true" when the repository is a fork
+	ForkKey = "fork"
+	// SizeKey is a key of the metadata config map holding the size of the repository in bytes
+	SizeKey = "size"
+	// LastPushedAtKey is a key of the metadata config map holding the time of the last push in RFC 3339 format
+	LastPushedAtKey = "lastPushedAt"
+	// TopicsKey is a key of the metadata config map holding the topics separated by new lines
+	TopicsKey = "topics"
+	// WebURLKey is a key of the metadata config map holding the URL of the repository page in the web UI
+	WebURLKey = "webURL"
+)
+
+// ConfigMapName returns name of the config map the metadata of the repository of the given GitSource is published in
+func ConfigMapName(gitSource *v1alpha1.GitSource) string {
+	return gitSource.Name + "-metadata"
+}
+
+// GetMetadata reads metadata of the git repository defined by the given v1alpha1.GitSource using the secret
+// and the transport settings of the given provider. If the git server doesn't provide any API for reading the metadata
+// then nil is returned. All calls to the git server are bound to the context.
+func GetMetadata(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) (*repository.Metadata, error) {
+	return getMetadata(ctx, log, gitSource, secretProvider,
+		repository.DefaultRegistry().MetadataServiceCreators())
+}
+
+func getMetadata(ctx context.Context,
+	log *log.GitSourceLogger,
+	gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider,
+	serviceCreators []repository.MetadataServiceCreator) (*repository.Metadata, error) {
+
+	service, err := repository.NewMetadataService(log, gitSource, secretProvider, serviceCreators)
+	if err != nil || service == nil {
+		return nil, err
+	}
+	return service.GetMetadata(ctx)
+}
+
+// ToData converts the metadata to the data of the metadata config map. The values that are not known are omitted.
+func ToData(metadata *repository.Metadata) map[string]string {
+	data := map[string]string{
+		ArchivedKey: strconv.FormatBool(metadata.Archived),
+		ForkKey:     strconv.FormatBool(metadata.Fork),
+	}
+	putIfNotEmpty(data, DescriptionKey, metadata.Description)
+	putIfNotEmpty(data, DefaultBranchKey, metadata.DefaultBranch)
+	putIfNotEmpty(data, VisibilityKey, string(metadata.Visibility))
+	putIfNotEmpty(data, TopicsKey, strings.Join(metadata.Topics, "\n"))
+	putIfNotEmpty(data, WebURLKey, metadata.WebURL)
+	if metadata.Size > 0 {
+		data[SizeKey] = strconv.FormatInt(metadata.Size, 10)
+	}
+	if metadata.LastPushedAt != nil {
+		data[LastPushedAtKey] = metadata.LastPushedAt.UTC().Format(time.RFC3339)
+	}
+	return data
+}
+
+func putIfNotEmpty(data map[string]string, key, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var logger = &log.GitSourceLogger{Logger: logf.Log}
+
+func TestGetMetadataUsingMatchingService(t *testing.T) {
+	// given
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	service.Metadata = &repository.Metadata{Description: "Some repo", Archived: true}
+	source := test.NewGitSource(test.WithFlavor("dummy"))
+	creators := []repository.MetadataServiceCreator{repository.NewMetadataServiceCreator(service.Creator())}
+
+	// when
+	metadata, err := getMetadata(context.Background(), logger, source, git.NewSecretProvider(nil), creators)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, service.Metadata, metadata)
+}
+
+func TestGetMetadataWhenNoServiceMatches(t *testing.T) {
+	// given
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	source := test.NewGitSource(test.WithFlavor("other"))
+	creators := []repository.MetadataServiceCreator{repository.NewMetadataServiceCreator(service.Creator())}
+
+	// when
+	metadata, err := getMetadata(context.Background(), logger, source, git.NewSecretProvider(nil), creators)
+
+	// then
+	require.NoError(t, err)
+	assert.Nil(t, metadata)
+}
+
+func TestToDataOmitsUnknownValues(t *testing.T) {
+	// given
+	pushedAt := time.Date(2019, 4, 10, 11, 45, 12, 0, time.FixedZone("CEST", 2*60*60))
+	metadata := &repository.Metadata{
+		DefaultBranch: "master",
+		Visibility:    repository.Public,
+		Fork:          true,
+		LastPushedAt:  &pushedAt,
+		Topics:        []string{"go", "git"},
+	}
+
+	// when
+	data := ToData(metadata)
+
+	// then
+	assert.Equal(t, map[string]string{
+		DefaultBranchKey: "master",
+		VisibilityKey:    "public",
+		ArchivedKey:      "false",
+		ForkKey:          "true",
+		LastPushedAtKey:  "2019-04-10T09:45:12Z",
+		TopicsKey:        "go\ngit",
+	}, data)
+}
@@ -0,0 +1,261 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OauthRefreshTokenKey is a key in a secret holding the refresh token used to get a new OAuth access token
+	// when the one stored under the password key expires
+	OauthRefreshTokenKey = "refresh-token"
+	// OauthClientIDKey is a key in a secret holding the ID of the OAuth application the tokens were issued to
+	OauthClientIDKey = "client-id"
+	// OauthClientSecretKey is a key in a secret holding the secret of the OAuth application
+	OauthClientSecretKey = "client-secret"
+	// OauthTokenURLKey is an optional key in a secret holding the URL the tokens are refreshed at. The token endpoint
+	// of Bitbucket is used for repositories hosted on Bitbucket, the one of GitLab otherwise.
+	OauthTokenURLKey = "token-url"
+	// OauthTokenExpiryKey is an optional key in a secret holding the time the access token expires at
+	// in the RFC 3339 format. If it is not set, then the access token is refreshed when it is used for the first time.
+	OauthTokenExpiryKey = "token-expiry"
+
+	bitbucketHost   = "bitbucket.org"
+	bitbucketFlavor = "bitbucket"
+)
+
+// tokenRefresher holds the current OAuth token and refreshes it using the refresh token when it expires
+type tokenRefresher struct {
+	config    *oauth2.Config
+	onRefresh func(*oauth2.Token) error
+	// load returns the token persisted in the secret (if not nil), so a token refreshed and stored by someone else
+	// is used instead of refreshing it again
+	load func() (*oauth2.Token, error)
+
+	mutex   sync.Mutex
+	current *oauth2.Token
+	// unsaved is true if the refreshed token couldn't be passed to onRefresh
+	unsaved bool
+}
+
+// refreshableTokens holds one refresher per secret and token URL for the whole process, so the secrets created
+// for each reconcile don't refresh the same token concurrently - the refresh tokens are usually valid only once
+var (
+	refreshableTokensMutex sync.Mutex
+	refreshableTokens      = map[refreshableTokenKey]*sharedTokenRefresher{}
+)
+
+// refreshableTokenKey identifies a shared refresher. The token URL is a part of the key as it can be derived
+// from the GitSource using the secret, so GitSources of different git services don't overwrite each other's
+// token endpoint.
+type refreshableTokenKey struct {
+	secretName types.NamespacedName
+	tokenURL   string
+}
+
+// sharedTokenRefresher is the refresher of the secret with the given UID. A secret that was deleted and created again
+// gets a new refresher.
+type sharedTokenRefresher struct {
+	uid types.UID
+	*tokenRefresher
+}
+
+// NewRefreshableOauthToken returns an OAuth token that is refreshed using the given config when it expires.
+// The refreshed tokens are passed to onRefresh (if not nil) so they can be stored - the refresh tokens are
+// usually valid only once.
+func NewRefreshableOauthToken(token *oauth2.Token, config *oauth2.Config, onRefresh func(*oauth2.Token) error) *OauthToken {
+	return newOauthTokenWithRefresher(&tokenRefresher{
+		config:    config,
+		onRefresh: onRefresh,
+		current:   token,
+	})
+}
+
+func newOauthTokenWithRefresher(refresher *tokenRefresher) *OauthToken {
+	oauthToken := NewOauthToken([]byte(refresher.accessToken()))
+	oauthToken.refresher = refresher
+	return oauthToken
+}
+
+// token returns the current token or a refreshed one if the current token has expired. Before the token
+// is refreshed, the persisted token is loaded and used if it differs from the current one.
+func (r *tokenRefresher) token(httpClient *http.Client) (*oauth2.Token, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.current.Valid() && !r.unsaved && r.load != nil {
+		persisted, err := r.load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the persisted OAuth token: %s", err)
+		}
+		if persisted.AccessToken != r.current.AccessToken || persisted.RefreshToken != r.current.RefreshToken {
+			r.current = persisted
+		}
+	}
+	if !r.current.Valid() {
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+		token, err := r.config.TokenSource(ctx, r.current).Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh the OAuth token: %s", err)
+		}
+		r.current = token
+		r.unsaved = true
+	}
+	if r.unsaved && r.onRefresh != nil {
+		if err := r.onRefresh(r.current); err != nil {
+			return nil, fmt.Errorf("failed to store the refreshed OAuth token: %s", err)
+		}
+	}
+	r.unsaved = false
+	return r.current, nil
+}
+
+// accessToken returns the current access token without refreshing it
+func (r *tokenRefresher) accessToken() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.current.AccessToken
+}
+
+// refreshingTokenSource provides the tokens of the refresher to oauth2 clients using the transport settings
+// of the secret
+type refreshingTokenSource struct {
+	oauthToken *OauthToken
+}
+
+func (s *refreshingTokenSource) Token() (*oauth2.Token, error) {
+	return s.oauthToken.refresher.token(s.oauthToken.settings.HTTPClient())
+}
+
+// newRefreshableOauthToken creates a refreshable OAuth token from the secret. All tokens created from the same secret
+// share one refresher, which writes the refreshed tokens back to the secret.
+func newRefreshableOauthToken(client client.Client, coreSecret *corev1.Secret, gitSource *v1alpha1.GitSource) (*OauthToken, error) {
+	token, err := persistedToken(coreSecret)
+	if err != nil {
+		return nil, err
+	}
+	tokenURL := string(coreSecret.Data[OauthTokenURLKey])
+	if tokenURL == "" {
+		gitURL, err := giturl.Parse(gitSource.Spec.URL)
+		if err != nil {
+			return nil, err
+		}
+		tokenURL = defaultTokenURL(gitURL, gitSource.Spec.Flavor)
+	}
+	secretName := types.NamespacedName{Namespace: coreSecret.Namespace, Name: coreSecret.Name}
+	key := refreshableTokenKey{secretName: secretName, tokenURL: tokenURL}
+
+	refreshableTokensMutex.Lock()
+	defer refreshableTokensMutex.Unlock()
+	shared, ok := refreshableTokens[key]
+	if !ok || shared.uid != coreSecret.UID {
+		// the cached secret is used only for a new refresher - the current token of an existing one can be newer
+		shared = &sharedTokenRefresher{
+			uid: coreSecret.UID,
+			tokenRefresher: &tokenRefresher{
+				config:    &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: tokenURL}},
+				onRefresh: secretUpdater(client, secretName),
+				load:      secretLoader(client, secretName),
+				current:   token,
+			},
+		}
+		refreshableTokens[key] = shared
+	}
+	// the client credentials come from the secret only, so the latest version of the secret is used
+	clientID := string(coreSecret.Data[OauthClientIDKey])
+	clientSecret := string(coreSecret.Data[OauthClientSecretKey])
+	shared.mutex.Lock()
+	if shared.config.ClientID != clientID || shared.config.ClientSecret != clientSecret {
+		shared.config = &oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: tokenURL},
+		}
+	}
+	shared.mutex.Unlock()
+	return newOauthTokenWithRefresher(shared.tokenRefresher), nil
+}
+
+// persistedToken returns the token stored in the secret
+func persistedToken(coreSecret *corev1.Secret) (*oauth2.Token, error) {
+	token := &oauth2.Token{
+		AccessToken:  string(coreSecret.Data[corev1.BasicAuthPasswordKey]),
+		RefreshToken: string(coreSecret.Data[OauthRefreshTokenKey]),
+		// without a known expiry the token is refreshed when it is used for the first time
+		Expiry: time.Now(),
+	}
+	if expiry := string(coreSecret.Data[OauthTokenExpiryKey]); expiry != "" {
+		expiresAt, err := time.Parse(time.RFC3339, expiry)
+		if err != nil {
+			return nil, fmt.Errorf("the expiry of the OAuth token is not in the RFC 3339 format: %s", err)
+		}
+		token.Expiry = expiresAt
+	}
+	return token, nil
+}
+
+// defaultTokenURL returns the URL of the token endpoint of the git service hosting the repository - Bitbucket
+// for the repositories hosted on bitbucket.org or with the bitbucket flavor, GitLab otherwise
+func defaultTokenURL(gitURL *giturl.URL, flavor string) string {
+	if gitURL.Host == bitbucketHost || flavor == bitbucketFlavor {
+		return gitURL.BaseURL() + "site/oauth2/access_token"
+	}
+	return gitURL.BaseURL() + "oauth/token"
+}
+
+// secretLoader returns a function reading the token from the latest version of the secret
+func secretLoader(client client.Client, secretName types.NamespacedName) func() (*oauth2.Token, error) {
+	return func() (*oauth2.Token, error) {
+		secret := &corev1.Secret{}
+		if err := secretReader(client).Get(context.TODO(), secretName, secret); err != nil {
+			return nil, err
+		}
+		return persistedToken(secret)
+	}
+}
+
+// secretUpdater returns a function writing the refreshed tokens to the latest version of the secret
+func secretUpdater(client client.Client, secretName types.NamespacedName) func(*oauth2.Token) error {
+	return func(token *oauth2.Token) error {
+		return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			secret := &corev1.Secret{}
+			if err := secretReader(client).Get(context.TODO(), secretName, secret); err != nil {
+				return err
+			}
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			secret.Data[corev1.BasicAuthPasswordKey] = []byte(token.AccessToken)
+			secret.Data[OauthRefreshTokenKey] = []byte(token.RefreshToken)
+			secret.Data[OauthTokenExpiryKey] = []byte(token.Expiry.Format(time.RFC3339))
+			return client.Update(context.TODO(), secret)
+		})
+	}
+}
+
+// apiReader reads the secrets holding refreshable OAuth tokens directly from the API server - the cache
+// of the client can contain a token that was already refreshed and whose refresh token isn't valid anymore
+var apiReader client.Reader
+
+// UseAPIReader makes the refreshable OAuth tokens read their secrets using the given reader. If the reader is nil,
+// then the client the secret was retrieved by is used.
+func UseAPIReader(reader client.Reader) {
+	apiReader = reader
+}
+
+func secretReader(client client.Client) client.Reader {
+	if apiReader != nil {
+		return apiReader
+	}
+	return client
+}
@@ -0,0 +1,271 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newTokenServer fakes an OAuth token endpoint issuing tokens valid for two hours. It counts the issued tokens.
+func newTokenServer(t *testing.T, issuedTokens *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+		issued := atomic.AddInt32(issuedTokens, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "access-token-%d", "refresh_token": "refresh-token-%d", "token_type": "bearer", "expires_in": 7200}`,
+			issued, issued)
+	}))
+}
+
+// newRefreshableSecretProvider returns a provider of the token stored in a new secret. The refreshers are shared
+// by the secrets with the same name for the whole process, so each test uses a secret with its own UID.
+func newRefreshableSecretProvider(t *testing.T, data map[string][]byte) (*git.SecretProvider, client.Client) {
+	sec := test.NewSecret(corev1.SecretTypeOpaque, data)
+	sec.UID = types.UID(t.Name())
+	gs := test.NewGitSource(test.WithURL("https://gitlab.com/some-org/some-repo"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+	require.NoError(t, err)
+	return secretProvider, client
+}
+
+func expiredTokenData(tokenURL string) map[string][]byte {
+	return map[string][]byte{
+		"password":      []byte("expired-token"),
+		"refresh-token": []byte("some-refresh-token"),
+		"token-url":     []byte(tokenURL),
+		"token-expiry":  []byte(time.Now().Add(-time.Minute).Format(time.RFC3339))}
+}
+
+func TestRefreshableOauthTokenRefreshedAndStoredInSecret(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	secretProvider, client := newRefreshableSecretProvider(t, map[string][]byte{
+		"password":      []byte("expired-token"),
+		"refresh-token": []byte("some-refresh-token"),
+		"client-id":     []byte("some-client"),
+		"client-secret": []byte("some-secret"),
+		"token-url":     []byte(server.URL),
+		"token-expiry":  []byte(time.Now().Add(-time.Minute).Format(time.RFC3339))})
+	secret := secretProvider.GetSecret(defaultToken)
+
+	// when
+	authMethod, err := secret.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, git.OauthTokenType, secret.SecretType())
+	assert.Equal(t, "access-token-1", authMethod.(*gitssh.Password).Password)
+	assert.Equal(t, "access-token-1", secret.SecretContent())
+
+	stored := &corev1.Secret{}
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: test.Namespace, Name: test.SecretName}, stored))
+	assert.Equal(t, "access-token-1", string(stored.Data["password"]))
+	assert.Equal(t, "refresh-token-1", string(stored.Data["refresh-token"]))
+	expiry, err := time.Parse(time.RFC3339, string(stored.Data["token-expiry"]))
+	require.NoError(t, err)
+	assert.True(t, expiry.After(time.Now().Add(time.Hour)))
+	assert.Equal(t, "some-client", string(stored.Data["client-id"]))
+}
+
+func TestRefreshableOauthTokenWithoutExpiryRefreshedOnlyOnce(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	secretProvider, _ := newRefreshableSecretProvider(t, map[string][]byte{
+		"password":      []byte("some-token"),
+		"refresh-token": []byte("some-refresh-token"),
+		"token-url":     []byte(server.URL)})
+	secret := secretProvider.GetSecret(defaultToken)
+	_, err := secret.GitAuthMethod()
+	require.NoError(t, err)
+
+	// when
+	authMethod, err := secret.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "access-token-1", authMethod.(*gitssh.Password).Password)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issuedTokens))
+}
+
+func TestRefreshableOauthTokenSharedBySecretsCreatedFromStaleCopies(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	secretProvider, _ := newRefreshableSecretProvider(t, expiredTokenData(server.URL))
+	_, err := secretProvider.GetSecret(defaultToken).GitAuthMethod()
+	require.NoError(t, err)
+	staleSecretProvider, _ := newRefreshableSecretProvider(t, expiredTokenData(server.URL))
+
+	// when
+	authMethod, err := staleSecretProvider.GetSecret(defaultToken).GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "access-token-1", authMethod.(*gitssh.Password).Password)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issuedTokens))
+}
+
+func TestRefreshableOauthTokenUsesTokenPersistedByOthers(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	secretProvider, client := newRefreshableSecretProvider(t, expiredTokenData(server.URL))
+	secret := secretProvider.GetSecret(defaultToken)
+
+	stored := &corev1.Secret{}
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Namespace: test.Namespace, Name: test.SecretName}, stored))
+	stored.Data["password"] = []byte("token-refreshed-by-others")
+	stored.Data["refresh-token"] = []byte("other-refresh-token")
+	stored.Data["token-expiry"] = []byte(time.Now().Add(time.Hour).Format(time.RFC3339))
+	require.NoError(t, client.Update(context.TODO(), stored))
+
+	// when
+	authMethod, err := secret.GitAuthMethod()
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "token-refreshed-by-others", authMethod.(*gitssh.Password).Password)
+	assert.Zero(t, atomic.LoadInt32(&issuedTokens))
+}
+
+func TestRefreshableOauthTokenRefreshedAtTokenURLOfEachGitSource(t *testing.T) {
+	// given
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "new-token", "refresh_token": "new-refresh-token", "token_type": "bearer", "expires_in": 7200}`)
+	}))
+	defer server.Close()
+	sec := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"password":      []byte("expired-token"),
+		"refresh-token": []byte("some-refresh-token"),
+		"token-expiry":  []byte(time.Now().Add(-time.Minute).Format(time.RFC3339))})
+	sec.UID = types.UID(t.Name())
+	gitLabSource := test.NewGitSource(test.WithURL(server.URL + "/some-org/some-repo"))
+	gitLabSource.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	bitbucketSource := test.NewGitSource(test.WithURL(server.URL+"/some-org/some-repo"), test.WithFlavor("bitbucket"))
+	bitbucketSource.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	gitLabProvider, err := git.NewGitSecretProvider(client, test.Namespace, gitLabSource)
+	require.NoError(t, err)
+	bitbucketProvider, err := git.NewGitSecretProvider(client, test.Namespace, bitbucketSource)
+	require.NoError(t, err)
+
+	// when
+	_, err = bitbucketProvider.GetSecret(defaultToken).GitAuthMethod()
+	require.NoError(t, err)
+	_, err = gitLabProvider.GetSecret(defaultToken).GitAuthMethod()
+	require.NoError(t, err)
+
+	// then
+	assert.Equal(t, []string{"/site/oauth2/access_token", "/oauth/token"}, paths)
+}
+
+func TestRefreshableOauthTokenClientSendsRefreshedToken(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+	token := git.NewRefreshableOauthToken(
+		&oauth2.Token{AccessToken: "expired-token", RefreshToken: "some-refresh-token", Expiry: time.Now().Add(-time.Minute)},
+		&oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: server.URL}},
+		nil)
+
+	// when
+	resp, err := token.Client().Get(api.URL)
+
+	// then
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	assert.Equal(t, "Bearer access-token-1", string(body[:n]))
+	assert.True(t, token.IsRefreshable())
+}
+
+func TestRefreshableOauthTokenFailsWhenTokenCannotBeStored(t *testing.T) {
+	// given
+	var issuedTokens int32
+	server := newTokenServer(t, &issuedTokens)
+	defer server.Close()
+	stored := 0
+	token := git.NewRefreshableOauthToken(
+		&oauth2.Token{AccessToken: "expired-token", RefreshToken: "some-refresh-token", Expiry: time.Now().Add(-time.Minute)},
+		&oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: server.URL}},
+		func(token *oauth2.Token) error {
+			stored++
+			if stored == 1 {
+				return fmt.Errorf("conflict")
+			}
+			return nil
+		})
+
+	// when
+	_, err := token.GitAuthMethod()
+
+	// then
+	require.Error(t, err)
+
+	// and when
+	authMethod, err := token.GitAuthMethod()
+
+	// then the token refreshed before is stored
+	require.NoError(t, err)
+	assert.Equal(t, "access-token-1", authMethod.(*gitssh.Password).Password)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issuedTokens))
+	assert.Equal(t, 2, stored)
+}
+
+func TestGetGitSecretWithInvalidOauthTokenExpiry(t *testing.T) {
+	//given
+	sec := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{
+		"password":      []byte("some-token"),
+		"refresh-token": []byte("some-refresh-token"),
+		"token-expiry":  []byte("tomorrow")})
+
+	gs := test.NewGitSource()
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	assert.Error(t, err)
+	assert.Nil(t, secretProvider)
+}
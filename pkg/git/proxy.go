@@ -0,0 +1,184 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// HTTPProxyAnnotation is an annotation of GitSource overriding URL of the proxy used for http connections
+	HTTPProxyAnnotation = "devconsole.openshift.io/http-proxy"
+	// HTTPSProxyAnnotation is an annotation of GitSource overriding URL of the proxy used for https and ssh connections
+	HTTPSProxyAnnotation = "devconsole.openshift.io/https-proxy"
+	// NoProxyAnnotation is an annotation of GitSource overriding the list of hosts that should be accessed directly
+	NoProxyAnnotation = "devconsole.openshift.io/no-proxy"
+)
+
+// ProxyConfig holds URLs of proxies used for connections to git servers together with a comma-separated list
+// of hosts, domains, IPs and CIDRs that should be accessed directly
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+}
+
+// Validate checks if the proxy URLs can be parsed and use a supported scheme
+func (c *ProxyConfig) Validate() error {
+	for _, proxy := range []string{c.HTTPProxy, c.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+		if _, err := parseProxyURL(proxy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *ProxyConfig) isEmpty() bool {
+	return c == nil || (c.HTTPProxy == "" && c.HTTPSProxy == "")
+}
+
+// ProxyURL returns URL of the proxy that should be used for a connection to the given address (host:port)
+// using the given protocol, or nil if the address should be accessed directly. Connections using other
+// protocols than http (https, ssh, git) go through the https proxy.
+func (c *ProxyConfig) ProxyURL(protocol, addr string) (*url.URL, error) {
+	if c == nil {
+		return nil, nil
+	}
+	proxy := c.HTTPSProxy
+	if protocol == "http" {
+		proxy = c.HTTPProxy
+	}
+	if proxy == "" || c.bypass(addr) {
+		return nil, nil
+	}
+	return parseProxyURL(proxy)
+}
+
+func parseProxyURL(proxy string) (*url.URL, error) {
+	address := proxy
+	if !strings.Contains(address, "://") {
+		// the same as http.ProxyFromEnvironment does - the address without scheme is considered to be http one
+		address = "http://" + address
+	}
+	proxyURL, err := url.Parse(address)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy address %q", proxy)
+	}
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q of the proxy address %q", proxyURL.Scheme, proxy)
+	}
+	return proxyURL, nil
+}
+
+// bypass returns true if the given address matches any of the entries of NoProxy
+func (c *ProxyConfig) bypass(addr string) bool {
+	host, port := splitHostPort(addr)
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(c.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entryHost, entryPort := splitHostPort(entry)
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+		domain := strings.TrimPrefix(entryHost, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitHostPort(addr string) (string, string) {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		return strings.ToLower(host), port
+	}
+	return strings.ToLower(strings.Trim(addr, "[]")), ""
+}
+
+// dialThroughProxy opens a tunnel to the given address through the proxy using HTTP CONNECT method
+func dialThroughProxy(ctx context.Context, dialer *net.Dialer, proxyURL *url.URL, addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		// limit the time of the handshake with the proxy
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if proxyURL.Scheme == "https" {
+		config := &tls.Config{ServerName: proxyURL.Hostname()}
+		if tlsConfig != nil {
+			config.RootCAs = tlsConfig.RootCAs
+			config.InsecureSkipVerify = tlsConfig.InsecureSkipVerify
+		}
+		conn = tls.Client(conn, config)
+	}
+
+	connectReq := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		connectReq.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := connectReq.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connectReq)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", proxyURL.Host, addr, resp.Status)
+	}
+	return &bufferedConn{Conn: conn, reader: reader}, nil
+}
+
+// bufferedConn reads the data that might have been already buffered while reading the response of the proxy
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,99 @@
+package refs
+
+import (
+	"context"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository/generic"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+const (
+	// BranchesKey is a key of the refs config map holding names of the branches separated by new lines
+	BranchesKey = "branches"
+	// TagsKey is a key of the refs config map holding names of the tags separated by new lines
+	TagsKey = "tags"
+	// TruncatedKey is a key of the refs config map set to "true" when the repository contains more branches or tags
+	// than MaxListedRefs
+	TruncatedKey = "truncated"
+
+	// MaxListedRefs is the maximal number of branches and the maximal number of tags that are listed
+	MaxListedRefs = 5 * repository.DefaultRefsPerPage
+)
+
+// Refs holds names of the branches and tags of a repository
+type Refs struct {
+	Branches []string
+	Tags     []string
+	// Truncated is true when only the first MaxListedRefs branches or tags are listed
+	Truncated bool
+}
+
+// ConfigMapName returns name of the config map the branches and tags of the given GitSource are published in
+func ConfigMapName(gitSource *v1alpha1.GitSource) string {
+	return gitSource.Name + "-refs"
+}
+
+// ListRefs lists branches and tags of the git repository defined by the given v1alpha1.GitSource using the secret
+// and the transport settings of the given provider. All calls to the git server are bound to the context.
+func ListRefs(ctx context.Context, log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) (*Refs, error) {
+	return listRefs(ctx, log, gitSource, secretProvider, repository.DefaultRegistry().ServiceCreators())
+}
+
+func listRefs(ctx context.Context,
+	log *log.GitSourceLogger,
+	gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider,
+	serviceCreators []repository.ServiceCreator) (*Refs, error) {
+
+	service, err := repository.NewGitService(log, gitSource, secretProvider, serviceCreators)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		service, err = generic.NewRepositoryService(gitSource, secretProvider)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	branches, branchesTruncated, err := listAll(ctx, service.ListBranches)
+	if err != nil {
+		return nil, err
+	}
+	tags, tagsTruncated, err := listAll(ctx, service.ListTags)
+	if err != nil {
+		return nil, err
+	}
+	return &Refs{
+		Branches:  branches,
+		Tags:      tags,
+		Truncated: branchesTruncated || tagsTruncated,
+	}, nil
+}
+
+type listFunc func(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error)
+
+// listAll goes through the pages until all names or MaxListedRefs names are listed
+func listAll(ctx context.Context, list listFunc) ([]string, bool, error) {
+	var names []string
+	options := repository.RefListOptions{Page: 1, PerPage: repository.DefaultRefsPerPage}
+	for {
+		refList, err := list(ctx, options)
+		if err != nil {
+			return nil, false, err
+		}
+		names = append(names, refList.Names...)
+		if len(names) >= MaxListedRefs {
+			return names[:MaxListedRefs], len(names) > MaxListedRefs || refList.NextPage > 0, nil
+		}
+		if refList.NextPage == 0 {
+			return names, false, nil
+		}
+		options.Page = refList.NextPage
+	}
+}
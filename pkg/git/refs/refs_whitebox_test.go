@@ -0,0 +1,85 @@
+package refs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var logger = &log.GitSourceLogger{Logger: logf.Log}
+
+func TestListRefsUsingMatchingService(t *testing.T) {
+	// given
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	service.Branches = []string{"master", "dev"}
+	service.Tags = []string{"v1.0.0"}
+	source := test.NewGitSource(test.WithFlavor("dummy"))
+
+	// when
+	refs, err := listRefs(context.Background(), logger, source, git.NewSecretProvider(nil),
+		[]repository.ServiceCreator{service.Creator()})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "master"}, refs.Branches)
+	assert.Equal(t, []string{"v1.0.0"}, refs.Tags)
+	assert.False(t, refs.Truncated)
+}
+
+func TestListRefsTruncatesLongLists(t *testing.T) {
+	// given
+	service := test.NewDummyService("dummy", false, test.S(), test.S(), true)
+	for i := 0; i < MaxListedRefs+1; i++ {
+		service.Tags = append(service.Tags, fmt.Sprintf("v%04d", i))
+	}
+	source := test.NewGitSource(test.WithFlavor("dummy"))
+
+	// when
+	refs, err := listRefs(context.Background(), logger, source, git.NewSecretProvider(nil),
+		[]repository.ServiceCreator{service.Creator()})
+
+	// then
+	require.NoError(t, err)
+	assert.Len(t, refs.Tags, MaxListedRefs)
+	assert.Equal(t, "v0000", refs.Tags[0])
+	assert.True(t, refs.Truncated)
+}
+
+func TestListRefsUsingGenericGit(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	dummyRepo.Tag("v1.0.0")
+	dummyRepo.CheckoutBranch("dev")
+	dummyRepo.Commit("pom.xml")
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
+
+	// when
+	refs, err := listRefs(context.Background(), logger, source, git.NewSecretProvider(nil), nil)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "master"}, refs.Branches)
+	assert.Equal(t, []string{"v1.0.0"}, refs.Tags)
+}
+
+func TestListRefsFailsWhenServiceCannotBeCreated(t *testing.T) {
+	// given
+	service := test.NewDummyService("failing", true, test.S(), test.S(), true)
+	source := test.NewGitSource(test.WithFlavor("failing"))
+
+	// when
+	_, err := listRefs(context.Background(), logger, source, git.NewSecretProvider(nil),
+		[]repository.ServiceCreator{service.Creator()})
+
+	// then
+	require.Error(t, err)
+}
@@ -1,5 +1,7 @@
 package bitbucket
 
+import "time"
+
 type Pagination struct {
 	Page int    `json:"page,omitempty"`
 	Next string `json:"next,omitempty"`
@@ -14,10 +16,34 @@ type FileEntry struct {
 	Path string `json:"path,omitempty"`
 }
 
+type FileMeta struct {
+	Path string `json:"path,omitempty"`
+	Type string `json:"type,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
 type RepositoryLanguage struct {
 	Language string `json:"language,omitempty"`
 }
 
+type Repository struct {
+	Description string      `json:"description,omitempty"`
+	MainBranch  *Ref        `json:"mainbranch,omitempty"`
+	IsPrivate   bool        `json:"is_private,omitempty"`
+	Size        int64       `json:"size,omitempty"`
+	UpdatedOn   *time.Time  `json:"updated_on,omitempty"`
+	Parent      *Repository `json:"parent,omitempty"`
+	Links       Links       `json:"links,omitempty"`
+}
+
+type Links struct {
+	HTML Link `json:"html,omitempty"`
+}
+
+type Link struct {
+	Href string `json:"href,omitempty"`
+}
+
 type ResponseError struct {
 	Error Error `json:"error,omitempty"`
 }
@@ -25,3 +51,35 @@ type ResponseError struct {
 type Error struct {
 	Message string `json:"message,omitempty"`
 }
+
+type RepositoryPermissions struct {
+	Pagination
+	Values []RepositoryPermission `json:"values,omitempty"`
+}
+
+type RepositoryPermission struct {
+	Permission string `json:"permission,omitempty"`
+}
+
+type Webhook struct {
+	UUID        string   `json:"uuid,omitempty"`
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Active      bool     `json:"active"`
+	Secret      string   `json:"secret,omitempty"`
+	Events      []string `json:"events,omitempty"`
+}
+
+type Webhooks struct {
+	Pagination
+	Values []Webhook `json:"values,omitempty"`
+}
+
+type Refs struct {
+	Pagination
+	Values []Ref `json:"values,omitempty"`
+}
+
+type Ref struct {
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,12 @@
+package bitbucket
+
+import "github.com/redhat-developer/devconsole-git/pkg/git/repository"
+
+func init() {
+	repository.Register(repository.Provider{
+		Name:           bitbucketFlavor,
+		Priority:       30,
+		Hosts:          []string{bitbucketHost},
+		ServiceCreator: NewRepoServiceIfMatches(),
+	})
+}
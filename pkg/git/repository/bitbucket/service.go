@@ -1,15 +1,20 @@
 package bitbucket
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -19,74 +24,71 @@ const (
 )
 
 type RepositoryService struct {
-	secret  git.Secret
-	client  *http.Client
-	baseURL string
-	repo    repository.StructuredIdentifier
-	log     *log.GitSourceLogger
+	secret           git.Secret
+	client           *http.Client
+	baseURL          string
+	repo             repository.StructuredIdentifier
+	log              *log.GitSourceLogger
+	webhookRequested bool
 }
 
 func NewRepoServiceIfMatches() repository.ServiceCreator {
 	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (repository.GitService, error) {
-		if secretProvider.SecretType() == git.SshKeyType {
+		if secretProvider.SecretType() == git.SshKeyType || secretProvider.SecretType() == git.GitHubAppType {
 			return nil, nil
 		}
-		endpoint, err := gittransport.NewEndpoint(gitSource.Spec.URL)
+		gitURL, err := giturl.Parse(gitSource.Spec.URL)
 		if err != nil {
 			return nil, err
 		}
-		if endpoint.Host == bitbucketHost || gitSource.Spec.Flavor == bitbucketFlavor {
+		if gitURL.Host == bitbucketHost || gitSource.Spec.Flavor == bitbucketFlavor {
 			secret := secretProvider.GetSecret(git.NewOauthToken([]byte("")))
-			return newBbService(log, gitSource, endpoint, secret)
+			return newBbService(log, gitSource, gitURL, secret)
 		}
 		return nil, nil
 	}
 }
 
-func newBbService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, endpoint *gittransport.Endpoint, secret git.Secret) (*RepositoryService, error) {
-	repo, err := repository.NewStructuredIdentifier(gitSource, endpoint)
+func newBbService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, gitURL *giturl.URL, secret git.Secret) (*RepositoryService, error) {
+	repo, err := repository.NewStructuredIdentifier(gitSource, gitURL)
 	if err != nil {
 		return nil, err
 	}
 	client := secret.Client()
 
 	return &RepositoryService{
-		secret:  secret,
-		client:  client,
-		repo:    repo,
-		baseURL: getBaseURL(endpoint),
-		log:     log,
+		secret:           secret,
+		client:           client,
+		repo:             repo,
+		baseURL:          getBaseURL(gitURL),
+		log:              log,
+		webhookRequested: git.IsWebhookRequested(gitSource),
 	}, nil
 }
 
-func getBaseURL(endpoint *gittransport.Endpoint) string {
-	baseURL := "https://api.bitbucket.org/"
-	if endpoint.Host != bitbucketHost {
-		host := endpoint.Host
-		if !strings.HasPrefix(host, "api.") {
-			host = "api." + host
-		}
-		protocol := endpoint.Protocol
-		if endpoint.Protocol == "ssh" || endpoint.Protocol == "git" {
-			protocol = "https"
-		}
-		baseURL = fmt.Sprintf("%s://%s/", protocol, host)
+func getBaseURL(gitURL *giturl.URL) string {
+	if gitURL.Host == bitbucketHost {
+		return "https://api.bitbucket.org/"
 	}
-	return baseURL
+	apiURL := *gitURL
+	if !strings.HasPrefix(apiURL.Host, "api.") {
+		apiURL.Host = "api." + apiURL.Host
+	}
+	return apiURL.BaseURL()
 }
 
-func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChecker, error) {
+func (s *RepositoryService) FileExistenceChecker(ctx context.Context) (repository.FileExistenceChecker, error) {
 	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/src/%s/?q=type="commit_file"`,
 		s.baseURL, s.repo.Owner, s.repo.Name, s.repo.Branch)
-	files, err := s.doPaginatedCalls(apiURL)
+	files, err := s.doPaginatedCalls(ctx, apiURL)
 	if err != nil {
 		return nil, err
 	}
 	return repository.NewCheckerWithFetchedFiles(files), nil
 }
 
-func (s *RepositoryService) doPaginatedCalls(apiURL string) ([]string, error) {
-	respBody, err := s.do(apiURL)
+func (s *RepositoryService) doPaginatedCalls(ctx context.Context, apiURL string) ([]string, error) {
+	respBody, err := s.do(ctx, apiURL)
 	if err != nil {
 		return nil, err
 	}
@@ -100,7 +102,7 @@ func (s *RepositoryService) doPaginatedCalls(apiURL string) ([]string, error) {
 		filenames = append(filenames, entry.Path)
 	}
 	if files.Next != "" {
-		anotherFiles, err := s.doPaginatedCalls(files.Next)
+		anotherFiles, err := s.doPaginatedCalls(ctx, files.Next)
 		if err != nil {
 			return nil, err
 		}
@@ -109,9 +111,9 @@ func (s *RepositoryService) doPaginatedCalls(apiURL string) ([]string, error) {
 	return filenames, nil
 }
 
-func (s *RepositoryService) GetLanguageList() ([]string, error) {
+func (s *RepositoryService) GetLanguageList(ctx context.Context) ([]string, error) {
 	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/`, s.baseURL, s.repo.Owner, s.repo.Name)
-	respBody, err := s.do(apiURL)
+	respBody, err := s.do(ctx, apiURL)
 	if err != nil {
 		return nil, err
 	}
@@ -124,56 +126,317 @@ func (s *RepositoryService) GetLanguageList() ([]string, error) {
 	return []string{repoLanguage.Language}, nil
 }
 
-func (s *RepositoryService) CheckCredentials() error {
+func (s *RepositoryService) CheckCredentials(ctx context.Context) error {
 	apiURL := fmt.Sprintf(`%s2.0/user`, s.baseURL)
-	_, err := s.do(apiURL)
+	_, err := s.do(ctx, apiURL)
 	return err
 }
 
-func (s *RepositoryService) CheckRepoAccessibility() error {
+func (s *RepositoryService) CheckRepoAccessibility(ctx context.Context) error {
 	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/`, s.baseURL, s.repo.Owner, s.repo.Name)
-	_, err := s.do(apiURL)
+	_, err := s.do(ctx, apiURL)
 	return err
 }
 
-func (s *RepositoryService) CheckBranch() error {
+func (s *RepositoryService) CheckBranch(ctx context.Context) error {
 	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/refs/branches/%s`, s.baseURL, s.repo.Owner, s.repo.Name, s.repo.Branch)
-	_, err := s.do(apiURL)
+	_, err := s.do(ctx, apiURL)
 	return err
 }
 
-func (s *RepositoryService) do(apiURL string) ([]byte, error) {
-	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+// CheckPermissions checks the scopes of the OAuth token sent in the X-OAuth-Scopes header
+// and the permission of the account in the repository. The permission to create webhooks is checked only if
+// the GitSource requests a webhook.
+func (s *RepositoryService) CheckPermissions(ctx context.Context) ([]repository.MissingPermission, error) {
+	if s.secret.SecretContent() == "" {
+		return nil, nil
+	}
+	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/`, s.baseURL, s.repo.Owner, s.repo.Name)
+	_, header, err := s.doWithHeader(ctx, apiURL)
 	if err != nil {
 		return nil, err
 	}
-	if s.secret.SecretType() == git.UsernamePasswordType {
-		req.SetBasicAuth(git.ParseUsernameAndPassword(s.secret.SecretContent()))
+
+	var missing []repository.MissingPermission
+	if granted, ok := header["X-Oauth-Scopes"]; ok {
+		scopes := repository.ParseScopes(strings.Join(granted, ","))
+		if !repository.HasAnyScope(scopes, "repository", "repository:write", "repository:admin") {
+			missing = repository.AppendMissing(missing, repository.ReadContents, "the token needs the repository scope")
+		}
+		if s.webhookRequested && !scopes["webhook"] {
+			missing = repository.AppendMissing(missing, repository.CreateWebhooks, "the token needs the webhook scope")
+		}
+	}
+	if !s.webhookRequested {
+		// the permission of the account is needed only to create webhooks
+		return missing, nil
 	}
-	resp, err := s.client.Do(req)
+
+	apiURL = fmt.Sprintf(`%s2.0/user/permissions/repositories?q=%s`, s.baseURL,
+		url.QueryEscape(fmt.Sprintf(`repository.full_name="%s/%s"`, s.repo.Owner, s.repo.Name)))
+	respBody, err := s.do(ctx, apiURL)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		err := resp.Body.Close()
+	var permissions RepositoryPermissions
+	err = json.Unmarshal(respBody, &permissions)
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions.Values) == 0 || permissions.Values[0].Permission != "admin" {
+		missing = repository.AppendMissing(missing, repository.CreateWebhooks,
+			"the account needs admin access to the repository")
+	}
+	return missing, nil
+}
+
+// GetMetadata reads the metadata from the repository details. Bitbucket doesn't support archiving
+// nor topics of repositories and it doesn't track the time of the last push, so the time of the last update is used.
+func (s *RepositoryService) GetMetadata(ctx context.Context) (*repository.Metadata, error) {
+	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/`, s.baseURL, s.repo.Owner, s.repo.Name)
+	respBody, err := s.do(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	var repo Repository
+	err = json.Unmarshal(respBody, &repo)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &repository.Metadata{
+		Description:  repo.Description,
+		Visibility:   repository.Public,
+		Fork:         repo.Parent != nil,
+		Size:         repo.Size,
+		LastPushedAt: repo.UpdatedOn,
+		WebURL:       repo.Links.HTML.Href,
+	}
+	if repo.IsPrivate {
+		metadata.Visibility = repository.Private
+	}
+	if repo.MainBranch != nil {
+		metadata.DefaultBranch = repo.MainBranch.Name
+	}
+	return metadata, nil
+}
+
+// CreateWebhook registers a webhook sending push events signed using the given secret
+func (s *RepositoryService) CreateWebhook(ctx context.Context, url, secret string) (string, error) {
+	if s.secret.SecretContent() == "" {
+		return "", fmt.Errorf("a secret with credentials is required to create a webhook")
+	}
+	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/hooks`, s.baseURL, s.repo.Owner, s.repo.Name)
+	respBody, _, err := s.doRequest(ctx, http.MethodPost, apiURL, Webhook{
+		Description: "GitSource push notifications",
+		URL:         url,
+		Active:      true,
+		Secret:      secret,
+		Events:      []string{"repo:push"},
+	})
+	if err != nil {
+		return "", err
+	}
+	var hook Webhook
+	err = json.Unmarshal(respBody, &hook)
+	if err != nil {
+		return "", err
+	}
+	return hook.UUID, nil
+}
+
+func (s *RepositoryService) FindWebhook(ctx context.Context, url string) (string, error) {
+	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/hooks?pagelen=100`, s.baseURL, s.repo.Owner, s.repo.Name)
+	for apiURL != "" {
+		respBody, err := s.do(ctx, apiURL)
 		if err != nil {
-			s.log.Error(err, "closing body failed")
+			return "", err
+		}
+		var hooks Webhooks
+		if err := json.Unmarshal(respBody, &hooks); err != nil {
+			return "", err
+		}
+		for _, hook := range hooks.Values {
+			if hook.URL == url {
+				return hook.UUID, nil
+			}
 		}
-	}()
+		apiURL = hooks.Next
+	}
+	return "", nil
+}
 
-	respBody, err := ioutil.ReadAll(resp.Body)
+func (s *RepositoryService) WebhookExists(ctx context.Context, id string) (bool, error) {
+	_, err := s.do(ctx, s.webhookURL(id))
+	if err != nil {
+		if apiErr := repository.AsAPIError(err); apiErr != nil && apiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *RepositoryService) DeleteWebhook(ctx context.Context, id string) error {
+	_, _, err := s.doRequest(ctx, http.MethodDelete, s.webhookURL(id), nil)
+	if apiErr := repository.AsAPIError(err); apiErr != nil && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// ListBranches returns the branches sorted by name
+func (s *RepositoryService) ListBranches(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	return s.listRefs(ctx, "branches", options)
+}
+
+// ListTags returns the tags sorted by name
+func (s *RepositoryService) ListTags(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	return s.listRefs(ctx, "tags", options)
+}
+
+// listRefs lists the references of the given type. The Bitbucket query matches the prefix anywhere in the name
+// so the returned names are filtered once more.
+func (s *RepositoryService) listRefs(ctx context.Context, refType string, options repository.RefListOptions) (*repository.RefList, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(options.PageNumber()))
+	query.Set("pagelen", strconv.Itoa(options.PageSize()))
+	query.Set("sort", "name")
+	if options.Prefix != "" {
+		query.Set("q", fmt.Sprintf(`name ~ "%s"`, strings.Replace(options.Prefix, `"`, `\"`, -1)))
+	}
+	apiURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/refs/%s?%s`,
+		s.baseURL, s.repo.Owner, s.repo.Name, refType, query.Encode())
+	respBody, err := s.do(ctx, apiURL)
+	if err != nil {
+		return nil, err
+	}
+	var refs Refs
+	if err := json.Unmarshal(respBody, &refs); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, ref := range refs.Values {
+		names = append(names, ref.Name)
+	}
+	refList := &repository.RefList{Names: options.FilterByPrefix(names)}
+	if refs.Next != "" {
+		refList.NextPage = options.PageNumber() + 1
+	}
+	return refList, nil
+}
 
+// GetFileContent checks the type and the size of the file using its metadata and then downloads the raw content
+func (s *RepositoryService) GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error) {
+	limit := repository.MaxFileSize(maxSize)
+	fileURL := fmt.Sprintf(`%s2.0/repositories/%s/%s/src/%s/%s`,
+		s.baseURL, s.repo.Owner, s.repo.Name, url.PathEscape(s.repo.Branch), escapePath(path))
+	respBody, err := s.do(ctx, fileURL+"?format=meta")
 	if err != nil {
+		if apiErr := repository.AsAPIError(err); apiErr != nil && apiErr.StatusCode == http.StatusNotFound {
+			return nil, &repository.FileNotFoundError{Path: path}
+		}
+		return nil, err
+	}
+	var meta FileMeta
+	if err := json.Unmarshal(respBody, &meta); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		errMsg := "call to the API endpoint %s failed with [%s] and message [%s]"
-		var respErr ResponseError
-		err = json.Unmarshal(respBody, &respErr)
-		if err != nil || respErr.Error.Message == "" {
-			return nil, fmt.Errorf(errMsg, apiURL, resp.Status, string(respBody))
+	if meta.Type != "commit_file" {
+		return nil, &repository.FileNotFoundError{Path: path}
+	}
+	if meta.Size > limit {
+		return nil, &repository.FileTooLargeError{Path: path, Limit: limit}
+	}
+
+	resp, err := s.send(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer s.closeBody(resp)
+	return repository.ReadLimited(path, resp.Body, limit)
+}
+
+// escapePath escapes every segment of the given path of a file
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func (s *RepositoryService) webhookURL(id string) string {
+	return fmt.Sprintf(`%s2.0/repositories/%s/%s/hooks/%s`, s.baseURL, s.repo.Owner, s.repo.Name, url.PathEscape(id))
+}
+
+func (s *RepositoryService) do(ctx context.Context, apiURL string) ([]byte, error) {
+	respBody, _, err := s.doWithHeader(ctx, apiURL)
+	return respBody, err
+}
+
+func (s *RepositoryService) doWithHeader(ctx context.Context, apiURL string) ([]byte, http.Header, error) {
+	return s.doRequest(ctx, http.MethodGet, apiURL, nil)
+}
+
+func (s *RepositoryService) doRequest(ctx context.Context, method, apiURL string, body interface{}) ([]byte, http.Header, error) {
+	resp, err := s.send(ctx, method, apiURL, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.closeBody(resp)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return respBody, resp.Header, nil
+}
+
+// send sends the request and returns the response if it is successful. The caller is responsible for closing its body.
+func (s *RepositoryService) send(ctx context.Context, method, apiURL string, body interface{}) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
 		}
-		return nil, fmt.Errorf(errMsg, apiURL, resp.Status, respErr.Error.Message)
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, apiURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.secret.SecretType() == git.UsernamePasswordType {
+		req.SetBasicAuth(git.ParseUsernameAndPassword(s.secret.SecretContent()))
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer s.closeBody(resp)
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	errMsg := "call to the API endpoint %s failed with [%s] and message [%s]"
+	var respErr ResponseError
+	err = json.Unmarshal(respBody, &respErr)
+	if err != nil || respErr.Error.Message == "" {
+		return nil, repository.NewAPIError(resp, fmt.Sprintf(errMsg, apiURL, resp.Status, string(respBody)))
+	}
+	return nil, repository.NewAPIError(resp, fmt.Sprintf(errMsg, apiURL, resp.Status, respErr.Error.Message))
+}
+
+func (s *RepositoryService) closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		s.log.Error(err, "closing body failed")
 	}
-	return respBody, nil
 }
@@ -1,9 +1,11 @@
 package bitbucket_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/bitbucket"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
@@ -12,6 +14,7 @@ import (
 	"gopkg.in/h2non/gock.v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"testing"
+	"time"
 )
 
 const (
@@ -45,14 +48,14 @@ func TestRepositoryServiceForAllValidAuthMethodsSuccessful(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 2)
 		assert.Contains(t, filesInRootDir, "pom.xml")
 		assert.Contains(t, filesInRootDir, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 1)
 		assert.Contains(t, languageList, "Java")
@@ -116,11 +119,11 @@ func TestRepositoryServiceForWrongBranch(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		assertErrorIsNotFound(t, err, repoIdentifier, "Commit not found")
 		require.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		assertErrorIsNotFound(t, err, repoIdentifier, "Commit not found")
 		require.Len(t, languageList, 0)
 	}
@@ -144,11 +147,11 @@ func TestRepositoryServiceForWrongRepo(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		assertErrorIsNotFound(t, err, "some-non-existing-org/some-repo", "Repository some-non-existing-org/some-repo not found")
 		require.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		assertErrorIsNotFound(t, err, "some-non-existing-org/some-repo", "Repository some-non-existing-org/some-repo not found")
 		require.Len(t, languageList, 0)
 	}
@@ -178,11 +181,11 @@ func TestRepositoryServiceReturningForbidden(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		assertErrorIsForbidden(t, err)
 		require.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		assertErrorIsForbidden(t, err)
 		require.Len(t, languageList, 0)
 	}
@@ -212,11 +215,11 @@ func TestRepositoryServiceReturningTokenExpired(t *testing.T) {
 	// then
 	require.NoError(t, err)
 
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 	assertErrorIsTokenExp(t, err)
 	require.Nil(t, checker)
 
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(context.Background())
 	assertErrorIsTokenExp(t, err)
 	require.Len(t, languageList, 0)
 }
@@ -244,14 +247,14 @@ func TestRepositoryServiceForPrivateInstance(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 2)
 		assert.Contains(t, filesInRootDir, "pom.xml")
 		assert.Contains(t, filesInRootDir, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 1)
 		assert.Contains(t, languageList, "Java")
@@ -277,7 +280,7 @@ func TestRepositoryServiceWithPaginatedResult(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 3)
@@ -285,7 +288,7 @@ func TestRepositoryServiceWithPaginatedResult(t *testing.T) {
 		assert.Contains(t, filesInRootDir, "mvnw")
 		assert.Contains(t, filesInRootDir, "any")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 1)
 		assert.Contains(t, languageList, "Java")
@@ -306,7 +309,7 @@ func TestRepositoryServiceCheckValidCredentials(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -327,7 +330,7 @@ func TestRepositoryServiceCheckInvalidCredentials(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 
 		// then
 		assert.Error(t, err)
@@ -346,7 +349,7 @@ func TestRepositoryServiceCheckAccessibleRepo(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -368,7 +371,7 @@ func TestRepositoryServiceCheckInaccessibleRepo(t *testing.T) {
 			require.NoError(t, err)
 
 			// when
-			err = service.CheckRepoAccessibility()
+			err = service.CheckRepoAccessibility(context.Background())
 
 			// then
 			assert.Error(t, err)
@@ -390,7 +393,7 @@ func TestRepositoryServiceCheckPresentBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -411,13 +414,353 @@ func TestRepositoryServiceCheckMissingBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.Error(t, err)
 	}
 }
 
+func TestRepositoryServiceCheckPermissionsOfTokenWithoutWebhookScope(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "account repository").
+		BodyString("{}")
+	mockBBPermissionCall(t, bbApiHost, repoIdentifier, "admin")
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "webhook scope")
+}
+
+func TestRepositoryServiceCheckPermissionsOfAccountWithWriteAccess(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "repository:write, webhook").
+		BodyString("{}")
+	mockBBPermissionCall(t, bbApiHost, repoIdentifier, "write")
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "admin access")
+}
+
+func TestRepositoryServiceCheckPermissionsOfAdmin(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "repository, webhook").
+		BodyString("{}")
+	mockBBPermissionCall(t, bbApiHost, repoIdentifier, "admin")
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestRepositoryServiceCheckPermissionsWithoutRequestedWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "account repository").
+		BodyString("{}")
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+	assert.False(t, gock.HasUnmatchedRequest())
+}
+
+func TestRepositoryServiceCreateWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Post(fmt.Sprintf("/2.0/repositories/%s/hooks", repoIdentifier)).
+		MatchType("json").
+		BodyString(`"events":\["repo:push"\]`).
+		Reply(201).
+		BodyString(`{"uuid": "{some-uuid}"}`)
+	gock.New(bbApiHost).
+		Delete(fmt.Sprintf("/2.0/repositories/%s/hooks/.+some-uuid.+", repoIdentifier)).
+		Reply(204)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+	webhookService := service.(repository.WebhookService)
+
+	// when
+	id, err := webhookService.CreateWebhook(context.Background(), "https://some.host/hook", "some-secret")
+	require.NoError(t, err)
+	err = webhookService.DeleteWebhook(context.Background(), id)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "{some-uuid}", id)
+	assert.True(t, gock.IsDone())
+}
+
+func TestRepositoryServiceFindWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/hooks", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"values": [{"uuid": "{other-uuid}", "url": "https://other.host/hook"}, ` +
+			`{"uuid": "{some-uuid}", "url": "https://some.host/hook"}]}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.(repository.WebhookService).FindWebhook(context.Background(), "https://some.host/hook")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "{some-uuid}", id)
+}
+
+func TestRepositoryServiceListBranches(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/refs/branches", repoIdentifier)).
+		MatchParam("pagelen", "2").
+		MatchParam("q", `name ~ "feature-"`).
+		MatchParam("sort", "name").
+		Reply(200).
+		BodyString(`{"page": 1, "next": "https://api.bitbucket.org/2.0/next", ` +
+			`"values": [{"name": "feature-a"}, {"name": "my-feature-b"}]}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	branches, err := service.ListBranches(context.Background(),
+		repository.RefListOptions{PerPage: 2, Prefix: "feature-"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature-a"}, branches.Names)
+	assert.Equal(t, 2, branches.NextPage)
+}
+
+func TestRepositoryServiceListTags(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/refs/tags", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"page": 1, "values": [{"name": "v1.0.0"}, {"name": "v1.1.0"}]}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	tags, err := service.ListTags(context.Background(), repository.RefListOptions{})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, tags.Names)
+	assert.Equal(t, 0, tags.NextPage)
+}
+
+func TestRepositoryServiceGetFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/docs/README.md", repoIdentifier)).
+		MatchParam("format", "meta").
+		Reply(200).
+		BodyString(`{"path": "docs/README.md", "type": "commit_file", "size": 11}`)
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/docs/README.md", repoIdentifier)).
+		Reply(200).
+		BodyString("# Some repo")
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	content, err := service.GetFileContent(context.Background(), "docs/README.md", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "# Some repo", string(content))
+	assert.True(t, gock.IsDone())
+}
+
+func TestRepositoryServiceGetFileContentOfDirectory(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/docs", repoIdentifier)).
+		MatchParam("format", "meta").
+		Reply(200).
+		BodyString(`{"path": "docs", "type": "commit_directory"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "docs", 0)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a file")
+}
+
+func TestRepositoryServiceGetFileContentExceedingLimit(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/README.md", repoIdentifier)).
+		MatchParam("format", "meta").
+		Reply(200).
+		BodyString(`{"path": "README.md", "type": "commit_file", "size": 11}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "README.md", 5)
+
+	// then
+	assert.Equal(t, &repository.FileTooLargeError{Path: "README.md", Limit: 5}, err)
+}
+
+func TestRepositoryServiceGetMissingFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/devfile.yaml", repoIdentifier)).
+		Reply(404).
+		BodyString(`{"type": "error", "error": {"message": "No such file or directory: devfile.yaml"}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "devfile.yaml", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "devfile.yaml"}, err)
+}
+
+func TestRepositoryServiceGetFileContentOfDirectory(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/src/master/docs", repoIdentifier)).
+		MatchParam("format", "meta").
+		Reply(200).
+		BodyString(`{"type": "commit_directory", "path": "docs"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "docs", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "docs"}, err)
+}
+
+func TestRepositoryServiceGetMetadata(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(bbApiHost).
+		Get(fmt.Sprintf("/2.0/repositories/%s/", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"description": "Some repo", "mainbranch": {"name": "develop"}, "is_private": false, "size": 4096,
+			"updated_on": "2019-04-10T09:45:12Z", "parent": {"full_name": "other-org/some-repo"},
+			"links": {"html": {"href": "https://bitbucket.org/some-org/some-repo"}}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := bitbucket.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	metadata, err := service.(repository.MetadataService).GetMetadata(context.Background())
+
+	// then
+	require.NoError(t, err)
+	updatedOn := time.Date(2019, 4, 10, 9, 45, 12, 0, time.UTC)
+	assert.Equal(t, &repository.Metadata{
+		Description:   "Some repo",
+		DefaultBranch: "develop",
+		Visibility:    repository.Public,
+		Fork:          true,
+		Size:          4096,
+		LastPushedAt:  &updatedOn,
+		WebURL:        "https://bitbucket.org/some-org/some-repo",
+	}, metadata)
+}
+
 func mockBBCalls(t *testing.T, host, prjPath, branch, lang string, files test.SliceOfStrings) {
 	mockBBFilesCall(t, host, prjPath, branch, "", "", files)
 	mockBBRepoCall(t, host, prjPath, lang)
@@ -462,3 +805,17 @@ func mockBBFilesCall(t *testing.T, host, prjPath, branch, pageParam, nextPage st
 	mock.Reply(200).
 		BodyString(string(bytes))
 }
+
+func mockBBPermissionCall(t *testing.T, host, prjPath, permission string) {
+	permissions := bitbucket.RepositoryPermissions{
+		Values: []bitbucket.RepositoryPermission{{Permission: permission}},
+	}
+	bytes, err := json.Marshal(permissions)
+	require.NoError(t, err)
+
+	gock.New(host).
+		Get("/2.0/user/permissions/repositories").
+		MatchParam("q", fmt.Sprintf(`repository.full_name="%s"`, prjPath)).
+		Reply(200).
+		BodyString(string(bytes))
+}
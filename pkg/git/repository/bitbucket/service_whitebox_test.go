@@ -5,16 +5,16 @@ import (
 	"github.com/stretchr/testify/require"
 	"testing"
 
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 )
 
 func TestGetBaseURLReturnsDefault(t *testing.T) {
 	// given
-	endpoint, err := gittransport.NewEndpoint("https://bitbucket.org/some-org/some-repo/")
+	gitURL, err := giturl.Parse("https://bitbucket.org/some-org/some-repo/")
 	require.NoError(t, err)
 
 	// when
-	url := getBaseURL(endpoint)
+	url := getBaseURL(gitURL)
 
 	// then
 	assert.Equal(t, "https://api.bitbucket.org/", url)
@@ -22,11 +22,11 @@ func TestGetBaseURLReturnsDefault(t *testing.T) {
 
 func TestGetBaseURLReturnsCustom(t *testing.T) {
 	// given
-	endpoint, err := gittransport.NewEndpoint("https://bitbucket.redhat.com/some-org/some-repo/")
+	gitURL, err := giturl.Parse("https://bitbucket.redhat.com/some-org/some-repo/")
 	require.NoError(t, err)
 
 	// when
-	url := getBaseURL(endpoint)
+	url := getBaseURL(gitURL)
 
 	// then
 	assert.Equal(t, "https://api.bitbucket.redhat.com/", url)
@@ -34,11 +34,11 @@ func TestGetBaseURLReturnsCustom(t *testing.T) {
 
 func TestGetBaseURLFromDefaultSsh(t *testing.T) {
 	// given
-	endpoint, err := gittransport.NewEndpoint("git@bitbucket.org:some-org/some-repo.git")
+	gitURL, err := giturl.Parse("git@bitbucket.org:some-org/some-repo.git")
 	require.NoError(t, err)
 
 	// when
-	url := getBaseURL(endpoint)
+	url := getBaseURL(gitURL)
 
 	// then
 	assert.Equal(t, "https://api.bitbucket.org/", url)
@@ -46,11 +46,11 @@ func TestGetBaseURLFromDefaultSsh(t *testing.T) {
 
 func TestGetBaseURLFromCustomSsh(t *testing.T) {
 	// given
-	endpoint, err := gittransport.NewEndpoint("git@bitbucket.redhat.com:some-org/some-repo.git")
+	gitURL, err := giturl.Parse("git@bitbucket.redhat.com:some-org/some-repo.git")
 	require.NoError(t, err)
 
 	// when
-	url := getBaseURL(endpoint)
+	url := getBaseURL(gitURL)
 
 	// then
 	assert.Equal(t, "https://api.bitbucket.redhat.com/", url)
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/base64"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
@@ -25,7 +26,7 @@ func (c *checkerWithFetchedFiles) GetListOfFoundFiles() []string {
 	return c.filenames
 }
 
-func (c *checkerWithFetchedFiles) DetectFiles(buildTool build.Tool) []string {
+func (c *checkerWithFetchedFiles) DetectFiles(ctx context.Context, buildTool build.Tool) []string {
 	detectedFiles := make(chan string, len(buildTool.ExpectedRegexps))
 	var wg sync.WaitGroup
 	wg.Add(len(buildTool.ExpectedRegexps))
@@ -78,7 +79,7 @@ func (c *checkerUsingHeaderRequests) GetListOfFoundFiles() []string {
 	return []string{}
 }
 
-func (c *checkerUsingHeaderRequests) DetectFiles(buildTool build.Tool) []string {
+func (c *checkerUsingHeaderRequests) DetectFiles(ctx context.Context, buildTool build.Tool) []string {
 	detectedFiles := make(chan string, len(buildTool.ExpectedFiles))
 	var wg sync.WaitGroup
 	wg.Add(len(buildTool.ExpectedFiles))
@@ -87,7 +88,7 @@ func (c *checkerUsingHeaderRequests) DetectFiles(buildTool build.Tool) []string
 	for _, file := range buildTool.ExpectedFiles {
 		go func(buildToolFile string) {
 			defer wg.Done()
-			if c.fileExists(client, buildToolFile) {
+			if c.fileExists(ctx, client, buildToolFile) {
 				detectedFiles <- buildToolFile
 			}
 		}(file)
@@ -105,13 +106,13 @@ func (c *checkerUsingHeaderRequests) DetectFiles(buildTool build.Tool) []string
 	return result
 }
 
-func (c *checkerUsingHeaderRequests) fileExists(client *http.Client, buildToolFile string) bool {
+func (c *checkerUsingHeaderRequests) fileExists(ctx context.Context, client *http.Client, buildToolFile string) bool {
 	url := c.baseURL + buildToolFile
 	request, err := NewRequest(http.MethodHead, url, c.secret)
 	if err != nil {
 		return false
 	}
-	resp, err := client.Do(request)
+	resp, err := client.Do(request.WithContext(ctx))
 	if err != nil {
 		return false
 	} else {
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxFileSize is the maximal size of a file whose content is retrieved when no other limit is requested
+const DefaultMaxFileSize = 1024 * 1024
+
+// FileNotFoundError is returned when the requested file doesn't exist in the branch of the repository
+// or when the path points to something else than a file (e.g. a directory or a submodule)
+type FileNotFoundError struct {
+	Path string
+}
+
+func (e *FileNotFoundError) Error() string {
+	return fmt.Sprintf("the file %s doesn't exist in the repository", e.Path)
+}
+
+// FileTooLargeError is returned when the size of the requested file exceeds the limit
+type FileTooLargeError struct {
+	Path  string
+	Limit int64
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("the file %s is larger than the limit of %d bytes", e.Path, e.Limit)
+}
+
+// MaxFileSize returns the given limit of the file size if it is positive, DefaultMaxFileSize otherwise
+func MaxFileSize(maxSize int64) int64 {
+	if maxSize <= 0 {
+		return DefaultMaxFileSize
+	}
+	return maxSize
+}
+
+// ReadLimited reads the whole content of the file from the reader.
+// If the content is larger than the given limit, then FileTooLargeError is returned.
+func ReadLimited(path string, reader io.Reader, limit int64) ([]byte, error) {
+	content := NewLimitedBuffer(path, limit)
+	if _, err := io.Copy(content, reader); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// LimitedBuffer is an io.Writer collecting the content of a file that fails with FileTooLargeError
+// when more than the limit of bytes is written
+type LimitedBuffer struct {
+	buffer bytes.Buffer
+	path   string
+	limit  int64
+}
+
+// NewLimitedBuffer returns a buffer for the content of the file with the given path that accepts at most limit bytes
+func NewLimitedBuffer(path string, limit int64) *LimitedBuffer {
+	return &LimitedBuffer{path: path, limit: limit}
+}
+
+func (b *LimitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.buffer.Len()+len(p)) > b.limit {
+		return 0, &FileTooLargeError{Path: b.path, Limit: b.limit}
+	}
+	return b.buffer.Write(p)
+}
+
+// Bytes returns the collected content
+func (b *LimitedBuffer) Bytes() []byte {
+	return b.buffer.Bytes()
+}
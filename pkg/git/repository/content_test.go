@@ -0,0 +1,34 @@
+package repository_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLimitedReadsWholeContent(t *testing.T) {
+	// when
+	content, err := repository.ReadLimited("README.md", strings.NewReader("some content"), 12)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "some content", string(content))
+}
+
+func TestReadLimitedFailsWhenContentExceedsLimit(t *testing.T) {
+	// when
+	_, err := repository.ReadLimited("README.md", strings.NewReader("some content"), 11)
+
+	// then
+	require.Error(t, err)
+	assert.Equal(t, &repository.FileTooLargeError{Path: "README.md", Limit: 11}, err)
+}
+
+func TestMaxFileSizeUsesDefaultWhenNotPositive(t *testing.T) {
+	assert.Equal(t, int64(repository.DefaultMaxFileSize), repository.MaxFileSize(0))
+	assert.Equal(t, int64(repository.DefaultMaxFileSize), repository.MaxFileSize(-1))
+	assert.Equal(t, int64(10), repository.MaxFileSize(10))
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// APIError is returned by the services when the API of the git server responds with an unsuccessful status code
+type APIError struct {
+	StatusCode int
+	Header     http.Header
+	message    string
+}
+
+// NewAPIError creates an APIError from the given response of the API with the message describing the failure
+func NewAPIError(resp *http.Response, message string) *APIError {
+	apiErr := &APIError{message: message}
+	if resp != nil {
+		apiErr.StatusCode = resp.StatusCode
+		apiErr.Header = resp.Header
+	}
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	return e.message
+}
+
+// AsAPIError returns the APIError if the given error is or wraps one, nil otherwise
+func AsAPIError(err error) *APIError {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr
+	}
+	return nil
+}
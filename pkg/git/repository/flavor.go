@@ -0,0 +1,19 @@
+package repository
+
+const (
+	GitHubFlavor    = "github"
+	GitLabFlavor    = "gitlab"
+	BitbucketFlavor = "bitbucket"
+	GiteaFlavor     = "gitea"
+)
+
+// FlavorForHost returns flavor of the git service hosted on the given host or an empty string if the host is unknown.
+// The providers registered in the default registry are used.
+func FlavorForHost(host string) string {
+	return defaultRegistry.FlavorForHost(host)
+}
+
+// IsKnownFlavor returns true if there is an enabled git service provider supporting the given flavor
+func IsKnownFlavor(flavor string) bool {
+	return defaultRegistry.IsKnownFlavor(flavor)
+}
@@ -0,0 +1,233 @@
+package generic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	gitcache "gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// cloneCache is the cache used by all services created by NewRepositoryService, nil if the repositories are fetched
+// into memory
+var cloneCache *CloneCache
+
+// UseCloneCache makes all services created by NewRepositoryService fetch the repositories into the given cache.
+// If the cache is nil, then each service fetches the repository into memory.
+func UseCloneCache(cache *CloneCache) {
+	cloneCache = cache
+}
+
+// CloneCache is an on-disk cache of bare repositories shared by the services of all GitSources pointing
+// to the same repository. Once a branch is fetched, only the objects of newer commits are fetched again.
+// When the total size of the cache exceeds the limit, the least recently used repositories are removed.
+type CloneCache struct {
+	dir     string
+	maxSize int64
+	mux     sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry is a bare repository stored in the cache
+type cacheEntry struct {
+	// mux serializes the fetches into the repository and the reads from it
+	mux     sync.Mutex
+	dir     string
+	storage *writeTrackingStorage
+	// the following fields are guarded by the mutex of the cache
+	lastUsed time.Time
+	inUse    int
+	size     int64
+}
+
+// NewCloneCache returns a cache storing the repositories in the given directory, limited to the given size in bytes.
+// The repositories already present in the directory are reused.
+func NewCloneCache(dir string, maxSize int64) (*CloneCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	cache := &CloneCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: map[string]*cacheEntry{},
+	}
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		entry := cache.newEntry(info.Name())
+		entry.lastUsed = info.ModTime()
+		entry.size, err = dirSize(entry.dir)
+		if err != nil {
+			return nil, err
+		}
+		cache.entries[info.Name()] = entry
+	}
+	return cache, nil
+}
+
+func (c *CloneCache) newEntry(key string) *cacheEntry {
+	dir := filepath.Join(c.dir, key)
+	return &cacheEntry{
+		dir: dir,
+		storage: &writeTrackingStorage{
+			Storage: filesystem.NewStorage(osfs.New(dir), gitcache.NewObjectLRUDefault()),
+		},
+	}
+}
+
+// writeTrackingStorage records that objects were written into the repository, so its size is computed again
+// only after a fetch. It is guarded by the mutex of the cache entry.
+type writeTrackingStorage struct {
+	*filesystem.Storage
+	written bool
+}
+
+func (s *writeTrackingStorage) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	s.written = true
+	return s.Storage.SetEncodedObject(obj)
+}
+
+func (s *writeTrackingStorage) PackfileWriter() (io.WriteCloser, error) {
+	s.written = true
+	return s.Storage.PackfileWriter()
+}
+
+// acquire returns the locked repository stored under the given key. The repository is not removed from the cache
+// until it is released.
+func (c *CloneCache) acquire(key string) *cacheEntry {
+	c.mux.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = c.newEntry(key)
+		c.entries[key] = entry
+	}
+	entry.inUse++
+	c.mux.Unlock()
+
+	entry.mux.Lock()
+	return entry
+}
+
+// release unlocks the repository and removes the least recently used repositories if the cache is too large.
+// The size of the repository is computed again only if objects were written into it.
+func (c *CloneCache) release(entry *cacheEntry) {
+	written := entry.storage.written
+	entry.storage.written = false
+	var size int64
+	var err error
+	if written {
+		size, err = dirSize(entry.dir)
+	}
+	entry.mux.Unlock()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if written && err == nil {
+		entry.size = size
+	}
+	entry.lastUsed = time.Now()
+	entry.inUse--
+	c.evict()
+}
+
+// evict removes the least recently used repositories that are not in use until the cache fits into the limit
+func (c *CloneCache) evict() {
+	var total int64
+	for _, entry := range c.entries {
+		total += entry.size
+	}
+	for total > c.maxSize {
+		var oldestKey string
+		var oldest *cacheEntry
+		for key, entry := range c.entries {
+			if entry.inUse == 0 && (oldest == nil || entry.lastUsed.Before(oldest.lastUsed)) {
+				oldestKey, oldest = key, entry
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		if err := os.RemoveAll(oldest.dir); err != nil {
+			return
+		}
+		delete(c.entries, oldestKey)
+		total -= oldest.size
+	}
+}
+
+// cacheKey returns the name of the directory the repository with the given URL is stored in.
+// The credentials possibly contained in the URL are not part of the key.
+func cacheKey(endpoint *transport.Endpoint) string {
+	withoutCredentials := *endpoint
+	withoutCredentials.User = ""
+	withoutCredentials.Password = ""
+	hash := sha256.Sum256([]byte(withoutCredentials.String()))
+	return hex.EncodeToString(hash[:])
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// repositoryStorage provides the storage the objects of the repository are fetched into
+type repositoryStorage interface {
+	// open locks the storage and returns it together with a function unlocking it
+	open() (storage.Storer, func())
+}
+
+// memoryStorage keeps the fetched objects in memory for the lifetime of the service
+type memoryStorage struct {
+	mux     sync.Mutex
+	storage storage.Storer
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{storage: memory.NewStorage()}
+}
+
+func (s *memoryStorage) open() (storage.Storer, func()) {
+	s.mux.Lock()
+	return s.storage, s.mux.Unlock
+}
+
+// cachedStorage keeps the fetched objects in the clone cache
+type cachedStorage struct {
+	cache *CloneCache
+	key   string
+}
+
+func (s *cachedStorage) open() (storage.Storer, func()) {
+	entry := s.cache.acquire(s.key)
+	return entry.storage, func() {
+		s.cache.release(entry)
+	}
+}
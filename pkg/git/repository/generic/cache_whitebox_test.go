@@ -0,0 +1,50 @@
+package generic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestCloneCacheComputesSizeOnlyAfterObjectsAreWritten(t *testing.T) {
+	// given
+	cacheDir, err := ioutil.TempDir("", "clone-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	cache, err := NewCloneCache(cacheDir, 100*1024*1024)
+	require.NoError(t, err)
+
+	entry := cache.acquire("some-repo")
+	require.NoError(t, os.MkdirAll(entry.dir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(entry.dir, "some-file"), make([]byte, 100), 0600))
+
+	// when
+	cache.release(entry)
+
+	// then
+	assert.Zero(t, entry.size)
+
+	// and given
+	entry = cache.acquire("some-repo")
+	obj := entry.storage.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	writer, err := obj.Writer()
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("some content"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	_, err = entry.storage.SetEncodedObject(obj)
+	require.NoError(t, err)
+
+	// when
+	cache.release(entry)
+
+	// then
+	assert.True(t, entry.size > 100)
+	assert.False(t, entry.storage.written)
+}
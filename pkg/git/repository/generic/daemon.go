@@ -0,0 +1,47 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const defaultGitDaemonPort = 9418
+
+// gitDaemonTransport runs git-upload-pack over git protocol connections opened using the transport settings
+type gitDaemonTransport struct {
+	ctx      context.Context
+	settings *git.TransportSettings
+}
+
+func (t *gitDaemonTransport) NewUploadPackSession(endpoint *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	port := endpoint.Port
+	if port <= 0 {
+		port = defaultGitDaemonPort
+	}
+	addr := net.JoinHostPort(endpoint.Host, strconv.Itoa(port))
+	conn, err := t.settings.DialContext(t.ctx, "git", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	host := endpoint.Host
+	if port != defaultGitDaemonPort {
+		host = addr
+	}
+	request := fmt.Sprintf("%s %s\x00host=%s\x00", transport.UploadPackServiceName, endpoint.Path, host)
+	if err := pktline.NewEncoder(conn).EncodeString(request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return newCommandSession(t.ctx, conn, conn, nil, nil, conn), nil
+}
+
+func (t *gitDaemonTransport) NewReceivePackSession(*transport.Endpoint, transport.AuthMethod) (transport.ReceivePackSession, error) {
+	return nil, errReceivePackNotSupported
+}
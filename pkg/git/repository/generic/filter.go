@@ -0,0 +1,114 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/capability"
+)
+
+// blobFilter is the object filter excluding all blobs from the pack sent by the server (partial clone)
+const blobFilter = "blob:none"
+
+// filterCapability is advertised by the servers that accept an object filter in the upload-pack request.
+// go-git doesn't support it so the filter line is added to the encoded request.
+const filterCapability = capability.Capability("filter")
+
+type objectFilterKey struct{}
+
+// withObjectFilter returns a context making the upload-pack requests sent within it filter the objects
+// using the given filter spec
+func withObjectFilter(ctx context.Context, filter string) context.Context {
+	return context.WithValue(ctx, objectFilterKey{}, filter)
+}
+
+// objectFilter returns the filter spec the upload-pack requests sent within the context should contain
+func objectFilter(ctx context.Context) string {
+	filter, _ := ctx.Value(objectFilterKey{}).(string)
+	return filter
+}
+
+// supportsBlobFiltering returns true if the server can send a pack without blobs and the missing blobs
+// later on, when they are requested by their hashes
+func supportsBlobFiltering(capabilities *capability.List) bool {
+	return capabilities.Supports(filterCapability) && capabilities.Supports(capability.AllowReachableSHA1InWant)
+}
+
+// encodeUploadRequest writes the wants, shallows and depth of the request followed by the object filter
+// of the context (if any)
+func encodeUploadRequest(ctx context.Context, w io.Writer, req *packp.UploadRequest) error {
+	filter := objectFilter(ctx)
+	if filter == "" {
+		return req.Encode(w)
+	}
+	var encoded bytes.Buffer
+	if err := req.Encode(&encoded); err != nil {
+		return err
+	}
+	filtered, err := insertFilter(encoded.Bytes(), filter)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(filtered)
+	return err
+}
+
+// insertFilter adds the filter line before the flush-pkt ending the wants, shallows and depth of the encoded
+// upload-pack request
+func insertFilter(request []byte, filter string) ([]byte, error) {
+	position := 0
+	for {
+		if len(request) < position+4 {
+			return nil, fmt.Errorf("the upload-pack request doesn't contain any flush-pkt")
+		}
+		length, err := strconv.ParseUint(string(request[position:position+4]), 16, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pkt-line length in the upload-pack request: %s", err)
+		}
+		if length == 0 {
+			break
+		}
+		if length < 4 {
+			return nil, fmt.Errorf("invalid pkt-line length %d in the upload-pack request", length)
+		}
+		position += int(length)
+	}
+	var filtered bytes.Buffer
+	filtered.Write(request[:position])
+	if err := pktline.NewEncoder(&filtered).Encodef("filter %s\n", filter); err != nil {
+		return nil, err
+	}
+	filtered.Write(request[position:])
+	return filtered.Bytes(), nil
+}
+
+// addObjectFilter adds the object filter to the body of the upload-pack request sent by the go-git http transport.
+// Other requests are not changed.
+func addObjectFilter(req *http.Request, filter string) error {
+	if filter == "" || req.Method != http.MethodPost || req.Body == nil ||
+		!strings.HasSuffix(req.URL.Path, "/git-upload-pack") {
+		return nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	if err := req.Body.Close(); err != nil {
+		return err
+	}
+	filtered, err := insertFilter(body, filter)
+	if err != nil {
+		return err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(filtered))
+	req.ContentLength = int64(len(filtered))
+	return nil
+}
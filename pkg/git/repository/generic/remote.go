@@ -0,0 +1,307 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/packfile"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/capability"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/sideband"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-git.v4/storage"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// remote communicates with a git server using the transport settings of the GitSource instead of the globally
+// registered go-git transports, so the custom certificates as well as the proxies are respected
+type remote struct {
+	endpoint   *transport.Endpoint
+	authMethod transport.AuthMethod
+	settings   *git.TransportSettings
+}
+
+// newRemote returns a remote for the given URL. The credentials contained in http(s) URLs are ignored, only the
+// ones of the secret are used. If an ssh key is used for a repository with an http(s) URL, then the repository
+// is accessed over ssh as the key (e.g. a deploy key) cannot authenticate http requests. Similarly, the repositories
+// with ssh URLs are accessed over https if the credentials can authenticate only http requests (e.g. GitHub Apps).
+func newRemote(url string, authMethod transport.AuthMethod, settings *git.TransportSettings) (*remote, error) {
+	gitURL, err := giturl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := gitURL.Endpoint()
+	if keys, ok := authMethod.(*gitssh.PublicKeys); ok && (endpoint.Protocol == "http" || endpoint.Protocol == "https") {
+		endpoint, err = sshEndpoint(gitURL, keys.User)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := authMethod.(*githttp.BasicAuth); ok && endpoint.Protocol == "ssh" {
+		endpoint, err = transport.NewEndpoint(gitURL.HTTPCloneURL())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &remote{
+		endpoint:   endpoint,
+		authMethod: authMethod,
+		settings:   settings,
+	}, nil
+}
+
+// transport returns a go-git transport that makes all connections bound to the given context
+func (r *remote) transport(ctx context.Context) (transport.Transport, error) {
+	switch r.endpoint.Protocol {
+	case "http", "https":
+		return githttp.NewClient(&http.Client{
+			Transport: &contextRoundTripper{ctx: ctx, base: r.settings.RoundTripper()},
+		}), nil
+	case "ssh":
+		return &sshTransport{ctx: ctx, settings: r.settings}, nil
+	case "git":
+		return &gitDaemonTransport{ctx: ctx, settings: r.settings}, nil
+	default:
+		return client.NewClient(r.endpoint)
+	}
+}
+
+func (r *remote) newSession(ctx context.Context) (transport.UploadPackSession, error) {
+	gitTransport, err := r.transport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := gitTransport.NewUploadPackSession(r.endpoint, r.authMethod)
+	if err != nil {
+		return nil, contextErrOr(ctx, err)
+	}
+	return session, nil
+}
+
+// listReferences returns all references advertised by the server
+func (r *remote) listReferences(ctx context.Context) (memory.ReferenceStorage, error) {
+	session, err := r.newSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	advRefs, err := session.AdvertisedReferences()
+	if err != nil {
+		return nil, contextErrOr(ctx, err)
+	}
+	return advRefs.AllReferences()
+}
+
+// openSession opens a new upload-pack session and reads the references advertised by the server
+func (r *remote) openSession(ctx context.Context) (transport.UploadPackSession, *packp.AdvRefs, error) {
+	session, err := r.newSession(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	advRefs, err := session.AdvertisedReferences()
+	if err != nil {
+		session.Close()
+		return nil, nil, contextErrOr(ctx, err)
+	}
+	return session, advRefs, nil
+}
+
+// fetchBranch fetches the last commit of the given branch into the storer and returns hash of the commit.
+// The server is always asked for the references, so the access to the repository is verified even if the commit
+// is already stored.
+//
+// If withoutBlobs is true, then the branches fetched before are sent as the commits the storer has, so only
+// the missing objects are fetched. If the server supports filtering, the blobs are left out as well - they are
+// fetched by fetchObjects when they are needed. Otherwise, the commit is fetched with all its objects.
+func (r *remote) fetchBranch(ctx context.Context, storer storage.Storer, branch string, withoutBlobs bool) (plumbing.Hash, error) {
+	session, advRefs, err := r.openSession(ctx)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer session.Close()
+
+	refs, err := advRefs.AllReferences()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	branchRef := plumbing.NewBranchReferenceName(branch)
+	ref, ok := refs[branchRef]
+	if !ok {
+		return plumbing.ZeroHash, fmt.Errorf("couldn't find remote ref %s", branchRef)
+	}
+
+	request, err := newUploadPackRequest(advRefs)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	request.Wants = []plumbing.Hash{ref.Hash()}
+	if withoutBlobs {
+		if _, err := storer.EncodedObject(plumbing.CommitObject, ref.Hash()); err == nil {
+			return ref.Hash(), nil
+		}
+		if request.Haves, err = storedBranchHeads(storer); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if supportsBlobFiltering(advRefs.Capabilities) {
+			if err := request.Capabilities.Set(filterCapability); err != nil {
+				return plumbing.ZeroHash, err
+			}
+			ctx = withObjectFilter(ctx, blobFilter)
+		}
+	}
+	if advRefs.Capabilities.Supports(capability.Shallow) {
+		request.Depth = packp.DepthCommits(1)
+		if len(request.Haves) > 0 {
+			// the server has to know that the stored commits don't have their parents
+			if request.Shallows, err = storer.Shallow(); err != nil {
+				return plumbing.ZeroHash, err
+			}
+		}
+		if err := request.Capabilities.Set(capability.Shallow); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	if err := uploadPack(ctx, session, storer, request); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := storer.SetReference(plumbing.NewHashReference(branchRef, ref.Hash())); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+// fetchObjects fetches the objects with the given hashes, that were left out by a filtered fetch, into the storer
+func (r *remote) fetchObjects(ctx context.Context, storer storage.Storer, hashes []plumbing.Hash) error {
+	session, advRefs, err := r.openSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	request, err := newUploadPackRequest(advRefs)
+	if err != nil {
+		return err
+	}
+	request.Wants = hashes
+	return uploadPack(ctx, session, storer, request)
+}
+
+func newUploadPackRequest(advRefs *packp.AdvRefs) (*packp.UploadPackRequest, error) {
+	request := packp.NewUploadPackRequestFromCapabilities(advRefs.Capabilities)
+	if advRefs.Capabilities.Supports(capability.NoProgress) {
+		if err := request.Capabilities.Set(capability.NoProgress); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// uploadPack sends the request and stores the objects of the received pack into the storer
+func uploadPack(ctx context.Context, session transport.UploadPackSession, storer storage.Storer,
+	request *packp.UploadPackRequest) (err error) {
+
+	response, err := session.UploadPack(ctx, request)
+	if err != nil {
+		return contextErrOr(ctx, err)
+	}
+	defer func() {
+		if closeErr := response.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if len(response.Shallows) > 0 {
+		shallows, err := storer.Shallow()
+		if err != nil {
+			return err
+		}
+		if err := storer.SetShallow(appendMissing(shallows, response.Shallows)); err != nil {
+			return err
+		}
+	}
+	if err := packfile.UpdateObjectStorage(storer, sidebandIfSupported(request.Capabilities, response)); err != nil {
+		return contextErrOr(ctx, err)
+	}
+	return nil
+}
+
+// storedBranchHeads returns the last commits of the branches fetched into the storer before
+func storedBranchHeads(storer storage.Storer) ([]plumbing.Hash, error) {
+	iter, err := storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	var heads []plumbing.Hash
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference && ref.Name().IsBranch() {
+			heads = appendMissing(heads, []plumbing.Hash{ref.Hash()})
+		}
+		return nil
+	})
+	return heads, err
+}
+
+func appendMissing(hashes []plumbing.Hash, toAppend []plumbing.Hash) []plumbing.Hash {
+	for _, hash := range toAppend {
+		missing := true
+		for _, existing := range hashes {
+			if existing == hash {
+				missing = false
+				break
+			}
+		}
+		if missing {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+func sidebandIfSupported(capabilities *capability.List, reader io.Reader) io.Reader {
+	switch {
+	case capabilities.Supports(capability.Sideband64k):
+		return sideband.NewDemuxer(sideband.Sideband64k, reader)
+	case capabilities.Supports(capability.Sideband):
+		return sideband.NewDemuxer(sideband.Sideband, reader)
+	default:
+		return reader
+	}
+}
+
+// contextErrOr returns the error of the context if it is done (the actual error is just a consequence of that),
+// the given error otherwise
+func contextErrOr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// contextRoundTripper binds all requests to the context. The object filter of the context the request was created
+// within is added to the upload-pack requests.
+type contextRoundTripper struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	boundReq := req.WithContext(t.ctx)
+	if err := addObjectFilter(boundReq, objectFilter(req.Context())); err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(boundReq)
+}
@@ -1,127 +1,147 @@
 package generic
 
 import (
+	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"gopkg.in/src-d/go-git.v4/storage"
+	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/src-d/enry.v1"
-	"gopkg.in/src-d/go-billy.v4/memfs"
-	gogit "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
-	"gopkg.in/src-d/go-git.v4/storage/memory"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
 )
 
 type RepositoryService struct {
-	branch               string
-	repository           *gogit.Repository
-	authMethod           transport.AuthMethod
-	treeLoader           *treeLoader
-	remoteBranchesLoader *remoteBranchesLoader
+	branch     string
+	storage    repositoryStorage
+	remote     *remote
+	treeLoader *treeLoader
+	refsLoader *refsLoader
 }
 
+// treeLoader remembers the last commit of the branch so the branch is fetched only once per service.
+// The branch is fetched without blobs if the server supports it - only the blobs that are read are fetched then.
 type treeLoader struct {
-	mux  sync.Mutex
-	tree *object.Tree
+	hash plumbing.Hash
 }
 
+// NewRepositoryService returns a service fetching the repository either into the clone cache set by UseCloneCache
+// or into memory if no cache is set
 func NewRepositoryService(gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (repository.GitService, error) {
-	store := memory.NewStorage()
-	return newRepositoryService(gitSource, secretProvider.GetSecret(nil), store)
+	return newRepositoryService(gitSource, secretProvider.GetSecret(nil), secretProvider.TransportSettings(), cloneCache)
 }
 
-func newRepositoryService(gitSource *v1alpha1.GitSource, secret git.Secret, storage storage.Storer) (*RepositoryService, error) {
+func newRepositoryService(gitSource *v1alpha1.GitSource, secret git.Secret, settings *git.TransportSettings,
+	cache *CloneCache) (*RepositoryService, error) {
+
 	branch := repository.Master
 	if gitSource.Spec.Ref != "" {
 		branch = gitSource.Spec.Ref
 	}
-	refSpec := fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/origin/%[1]s", branch)
-	repo, err := gogit.Init(storage, memfs.New())
-	if err != nil {
-		return nil, err
-	}
-	_, err = repo.CreateRemote(&config.RemoteConfig{
-		Name:  gogit.DefaultRemoteName,
-		URLs:  []string{gitSource.Spec.URL},
-		Fetch: []config.RefSpec{config.RefSpec(refSpec)},
-	})
-	if err != nil {
-		return nil, err
-	}
 
 	var authMethod transport.AuthMethod
+	var err error
 	if secret != nil {
 		authMethod, err = secret.GitAuthMethod()
 		if err != nil {
 			return nil, err
 		}
 	}
+	remote, err := newRemote(gitSource.Spec.URL, authMethod, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var storage repositoryStorage = newMemoryStorage()
+	if cache != nil {
+		storage = &cachedStorage{cache: cache, key: cacheKey(remote.endpoint)}
+	}
 
 	service := &RepositoryService{
-		branch:               branch,
-		repository:           repo,
-		authMethod:           authMethod,
-		treeLoader:           &treeLoader{},
-		remoteBranchesLoader: &remoteBranchesLoader{},
+		branch:     branch,
+		storage:    storage,
+		remote:     remote,
+		treeLoader: &treeLoader{},
+		refsLoader: &refsLoader{},
 	}
 
 	return service, nil
 }
 
-func (l *treeLoader) fetchTree(repository *gogit.Repository, authMethod transport.AuthMethod) (*object.Tree, error) {
-	l.mux.Lock()
-	defer l.mux.Unlock()
-
-	if l.tree != nil {
-		return l.tree, nil
+// loadTree returns the tree of the last commit of the branch. The branch is fetched again only if the commit
+// is not in the storage anymore (it was evicted from the clone cache). The storage has to be open.
+func (l *treeLoader) loadTree(ctx context.Context, remote *remote, storage storage.Storer, branch string) (*object.Tree, error) {
+	commit, err := object.GetCommit(storage, l.hash)
+	if l.hash.IsZero() || err == plumbing.ErrObjectNotFound {
+		l.hash, err = remote.fetchBranch(ctx, storage, branch, true)
+		if err != nil {
+			return nil, err
+		}
+		commit, err = object.GetCommit(storage, l.hash)
 	}
-	err := repository.Fetch(&gogit.FetchOptions{
-		Auth:       authMethod,
-		Depth:      1,
-		Tags:       gogit.NoTags,
-		RemoteName: gogit.DefaultRemoteName,
-	})
 	if err != nil {
 		return nil, err
 	}
-	commitIter, err := repository.CommitObjects()
-	if err != nil {
-		return nil, err
+	return commit.Tree()
+}
+
+// loadBlobs makes sure that the blobs with the given hashes are in the storage. If the server refuses to send
+// the blobs that were left out when the branch was fetched, the branch is fetched again with all its objects.
+func (l *treeLoader) loadBlobs(ctx context.Context, remote *remote, storage storage.Storer, branch string,
+	hashes []plumbing.Hash) error {
+
+	var missing []plumbing.Hash
+	for _, hash := range hashes {
+		if _, err := storage.EncodedObject(plumbing.BlobObject, hash); err == plumbing.ErrObjectNotFound {
+			missing = append(missing, hash)
+		}
 	}
-	commitToList, err := commitIter.Next()
-	if err != nil {
-		return nil, err
+	if len(missing) == 0 {
+		return nil
 	}
-
-	commit, err := repository.CommitObject(commitToList.Hash)
-	if err != nil {
-		return nil, err
+	err := remote.fetchObjects(ctx, storage, missing)
+	if err == nil || ctx.Err() != nil {
+		return err
 	}
+	_, err = remote.fetchBranch(ctx, storage, branch, false)
+	return err
+}
+
+// withTree opens the storage and calls the function with the tree of the last commit of the branch.
+// The tree must not be used after the function returns as the storage is closed.
+func (s *RepositoryService) withTree(ctx context.Context, fn func(tree *object.Tree, storage storage.Storer) error) error {
+	storage, closeStorage := s.storage.open()
+	defer closeStorage()
 
-	tree, err := commit.Tree()
+	tree, err := s.treeLoader.loadTree(ctx, s.remote, storage, s.branch)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	l.tree = tree
-	return tree, nil
+	return fn(tree, storage)
 }
 
-func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChecker, error) {
-	tree, err := s.treeLoader.fetchTree(s.repository, s.authMethod)
-	if err != nil {
-		return nil, err
-	}
+// FileExistenceChecker lists the files in the root directory of the branch. The blobs are not needed for that.
+func (s *RepositoryService) FileExistenceChecker(ctx context.Context) (repository.FileExistenceChecker, error) {
 	var filenames []string
-	err = tree.Files().ForEach(func(f *object.File) error {
-		if !strings.Contains(f.Name, "/") {
-			filenames = append(filenames, f.Name)
+	err := s.withTree(ctx, func(tree *object.Tree, storage storage.Storer) error {
+		for _, entry := range tree.Entries {
+			if isFile(entry) {
+				filenames = append(filenames, entry.Name)
+			}
 		}
 		return nil
 	})
@@ -131,31 +151,53 @@ func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChec
 	return repository.NewCheckerWithFetchedFiles(filenames), nil
 }
 
-func (s *RepositoryService) GetLanguageList() ([]string, error) {
-	tree, err := s.treeLoader.fetchTree(s.repository, s.authMethod)
-	if err != nil {
-		return nil, err
-	}
-
+// GetLanguageList detects the languages by the names of the files. Only the blobs of the files whose language
+// cannot be detected by the name are read.
+func (s *RepositoryService) GetLanguageList(ctx context.Context) ([]string, error) {
 	languagesCounts := map[string]int{}
-	err = tree.Files().ForEach(func(f *object.File) error {
-		language, safe := enry.GetLanguageByExtension(f.Name)
-		if safe {
-			languagesCounts[language]++
-		} else {
-			language, safe := enry.GetLanguageByFilename(f.Name)
+	err := s.withTree(ctx, func(tree *object.Tree, storage storage.Storer) error {
+		var ambiguous []object.TreeEntry
+		walker := object.NewTreeWalker(tree, true, nil)
+		defer walker.Close()
+		for {
+			name, entry, err := walker.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if !isFile(entry) {
+				continue
+			}
+			language, safe := enry.GetLanguageByExtension(name)
 			if safe {
 				languagesCounts[language]++
+			} else if language, safe := enry.GetLanguageByFilename(name); safe {
+				languagesCounts[language]++
 			} else {
-				content, err := f.Contents()
-				if err != nil {
-					logrus.Warn(err)
-				} else {
-					language, safe = enry.GetLanguageByContent(f.Name, []byte(content))
-				}
-				if safe {
-					languagesCounts[language]++
-				}
+				ambiguous = append(ambiguous, object.TreeEntry{Name: name, Mode: entry.Mode, Hash: entry.Hash})
+			}
+		}
+		if len(ambiguous) == 0 {
+			return nil
+		}
+
+		var hashes []plumbing.Hash
+		for _, entry := range ambiguous {
+			hashes = append(hashes, entry.Hash)
+		}
+		if err := s.treeLoader.loadBlobs(ctx, s.remote, storage, s.branch, hashes); err != nil {
+			return err
+		}
+		for _, entry := range ambiguous {
+			content, err := readBlob(storage, entry.Hash)
+			if err != nil {
+				logrus.Warn(err)
+				continue
+			}
+			if language, safe := enry.GetLanguageByContent(entry.Name, content); safe {
+				languagesCounts[language]++
 			}
 		}
 		return nil
@@ -166,22 +208,40 @@ func (s *RepositoryService) GetLanguageList() ([]string, error) {
 	return git.SortLanguagesWithInts(languagesCounts), nil
 }
 
-func (s *RepositoryService) CheckCredentials() error {
-	_, err := s.remoteBranchesLoader.load(s.repository, s.authMethod)
+// isFile returns true if the entry is a regular file, an executable or a symlink
+func isFile(entry object.TreeEntry) bool {
+	return entry.Mode != filemode.Dir && entry.Mode != filemode.Submodule
+}
+
+func readBlob(storage storage.Storer, hash plumbing.Hash) ([]byte, error) {
+	blob, err := object.GetBlob(storage, hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func (s *RepositoryService) CheckCredentials(ctx context.Context) error {
+	_, err := s.refsLoader.load(ctx, s.remote)
 	return err
 }
 
-func (s *RepositoryService) CheckRepoAccessibility() error {
-	_, err := s.remoteBranchesLoader.load(s.repository, s.authMethod)
+func (s *RepositoryService) CheckRepoAccessibility(ctx context.Context) error {
+	_, err := s.refsLoader.load(ctx, s.remote)
 	return err
 }
 
-func (s *RepositoryService) CheckBranch() error {
-	branches, err := s.remoteBranchesLoader.load(s.repository, s.authMethod)
+func (s *RepositoryService) CheckBranch(ctx context.Context) error {
+	refs, err := s.refsLoader.load(ctx, s.remote)
 	if err != nil {
 		return err
 	}
-	for _, branch := range branches {
+	for _, branch := range refs.branches {
 		if branch == s.branch {
 			return nil
 		}
@@ -189,26 +249,94 @@ func (s *RepositoryService) CheckBranch() error {
 	return fmt.Errorf("branch not found")
 }
 
-type remoteBranchesLoader struct {
-	remoteBranches []string
+// CheckPermissions returns an empty list as the git protocol doesn't tell which permissions the credentials grant
+func (s *RepositoryService) CheckPermissions(ctx context.Context) ([]repository.MissingPermission, error) {
+	return nil, nil
+}
+
+// ListBranches returns the branches advertised by the git server sorted by name
+func (s *RepositoryService) ListBranches(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	refs, err := s.refsLoader.load(ctx, s.remote)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewRefList(refs.branches, options), nil
 }
 
-func (l *remoteBranchesLoader) load(repository *gogit.Repository, authMethod transport.AuthMethod) ([]string, error) {
-	if l.remoteBranches != nil {
-		return l.remoteBranches, nil
+// ListTags returns the tags advertised by the git server sorted by name
+func (s *RepositoryService) ListTags(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	refs, err := s.refsLoader.load(ctx, s.remote)
+	if err != nil {
+		return nil, err
 	}
-	remote, err := repository.Remote(gogit.DefaultRemoteName)
+	return repository.NewRefList(refs.tags, options), nil
+}
+
+// GetFileContent reads the file from the tree of the last commit of the branch. Only the blob of the file is fetched
+// if the branch was fetched without blobs.
+func (s *RepositoryService) GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error) {
+	var content []byte
+	err := s.withTree(ctx, func(tree *object.Tree, storage storage.Storer) error {
+		entry, err := tree.FindEntry(strings.Trim(path, "/"))
+		if err != nil || !isFile(*entry) {
+			return &repository.FileNotFoundError{Path: path}
+		}
+		if err := s.treeLoader.loadBlobs(ctx, s.remote, storage, s.branch, []plumbing.Hash{entry.Hash}); err != nil {
+			return err
+		}
+		blob, err := object.GetBlob(storage, entry.Hash)
+		if err != nil {
+			return err
+		}
+		limit := repository.MaxFileSize(maxSize)
+		if blob.Size > limit {
+			return &repository.FileTooLargeError{Path: path, Limit: limit}
+		}
+		reader, err := blob.Reader()
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		content, err = repository.ReadLimited(path, reader, limit)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	references, err := remote.List(&gogit.ListOptions{Auth: authMethod})
+	return content, nil
+}
+
+// remoteRefs holds names of the branches and tags advertised by the git server
+type remoteRefs struct {
+	branches []string
+	tags     []string
+}
+
+type refsLoader struct {
+	mux  sync.Mutex
+	refs *remoteRefs
+}
+
+func (l *refsLoader) load(ctx context.Context, remote *remote) (*remoteRefs, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	if l.refs != nil {
+		return l.refs, nil
+	}
+	references, err := remote.listReferences(ctx)
 	if err != nil {
 		return nil, err
 	}
-	var branches []string
-	for _, ref := range references {
-		branches = append(branches, ref.Name().Short())
+	refs := &remoteRefs{}
+	for name := range references {
+		switch {
+		case name.IsBranch():
+			refs.branches = append(refs.branches, strings.TrimPrefix(name.String(), branchRefPrefix))
+		case name.IsTag():
+			refs.tags = append(refs.tags, strings.TrimPrefix(name.String(), tagRefPrefix))
+		}
 	}
-	l.remoteBranches = branches
-	return branches, nil
+	l.refs = refs
+	return refs, nil
 }
@@ -1,13 +1,22 @@
 package generic_test
 
 import (
+	"context"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/generic"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	gitcache "gopkg.in/src-d/go-git.v4/plumbing/cache"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 const pathToTestDir = "../../../test"
@@ -28,27 +37,27 @@ func TestNewRepositoryServiceForAllSecretsAndMethods(t *testing.T) {
 
 		// then
 		require.NoError(t, err)
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		rootFiles := checker.GetListOfFoundFiles()
 		require.Len(t, rootFiles, 2)
 		assert.Contains(t, rootFiles, "pom.xml")
 		assert.Contains(t, rootFiles, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		assert.Len(t, languageList, 3)
 		assert.Contains(t, languageList, "XML")
 		assert.Contains(t, languageList, "Java")
 		assert.Contains(t, languageList, "Go")
 
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 		require.NoError(t, err)
 
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 		require.NoError(t, err)
 
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 		require.NoError(t, err)
 	}
 }
@@ -67,7 +76,7 @@ func TestNewRepositoryServiceShouldReturnFilesAddedByMultipleCommits(t *testing.
 
 	// then
 	require.NoError(t, err)
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 	require.NoError(t, err)
 	rootFiles := checker.GetListOfFoundFiles()
 	require.Len(t, rootFiles, 3)
@@ -88,12 +97,12 @@ func TestNewRepositoryServiceWithEmptyMasterBranch(t *testing.T) {
 
 	// then
 	require.NoError(t, err)
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 	require.NoError(t, err)
 	rootFiles := checker.GetListOfFoundFiles()
 	require.Len(t, rootFiles, 0)
 
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, languageList, 0)
 }
@@ -110,14 +119,14 @@ func TestNewRepositoryServiceWithOtherThanMasterBranch(t *testing.T) {
 
 	// then
 	require.NoError(t, err)
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 	require.NoError(t, err)
 	rootFiles := checker.GetListOfFoundFiles()
 	require.Len(t, rootFiles, 2)
 	assert.Contains(t, rootFiles, "main.go")
 	assert.Contains(t, rootFiles, "any-file")
 
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, languageList, 1)
 	assert.Contains(t, languageList, "Go")
@@ -134,7 +143,7 @@ func TestNewRepositoryServiceShouldLoadFilesOnlyOnce(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 
 	// then
 	require.NoError(t, err)
@@ -144,14 +153,14 @@ func TestNewRepositoryServiceShouldLoadFilesOnlyOnce(t *testing.T) {
 
 	// and when
 	dummyRepo.Commit("pom.xml")
-	checker, err = service.FileExistenceChecker()
+	checker, err = service.FileExistenceChecker(context.Background())
 
 	// then it should use cache
 	require.NoError(t, err)
 	rootFiles = checker.GetListOfFoundFiles()
 	require.Len(t, rootFiles, 1)
 	assert.Contains(t, rootFiles, "main.go")
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, languageList, 1)
 	assert.Contains(t, languageList, "Go")
@@ -168,7 +177,7 @@ func TestNewRepositoryServiceShouldLoadRemoteBranchesOnlyOnce(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	require.NoError(t, err)
@@ -178,10 +187,108 @@ func TestNewRepositoryServiceShouldLoadRemoteBranchesOnlyOnce(t *testing.T) {
 	dummyRepo.Commit("second-pom.xml")
 
 	// then it should use cache thus fail because originally the dev branch was missing
-	err = service.CheckBranch()
+	err = service.CheckBranch(context.Background())
 	require.Error(t, err)
 }
 
+func TestNewRepositoryServiceListsBranchesAndTags(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	dummyRepo.Tag("v1.0.0")
+	dummyRepo.CheckoutBranch("feature-b")
+	dummyRepo.Commit("pom.xml")
+	dummyRepo.CheckoutBranch("feature-a")
+	dummyRepo.Commit("mvnw")
+	dummyRepo.Tag("v1.1.0")
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
+
+	service, err := generic.NewRepositoryService(source,
+		git.NewSecretProvider(git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))))
+	require.NoError(t, err)
+
+	// when
+	branches, err := service.ListBranches(context.Background(), repository.RefListOptions{})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature-a", "feature-b", "master"}, branches.Names)
+	assert.Equal(t, 0, branches.NextPage)
+
+	// and when
+	filtered, err := service.ListBranches(context.Background(), repository.RefListOptions{Prefix: "feature-", PerPage: 1})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature-a"}, filtered.Names)
+	assert.Equal(t, 2, filtered.NextPage)
+
+	// and when
+	tags, err := service.ListTags(context.Background(), repository.RefListOptions{})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, tags.Names)
+}
+
+func TestNewRepositoryServiceCheckBranchIgnoresTags(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	dummyRepo.Tag("release")
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path), test.WithRef("release"))
+
+	service, err := generic.NewRepositoryService(source,
+		git.NewSecretProvider(git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckBranch(context.Background())
+
+	// then
+	require.Error(t, err)
+}
+
+func TestNewRepositoryServiceGetsFileContent(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.CommitWithContent(map[string]string{
+		"README.md":           "# Some repo",
+		"config/devfile.yaml": "specVersion: 0.0.1",
+	})
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
+
+	service, err := generic.NewRepositoryService(source,
+		git.NewSecretProvider(git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))))
+	require.NoError(t, err)
+
+	// when
+	readme, err := service.GetFileContent(context.Background(), "README.md", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "# Some repo", string(readme))
+
+	// and when
+	devfile, err := service.GetFileContent(context.Background(), "config/devfile.yaml", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "specVersion: 0.0.1", string(devfile))
+
+	// and when
+	_, err = service.GetFileContent(context.Background(), "Dockerfile", 0)
+
+	// then
+	require.IsType(t, &repository.FileNotFoundError{}, err)
+
+	// and when
+	_, err = service.GetFileContent(context.Background(), "README.md", 5)
+
+	// then
+	require.IsType(t, &repository.FileTooLargeError{}, err)
+}
+
 func TestNewRepositoryServiceUsingSSh(t *testing.T) {
 	// given
 	allowedPubKey := test.PublicWithoutPassphrase(t, pathToTestDir)
@@ -191,13 +298,14 @@ func TestNewRepositoryServiceUsingSSh(t *testing.T) {
 	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
 	dummyRepo.Commit("main.go")
 	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+	trustTestServer(sshKey)
 
-	service, err := generic.NewRepositoryService(source,
-		git.NewSecretProvider(git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))))
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(sshKey))
 	require.NoError(t, err)
 
 	// when
-	checker, err := service.FileExistenceChecker()
+	checker, err := service.FileExistenceChecker(context.Background())
 
 	// then
 	require.NoError(t, err)
@@ -206,18 +314,18 @@ func TestNewRepositoryServiceUsingSSh(t *testing.T) {
 	require.Len(t, rootFiles, 1)
 	assert.Contains(t, rootFiles, "main.go")
 
-	languageList, err := service.GetLanguageList()
+	languageList, err := service.GetLanguageList(context.Background())
 	require.NoError(t, err)
 	assert.Len(t, languageList, 1)
 	assert.Contains(t, languageList, "Go")
 
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 	require.NoError(t, err)
 
-	err = service.CheckRepoAccessibility()
+	err = service.CheckRepoAccessibility(context.Background())
 	require.NoError(t, err)
 
-	err = service.CheckBranch()
+	err = service.CheckBranch(context.Background())
 	require.NoError(t, err)
 }
 
@@ -231,41 +339,49 @@ func TestNewRepositoryServiceUsingSShWithWrongKey(t *testing.T) {
 	dummyRepo.Commit("main.go")
 	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 
-	service, err := generic.NewRepositoryService(source,
-		git.NewSecretProvider(git.NewSshKey(test.PrivateWithPassphrase(t, pathToTestDir), []byte("secret"))))
+	sshKey := git.NewSshKey(test.PrivateWithPassphrase(t, pathToTestDir), []byte("secret"))
+	trustTestServer(sshKey)
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(sshKey))
 	require.NoError(t, err)
 
 	// when
-	_, err = service.FileExistenceChecker()
+	_, err = service.FileExistenceChecker(context.Background())
 
 	// then
 	assertHandshakeFailed(t, err)
 
 	// and when
-	_, err = service.GetLanguageList()
+	_, err = service.GetLanguageList(context.Background())
 
 	// then
 	assertHandshakeFailed(t, err)
 
 	// and when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	assertHandshakeFailed(t, err)
 
 	// and when
-	err = service.CheckRepoAccessibility()
+	err = service.CheckRepoAccessibility(context.Background())
 
 	// then
 	assertHandshakeFailed(t, err)
 
 	// and when
-	err = service.CheckBranch()
+	err = service.CheckBranch(context.Background())
 
 	// then
 	assertHandshakeFailed(t, err)
 }
 
+func trustTestServer(secrets ...git.Secret) {
+	for _, secret := range secrets {
+		secret.SetTransportSettings(git.NewTransportSettings().WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts())))
+	}
+}
+
 func assertHandshakeFailed(t *testing.T, err error) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "ssh: handshake failed: ssh: unable to authenticate")
@@ -280,6 +396,7 @@ func TestNewRepositoryServiceUsingBasicSShAuth(t *testing.T) {
 	dummyRepo.Commit("main.go")
 	usernamePassword := git.NewUsernamePassword("user", "super-secret")
 	oauthToken := git.NewOauthToken([]byte("super-secret"))
+	trustTestServer(usernamePassword, oauthToken)
 	for _, secret := range []git.Secret{usernamePassword, oauthToken} {
 		source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 
@@ -287,7 +404,7 @@ func TestNewRepositoryServiceUsingBasicSShAuth(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 
 		// then
 		require.NoError(t, err)
@@ -296,18 +413,18 @@ func TestNewRepositoryServiceUsingBasicSShAuth(t *testing.T) {
 		require.Len(t, rootFiles, 1)
 		assert.Contains(t, rootFiles, "main.go")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		assert.Len(t, languageList, 1)
 		assert.Contains(t, languageList, "Go")
 
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 		require.NoError(t, err)
 
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 		require.NoError(t, err)
 
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 		require.NoError(t, err)
 	}
 }
@@ -321,6 +438,7 @@ func TestNewRepositoryServiceUsingBasicSShAuthWithWrongPassword(t *testing.T) {
 	dummyRepo.Commit("main.go")
 	usernamePassword := git.NewUsernamePassword("user", "wrong-secret")
 	oauthToken := git.NewOauthToken([]byte("wrong-secret"))
+	trustTestServer(usernamePassword, oauthToken)
 	for _, secret := range []git.Secret{usernamePassword, oauthToken} {
 		source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
 
@@ -328,33 +446,378 @@ func TestNewRepositoryServiceUsingBasicSShAuthWithWrongPassword(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		_, err = service.FileExistenceChecker()
+		_, err = service.FileExistenceChecker(context.Background())
 
 		// then
 		assertHandshakeFailed(t, err)
 
 		// and when
-		_, err = service.GetLanguageList()
+		_, err = service.GetLanguageList(context.Background())
 
 		// then
 		assertHandshakeFailed(t, err)
 
 		// and when
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 
 		// then
 		assertHandshakeFailed(t, err)
 
 		// and when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assertHandshakeFailed(t, err)
 
 		// and when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assertHandshakeFailed(t, err)
 	}
 }
+
+func TestNewRepositoryServiceUsingSShWithMismatchingHostKey(t *testing.T) {
+	// given
+	allowedPubKey := test.PublicWithoutPassphrase(t, pathToTestDir)
+	reset := test.RunKeySshServer(t, allowedPubKey)
+	defer reset()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+
+	for _, policy := range []*git.HostKeyPolicy{git.NewKnownHostsPolicy(test.MismatchingKnownHosts()), nil} {
+		sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+		sshKey.SetTransportSettings(git.NewTransportSettings().WithHostKeyPolicy(policy))
+
+		service, err := generic.NewRepositoryService(source, git.NewSecretProvider(sshKey))
+		require.NoError(t, err)
+
+		// when
+		err = service.CheckCredentials(context.Background())
+
+		// then
+		require.Error(t, err)
+		assert.True(t, git.IsHostKeyError(err))
+	}
+}
+
+func TestNewRepositoryServiceUsingSShWithInsecureHostKeyPolicy(t *testing.T) {
+	// given
+	allowedPubKey := test.PublicWithoutPassphrase(t, pathToTestDir)
+	reset := test.RunKeySshServer(t, allowedPubKey)
+	defer reset()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+	sshKey.SetTransportSettings(git.NewTransportSettings().WithHostKeyPolicy(git.NewInsecureHostKeyPolicy()))
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(sshKey))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckCredentials(context.Background())
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestNewRepositoryServiceUsingHttpsWithCABundle(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	server, repoURL := test.RunTLSGitServer(t, dummyRepo.Path)
+	defer server.Close()
+	source := test.NewGitSource(test.WithURL(repoURL))
+	settings, err := git.NewTransportSettings().WithCABundle(test.CACert(server))
+	require.NoError(t, err)
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProviderWithSettings(nil, settings))
+	require.NoError(t, err)
+
+	// when
+	checker, err := service.FileExistenceChecker(context.Background())
+
+	// then
+	require.NoError(t, err)
+	rootFiles := checker.GetListOfFoundFiles()
+	require.Len(t, rootFiles, 1)
+	assert.Contains(t, rootFiles, "main.go")
+
+	err = service.CheckCredentials(context.Background())
+	require.NoError(t, err)
+
+	err = service.CheckBranch(context.Background())
+	require.NoError(t, err)
+}
+
+func TestNewRepositoryServiceUsingHttpsWithInsecureSkipTLSVerify(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	server, repoURL := test.RunTLSGitServer(t, dummyRepo.Path)
+	defer server.Close()
+	source := test.NewGitSource(test.WithURL(repoURL))
+	settings := git.NewTransportSettings().WithInsecureSkipTLSVerify()
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProviderWithSettings(nil, settings))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckBranch(context.Background())
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestNewRepositoryServiceUsingHttpsWithUnknownCA(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	server, repoURL := test.RunTLSGitServer(t, dummyRepo.Path)
+	defer server.Close()
+	source := test.NewGitSource(test.WithURL(repoURL))
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(nil))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.FileExistenceChecker(context.Background())
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+
+	// and when
+	err = service.CheckCredentials(context.Background())
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+}
+
+func TestNewRepositoryServiceUsingSShThroughProxy(t *testing.T) {
+	// given
+	allowedPubKey := test.PublicWithoutPassphrase(t, pathToTestDir)
+	reset := test.RunKeySshServer(t, allowedPubKey)
+	defer reset()
+	proxy := test.RunProxy()
+	defer proxy.Close()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+	settings := git.NewTransportSettings().
+		WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts())).
+		WithProxy(&git.ProxyConfig{HTTPSProxy: proxy.URL})
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProviderWithSettings(sshKey, settings))
+	require.NoError(t, err)
+
+	// when
+	checker, err := service.FileExistenceChecker(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, checker.GetListOfFoundFiles())
+
+	err = service.CheckBranch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, proxy.Requests())
+}
+
+func TestNewRepositoryServiceUsingHttpsThroughProxy(t *testing.T) {
+	// given
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	server, repoURL := test.RunTLSGitServer(t, dummyRepo.Path)
+	defer server.Close()
+	proxy := test.RunProxy()
+	defer proxy.Close()
+	source := test.NewGitSource(test.WithURL(repoURL))
+	settings, err := git.NewTransportSettings().WithCABundle(test.CACert(server))
+	require.NoError(t, err)
+	settings.WithProxy(&git.ProxyConfig{HTTPSProxy: proxy.URL})
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProviderWithSettings(nil, settings))
+	require.NoError(t, err)
+
+	// when
+	checker, err := service.FileExistenceChecker(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"main.go"}, checker.GetListOfFoundFiles())
+	assert.NotZero(t, proxy.Requests())
+}
+
+func TestNewRepositoryServiceUsingSShWithNoProxyHost(t *testing.T) {
+	// given
+	allowedPubKey := test.PublicWithoutPassphrase(t, pathToTestDir)
+	reset := test.RunKeySshServer(t, allowedPubKey)
+	defer reset()
+	proxy := test.RunProxy()
+	defer proxy.Close()
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("main.go")
+	source := test.NewGitSource(test.WithURL("ssh://git@localhost:2222" + dummyRepo.Path))
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+	settings := git.NewTransportSettings().
+		WithHostKeyPolicy(git.NewKnownHostsPolicy(test.KnownHosts())).
+		WithProxy(&git.ProxyConfig{HTTPSProxy: proxy.URL, NoProxy: "localhost"})
+
+	service, err := generic.NewRepositoryService(source, git.NewSecretProviderWithSettings(sshKey, settings))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckBranch(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, 0, proxy.Requests())
+}
+
+func TestNewRepositoryServiceWithUnresponsiveServerTimesOut(t *testing.T) {
+	// given
+	listener := test.RunUnresponsiveListener(t)
+	defer listener.Close()
+	sshKey := git.NewSshKey(test.PrivateWithoutPassphrase(t, pathToTestDir), []byte(""))
+	settings := git.NewTransportSettings().WithHostKeyPolicy(git.NewInsecureHostKeyPolicy())
+
+	for _, url := range []string{
+		"ssh://git@" + listener.Addr().String() + "/owner/repo.git",
+		"git://" + listener.Addr().String() + "/owner/repo.git"} {
+
+		service, err := generic.NewRepositoryService(test.NewGitSource(test.WithURL(url)),
+			git.NewSecretProviderWithSettings(sshKey, settings))
+		require.NoError(t, err)
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+
+		// when
+		start := time.Now()
+		_, err = service.FileExistenceChecker(ctx)
+		cancel()
+
+		// then
+		require.Error(t, err, url)
+		assert.True(t, git.IsTimeout(ctx, err), url)
+		assert.True(t, time.Since(start) < 5*time.Second, url)
+	}
+}
+
+func TestNewRepositoryServiceFetchesNewCommitsIntoCloneCache(t *testing.T) {
+	// given
+	cacheDir, err := ioutil.TempDir("", "clone-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	cache, err := generic.NewCloneCache(cacheDir, 100*1024*1024)
+	require.NoError(t, err)
+	generic.UseCloneCache(cache)
+	defer generic.UseCloneCache(nil)
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.Commit("pom.xml")
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(nil))
+	require.NoError(t, err)
+	_, err = service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	dummyRepo.Commit("main.go")
+
+	// when
+	service, err = generic.NewRepositoryService(source, git.NewSecretProvider(nil))
+
+	// then
+	require.NoError(t, err)
+	checker, err := service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	rootFiles := checker.GetListOfFoundFiles()
+	require.Len(t, rootFiles, 2)
+	assert.Contains(t, rootFiles, "pom.xml")
+	assert.Contains(t, rootFiles, "main.go")
+	cachedRepos, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, cachedRepos, 1)
+}
+
+func TestCloneCacheRemovesRepositoriesExceedingLimit(t *testing.T) {
+	// given
+	cacheDir, err := ioutil.TempDir("", "clone-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	cache, err := generic.NewCloneCache(cacheDir, 1)
+	require.NoError(t, err)
+	generic.UseCloneCache(cache)
+	defer generic.UseCloneCache(nil)
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.CommitWithContent(map[string]string{"README.md": "# Some repo"})
+	source := test.NewGitSource(test.WithURL(dummyRepo.Path))
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(nil))
+	require.NoError(t, err)
+
+	// when
+	content, err := service.GetFileContent(context.Background(), "README.md", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "# Some repo", string(content))
+	cachedRepos, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Empty(t, cachedRepos)
+}
+
+func TestNewRepositoryServiceFetchesOnlyBlobsThatAreRead(t *testing.T) {
+	// given
+	cacheDir, err := ioutil.TempDir("", "clone-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	cloneCache, err := generic.NewCloneCache(cacheDir, 100*1024*1024)
+	require.NoError(t, err)
+	generic.UseCloneCache(cloneCache)
+	defer generic.UseCloneCache(nil)
+
+	dummyRepo := test.NewDummyGitRepo(t, repository.Master)
+	dummyRepo.AllowPartialClone()
+	dummyRepo.CommitWithContent(map[string]string{
+		"README.md":           "# Some repo",
+		"pom.xml":             "<project/>",
+		"config/devfile.yaml": "specVersion: 0.0.1",
+	})
+	repoURL, stopDaemon := test.RunGitDaemon(t, dummyRepo.Path)
+	defer stopDaemon()
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := generic.NewRepositoryService(source, git.NewSecretProvider(nil))
+	require.NoError(t, err)
+
+	// when
+	checker, err := service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	devfile, err := service.GetFileContent(context.Background(), "config/devfile.yaml", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "specVersion: 0.0.1", string(devfile))
+	rootFiles := checker.GetListOfFoundFiles()
+	require.Len(t, rootFiles, 2)
+	assert.Contains(t, rootFiles, "README.md")
+	assert.Contains(t, rootFiles, "pom.xml")
+
+	cachedRepos, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Len(t, cachedRepos, 1)
+	cachedRepo := filesystem.NewStorage(osfs.New(filepath.Join(cacheDir, cachedRepos[0].Name())), gitcache.NewObjectLRUDefault())
+	blobs, err := cachedRepo.IterEncodedObjects(plumbing.BlobObject)
+	require.NoError(t, err)
+	var blobCount int
+	require.NoError(t, blobs.ForEach(func(plumbing.EncodedObject) error {
+		blobCount++
+		return nil
+	}))
+	assert.Equal(t, 1, blobCount)
+}
@@ -0,0 +1,180 @@
+package generic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp"
+	"gopkg.in/src-d/go-git.v4/plumbing/protocol/packp/capability"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+var errReceivePackNotSupported = errors.New("pushing to the remote repository is not supported")
+
+var repoNotFoundMessages = []string{
+	"ERROR: Repository not found",
+	"conq: repository does not exist",
+	"does not appear to be a git repository",
+	"repository not found",
+	"no such repository",
+	"access denied",
+}
+
+// commandSession is an upload-pack session communicating with a git-upload-pack command running on the server.
+// It is used by the transports opening the connections on their own (ssh, git) so the connections can go through
+// a proxy.
+type commandSession struct {
+	stdin    io.Writer
+	stdout   io.Reader
+	stderr   *stderrBuffer
+	wait     func() error
+	closer   io.Closer
+	advRefs  *packp.AdvRefs
+	packSent bool
+	close    sync.Once
+	done     chan struct{}
+}
+
+// newCommandSession creates a session over the given streams of the command. The wait function (if not nil)
+// should block until the command exits and its error output is collected. The session is closed when the context
+// is done so all pending reads and writes are interrupted.
+func newCommandSession(ctx context.Context, stdin io.Writer, stdout io.Reader, stderr *stderrBuffer, wait func() error,
+	closer io.Closer) *commandSession {
+
+	if stderr == nil {
+		stderr = &stderrBuffer{}
+	}
+	session := &commandSession{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+		wait:   wait,
+		closer: closer,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Close()
+		case <-session.done:
+		}
+	}()
+	return session
+}
+
+func (s *commandSession) AdvertisedReferences() (*packp.AdvRefs, error) {
+	if s.advRefs != nil {
+		return s.advRefs, nil
+	}
+	advRefs := packp.NewAdvRefs()
+	if err := advRefs.Decode(s.stdout); err != nil {
+		return nil, s.decodeError(err)
+	}
+	// the same as go-git transports do - the decoder of the upload-pack response doesn't support these
+	advRefs.Capabilities.Delete(capability.MultiACK)
+	advRefs.Capabilities.Delete(capability.MultiACKDetailed)
+	s.advRefs = advRefs
+	return advRefs, nil
+}
+
+func (s *commandSession) decodeError(err error) error {
+	if err == packp.ErrEmptyAdvRefs {
+		return transport.ErrEmptyRemoteRepository
+	}
+	if err == packp.ErrEmptyInput && s.wait != nil {
+		// the command has exited without any output - collect the reason from the error output
+		_ = s.wait()
+	}
+	stderr := strings.TrimSpace(s.stderr.String())
+	if isRepoNotFound(stderr) || isRepoNotFound(err.Error()) {
+		return transport.ErrRepositoryNotFound
+	}
+	if stderr != "" {
+		return fmt.Errorf("%s: %s", err, stderr)
+	}
+	return err
+}
+
+func isRepoNotFound(message string) bool {
+	for _, notFound := range repoNotFoundMessages {
+		if strings.Contains(strings.ToLower(message), strings.ToLower(notFound)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *commandSession) UploadPack(ctx context.Context, req *packp.UploadPackRequest) (*packp.UploadPackResponse, error) {
+	if req.IsEmpty() {
+		return nil, transport.ErrEmptyUploadPackRequest
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.AdvertisedReferences(); err != nil {
+		return nil, err
+	}
+
+	s.packSent = true
+	if err := encodeUploadRequest(ctx, s.stdin, &req.UploadRequest); err != nil {
+		return nil, fmt.Errorf("sending upload-req message: %s", err)
+	}
+	if err := req.UploadHaves.Encode(s.stdin, true); err != nil {
+		return nil, fmt.Errorf("sending haves message: %s", err)
+	}
+	if err := pktline.NewEncoder(s.stdin).EncodeString("done\n"); err != nil {
+		return nil, fmt.Errorf("sending done message: %s", err)
+	}
+
+	response := packp.NewUploadPackResponse(req)
+	if err := response.Decode(&sessionReader{Reader: s.stdout, session: s}); err != nil {
+		return nil, s.decodeError(err)
+	}
+	return response, nil
+}
+
+func (s *commandSession) Close() (err error) {
+	s.close.Do(func() {
+		close(s.done)
+		if s.advRefs != nil && !s.packSent {
+			// tell the server that nothing is going to be requested
+			_ = pktline.NewEncoder(s.stdin).Flush()
+		}
+		err = s.closer.Close()
+	})
+	return
+}
+
+// sessionReader closes the whole session when the response of upload-pack is closed
+type sessionReader struct {
+	io.Reader
+	session *commandSession
+}
+
+func (r *sessionReader) Close() error {
+	return r.session.Close()
+}
+
+// stderrBuffer collects the error output of the command that is written concurrently with reading the standard output
+type stderrBuffer struct {
+	mux    sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *stderrBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *stderrBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buffer.String()
+}
@@ -0,0 +1,98 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+const defaultSshPort = 22
+
+// sshEndpoint returns the ssh endpoint of the repository with the given http(s) URL. Git servers serve
+// the repositories on the same host and path over both protocols.
+func sshEndpoint(gitURL *giturl.URL, user string) (*transport.Endpoint, error) {
+	sshURL := *gitURL
+	sshURL.User = user
+	parsed, err := giturl.Parse(sshURL.SSHCloneURL())
+	if err != nil {
+		return nil, err
+	}
+	return parsed.Endpoint(), nil
+}
+
+// sshTransport runs git-upload-pack over ssh connections opened using the transport settings
+type sshTransport struct {
+	ctx      context.Context
+	settings *git.TransportSettings
+}
+
+func (t *sshTransport) NewUploadPackSession(endpoint *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	if auth == nil {
+		return nil, transport.ErrAuthenticationRequired
+	}
+	sshAuth, ok := auth.(gitssh.AuthMethod)
+	if !ok {
+		return nil, transport.ErrInvalidAuthMethod
+	}
+	config, err := sshAuth.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	port := endpoint.Port
+	if port <= 0 {
+		port = defaultSshPort
+	}
+	addr := net.JoinHostPort(endpoint.Host, strconv.Itoa(port))
+	conn, err := t.settings.DialContext(t.ctx, "ssh", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := t.ctx.Deadline(); ok {
+		// limit the time of the ssh handshake
+		conn.SetDeadline(deadline)
+	}
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	client := ssh.NewClient(sshConn, channels, requests)
+
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	stderr := &stderrBuffer{}
+	session.Stderr = stderr
+
+	if err := session.Start(fmt.Sprintf("%s '%s'", transport.UploadPackServiceName, endpoint.Path)); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return newCommandSession(t.ctx, stdin, stdout, stderr, session.Wait, client), nil
+}
+
+func (t *sshTransport) NewReceivePackSession(*transport.Endpoint, transport.AuthMethod) (transport.ReceivePackSession, error) {
+	return nil, errReceivePackNotSupported
+}
@@ -0,0 +1,30 @@
+package gitea
+
+import "time"
+
+type Repository struct {
+	Description   string     `json:"description,omitempty"`
+	DefaultBranch string     `json:"default_branch,omitempty"`
+	Private       bool       `json:"private,omitempty"`
+	Internal      bool       `json:"internal,omitempty"`
+	Archived      bool       `json:"archived,omitempty"`
+	Fork          bool       `json:"fork,omitempty"`
+	Size          int64      `json:"size,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	Topics        []string   `json:"topics,omitempty"`
+	HTMLURL       string     `json:"html_url,omitempty"`
+}
+
+type Hook struct {
+	ID     int64      `json:"id,omitempty"`
+	Type   string     `json:"type,omitempty"`
+	Active bool       `json:"active"`
+	Events []string   `json:"events,omitempty"`
+	Config HookConfig `json:"config"`
+}
+
+type HookConfig struct {
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Secret      string `json:"secret,omitempty"`
+}
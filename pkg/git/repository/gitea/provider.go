@@ -0,0 +1,13 @@
+package gitea
+
+import "github.com/redhat-developer/devconsole-git/pkg/git/repository"
+
+func init() {
+	repository.Register(repository.Provider{
+		Name:                   repository.GiteaFlavor,
+		Priority:               10,
+		Hosts:                  []string{giteaHost},
+		WebhookServiceCreator:  NewWebhookServiceIfMatches(),
+		MetadataServiceCreator: NewMetadataServiceIfMatches(),
+	})
+}
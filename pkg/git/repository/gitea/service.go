@@ -0,0 +1,229 @@
+package gitea
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+const (
+	giteaHost = "gitea.com"
+	// webhookPageSize is the number of webhooks listed per page - the maximal page size of Gitea
+	webhookPageSize = 50
+)
+
+// RepositoryService manages webhooks and reads metadata of a repository hosted on Gitea
+type RepositoryService struct {
+	secret  git.Secret
+	client  *http.Client
+	baseURL string
+	repo    repository.StructuredIdentifier
+	log     *log.GitSourceLogger
+}
+
+// NewWebhookServiceIfMatches returns function creating Gitea webhook service if either host of the git repo URL
+// is gitea.com or flavor of the given git source is gitea, nil otherwise
+func NewWebhookServiceIfMatches() repository.WebhookServiceCreator {
+	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (repository.WebhookService, error) {
+		service, err := newServiceIfMatches(log, gitSource, secretProvider)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		return service, nil
+	}
+}
+
+// NewMetadataServiceIfMatches returns function creating Gitea metadata service if either host of the git repo URL
+// is gitea.com or flavor of the given git source is gitea, nil otherwise
+func NewMetadataServiceIfMatches() repository.MetadataServiceCreator {
+	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (repository.MetadataService, error) {
+		service, err := newServiceIfMatches(log, gitSource, secretProvider)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		return service, nil
+	}
+}
+
+func newServiceIfMatches(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+	secretProvider *git.SecretProvider) (*RepositoryService, error) {
+	if secretProvider.SecretType() == git.SshKeyType || secretProvider.SecretType() == git.GitHubAppType {
+		return nil, nil
+	}
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	if err != nil {
+		return nil, err
+	}
+	if gitURL.Host == giteaHost || gitSource.Spec.Flavor == repository.GiteaFlavor {
+		secret := secretProvider.GetSecret(git.NewOauthToken([]byte("")))
+		repo, err := repository.NewStructuredIdentifier(gitSource, gitURL)
+		if err != nil {
+			return nil, err
+		}
+		return &RepositoryService{
+			secret:  secret,
+			client:  secret.Client(),
+			baseURL: gitURL.BaseURL(),
+			repo:    repo,
+			log:     log,
+		}, nil
+	}
+	return nil, nil
+}
+
+// CreateWebhook registers a webhook sending push events as JSON payloads signed using the given secret
+func (s *RepositoryService) CreateWebhook(ctx context.Context, url, secret string) (string, error) {
+	if s.secret.SecretContent() == "" {
+		return "", fmt.Errorf("a secret with credentials is required to create a webhook")
+	}
+	apiURL := fmt.Sprintf("%sapi/v1/repos/%s/%s/hooks", s.baseURL, s.repo.Owner, s.repo.Name)
+	respBody, err := s.do(ctx, http.MethodPost, apiURL, Hook{
+		Type:   "gitea",
+		Active: true,
+		Events: []string{"push"},
+		Config: HookConfig{
+			URL:         url,
+			ContentType: "json",
+			Secret:      secret,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	var hook Hook
+	err = json.Unmarshal(respBody, &hook)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(hook.ID), nil
+}
+
+func (s *RepositoryService) FindWebhook(ctx context.Context, url string) (string, error) {
+	for page := 1; ; page++ {
+		apiURL := fmt.Sprintf("%sapi/v1/repos/%s/%s/hooks?page=%d&limit=%d",
+			s.baseURL, s.repo.Owner, s.repo.Name, page, webhookPageSize)
+		respBody, err := s.do(ctx, http.MethodGet, apiURL, nil)
+		if err != nil {
+			return "", err
+		}
+		var hooks []Hook
+		if err := json.Unmarshal(respBody, &hooks); err != nil {
+			return "", err
+		}
+		for _, hook := range hooks {
+			if hook.Config.URL == url {
+				return fmt.Sprint(hook.ID), nil
+			}
+		}
+		if len(hooks) < webhookPageSize {
+			return "", nil
+		}
+	}
+}
+
+func (s *RepositoryService) WebhookExists(ctx context.Context, id string) (bool, error) {
+	_, err := s.do(ctx, http.MethodGet, s.webhookURL(id), nil)
+	if err != nil {
+		if apiErr := repository.AsAPIError(err); apiErr != nil && apiErr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *RepositoryService) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.webhookURL(id), nil)
+	if apiErr := repository.AsAPIError(err); apiErr != nil && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// GetMetadata reads the metadata from the repository details. Gitea provides the size of the repository in kilobytes
+// and it doesn't track the time of the last push, so the time of the last update is used instead.
+func (s *RepositoryService) GetMetadata(ctx context.Context) (*repository.Metadata, error) {
+	apiURL := fmt.Sprintf("%sapi/v1/repos/%s/%s", s.baseURL, s.repo.Owner, s.repo.Name)
+	respBody, err := s.do(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	var repo Repository
+	err = json.Unmarshal(respBody, &repo)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &repository.Metadata{
+		Description:   repo.Description,
+		DefaultBranch: repo.DefaultBranch,
+		Visibility:    repository.Public,
+		Archived:      repo.Archived,
+		Fork:          repo.Fork,
+		Size:          repo.Size * 1024,
+		LastPushedAt:  repo.UpdatedAt,
+		Topics:        repo.Topics,
+		WebURL:        repo.HTMLURL,
+	}
+	if repo.Private {
+		metadata.Visibility = repository.Private
+	} else if repo.Internal {
+		metadata.Visibility = repository.Internal
+	}
+	return metadata, nil
+}
+
+func (s *RepositoryService) webhookURL(id string) string {
+	return fmt.Sprintf("%sapi/v1/repos/%s/%s/hooks/%s", s.baseURL, s.repo.Owner, s.repo.Name, id)
+}
+
+func (s *RepositoryService) do(ctx context.Context, method, apiURL string, body interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+	req, err := http.NewRequest(method, apiURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.secret.SecretType() == git.UsernamePasswordType {
+		req.SetBasicAuth(git.ParseUsernameAndPassword(s.secret.SecretContent()))
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			s.log.Error(err, "closing body failed")
+		}
+	}()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, repository.NewAPIError(resp, fmt.Sprintf("call to the API endpoint %s failed with [%s] and message [%s]",
+			apiURL, resp.Status, string(respBody)))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,165 @@
+package gitea_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository/gitea"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"testing"
+	"time"
+)
+
+const (
+	repoIdentifier = "some-org/some-repo"
+	giteaHost      = "https://gitea.example.com"
+	repoURL        = giteaHost + "/" + repoIdentifier
+)
+
+var (
+	oauthToken = git.NewOauthToken([]byte("some-token"))
+	logger     = &log.GitSourceLogger{Logger: logf.Log}
+)
+
+func TestNewWebhookServiceIfMatchesShouldMatchWhenFlavorIsGitea(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+
+	// when
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+
+	// then
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+}
+
+func TestNewWebhookServiceIfMatchesShouldNotMatchWhenGitHubHost(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithURL("https://github.com/" + repoIdentifier))
+
+	// when
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+
+	// then
+	require.NoError(t, err)
+	assert.Nil(t, service)
+}
+
+func TestWebhookServiceCreateWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(giteaHost).
+		Post(fmt.Sprintf("/api/v1/repos/%s/hooks", repoIdentifier)).
+		MatchHeader("Authorization", "Bearer some-token").
+		BodyString(`"type":"gitea"`).
+		BodyString(`"secret":"some-secret"`).
+		Reply(201).
+		BodyString(`{"id": 5, "type": "gitea", "active": true, "config": {"url": "https://some.host/hook"}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.CreateWebhook(context.Background(), "https://some.host/hook", "some-secret")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "5", id)
+}
+
+func TestWebhookServiceFindWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(giteaHost).
+		Get(fmt.Sprintf("/api/v1/repos/%s/hooks", repoIdentifier)).
+		MatchParam("page", "1").
+		Reply(200).
+		BodyString(`[{"id": 4, "config": {"url": "https://other.host/hook"}}, {"id": 5, "config": {"url": "https://some.host/hook"}}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.FindWebhook(context.Background(), "https://some.host/hook")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "5", id)
+}
+
+func TestWebhookServiceCheckAndDeleteRemovedWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(giteaHost).
+		Get(fmt.Sprintf("/api/v1/repos/%s/hooks/5", repoIdentifier)).
+		Reply(404)
+	gock.New(giteaHost).
+		Delete(fmt.Sprintf("/api/v1/repos/%s/hooks/5", repoIdentifier)).
+		Reply(404)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	exists, err := service.WebhookExists(context.Background(), "5")
+	require.NoError(t, err)
+	err = service.DeleteWebhook(context.Background(), "5")
+
+	// then
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestWebhookServiceRequiresCredentials(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+	service, err := gitea.NewWebhookServiceIfMatches()(logger, source, git.NewSecretProvider(nil))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.CreateWebhook(context.Background(), "https://some.host/hook", "some-secret")
+
+	// then
+	assert.Error(t, err)
+}
+
+func TestMetadataServiceGetMetadata(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(giteaHost).
+		Get(fmt.Sprintf("/api/v1/repos/%s", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"description": "Some repo", "default_branch": "main", "private": true, "archived": true,
+			"fork": false, "size": 3, "updated_at": "2019-04-10T09:45:12Z", "topics": ["go"],
+			"html_url": "https://gitea.example.com/some-org/some-repo"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithFlavor("gitea"))
+	service, err := gitea.NewMetadataServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	metadata, err := service.GetMetadata(context.Background())
+
+	// then
+	require.NoError(t, err)
+	updatedAt := time.Date(2019, 4, 10, 9, 45, 12, 0, time.UTC)
+	assert.Equal(t, &repository.Metadata{
+		Description:   "Some repo",
+		DefaultBranch: "main",
+		Visibility:    repository.Private,
+		Archived:      true,
+		Size:          3072,
+		LastPushedAt:  &updatedAt,
+		Topics:        []string{"go"},
+		WebURL:        "https://gitea.example.com/some-org/some-repo",
+	}, metadata)
+}
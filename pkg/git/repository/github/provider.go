@@ -0,0 +1,12 @@
+package github
+
+import "github.com/redhat-developer/devconsole-git/pkg/git/repository"
+
+func init() {
+	repository.Register(repository.Provider{
+		Name:           githubFlavor,
+		Priority:       40,
+		Hosts:          []string{githubHost},
+		ServiceCreator: NewRepoServiceIfMatches(),
+	})
+}
@@ -6,9 +6,14 @@ import (
 	gogh "github.com/google/go-github/github"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
@@ -16,7 +21,13 @@ const (
 	githubFlavor = "github"
 )
 
-var anonymousSecret = git.NewUsernamePassword("anonymous", "")
+var anonymousSecret = newAnonymousSecret()
+
+// newAnonymousSecret returns a new instance of the anonymous secret so the transport settings set to it
+// are not shared among the git sources
+func newAnonymousSecret() git.Secret {
+	return git.NewUsernamePassword("anonymous", "")
+}
 
 type RepositoryService struct {
 	gitSource *v1alpha1.GitSource
@@ -25,6 +36,10 @@ type RepositoryService struct {
 	filenames []string
 	secret    git.Secret
 	log       *log.GitSourceLogger
+	// fetchedRepo is the repository (and the response it was read from) fetched when its accessibility was checked,
+	// so the permissions are checked without fetching it again
+	fetchedRepo     *gogh.Repository
+	fetchedResponse *gogh.Response
 }
 
 // NewRepoServiceIfMatches returns function creating Github repository service if either host of the git repo URL is github.com
@@ -34,29 +49,36 @@ func NewRepoServiceIfMatches() repository.ServiceCreator {
 		if secretProvider.SecretType() == git.SshKeyType {
 			return nil, nil
 		}
-		endpoint, err := gittransport.NewEndpoint(gitSource.Spec.URL)
+		gitURL, err := giturl.Parse(gitSource.Spec.URL)
 		if err != nil {
 			return nil, err
 		}
-		if endpoint.Host == githubHost || gitSource.Spec.Flavor == githubFlavor {
-			secret := secretProvider.GetSecret(anonymousSecret)
-			return newGhService(log, gitSource, endpoint, secret)
+		if gitURL.Host == githubHost || gitSource.Spec.Flavor == githubFlavor {
+			secret := secretProvider.GetSecret(newAnonymousSecret())
+			return newGhService(log, gitSource, gitURL, secret)
 		}
 		return nil, nil
 	}
 }
 
-func newGhService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, endpoint *gittransport.Endpoint, secret git.Secret) (*RepositoryService, error) {
-	repo, err := repository.NewStructuredIdentifier(gitSource, endpoint)
+func newGhService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, gitURL *giturl.URL, secret git.Secret) (*RepositoryService, error) {
+	repo, err := repository.NewStructuredIdentifier(gitSource, gitURL)
 	if err != nil {
 		return nil, err
 	}
 	baseClient := secret.Client()
 	if secret.SecretType() == git.UsernamePasswordType {
 		username, password := git.ParseUsernameAndPassword(secret.SecretContent())
-		baseClient.Transport = &gogh.BasicAuthTransport{Username: username, Password: password}
+		baseClient.Transport = &gogh.BasicAuthTransport{Username: username, Password: password, Transport: baseClient.Transport}
 	}
 	client := gogh.NewClient(baseClient)
+	if app, ok := secret.(*git.GitHubApp); ok {
+		// the installation tokens are valid only for the API (of a GitHub Enterprise server) they were issued by
+		client.BaseURL, err = url.Parse(app.APIURL())
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	return &RepositoryService{
 		gitSource: gitSource,
@@ -67,20 +89,20 @@ func newGhService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, endpo
 	}, nil
 }
 
-func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChecker, error) {
+func (s *RepositoryService) FileExistenceChecker(ctx context.Context) (repository.FileExistenceChecker, error) {
 	if isAnonymousSecret(s.secret) {
 		baseURL := fmt.Sprintf("https://github.com/%s/%s/blob/%s/", s.repo.Owner, s.repo.Name, s.repo.Branch)
 		return repository.NewCheckerUsingHeaderRequests(s.log, baseURL, s.secret), nil
 	}
 
 	tree, _, err := s.client.Git.GetTree(
-		context.Background(),
+		ctx,
 		s.repo.Owner,
 		s.repo.Name,
 		s.repo.Branch,
 		false)
 	if err != nil {
-		return nil, err
+		return nil, toServiceError(err)
 	}
 	var filenames []string
 	for _, entry := range tree.Entries {
@@ -89,18 +111,18 @@ func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChec
 	return repository.NewCheckerWithFetchedFiles(filenames), nil
 }
 
-func (s *RepositoryService) GetLanguageList() ([]string, error) {
+func (s *RepositoryService) GetLanguageList(ctx context.Context) ([]string, error) {
 	if isAnonymousSecret(s.secret) {
 		return []string{}, nil
 	}
 
 	languages, _, err := s.client.Repositories.ListLanguages(
-		context.Background(),
+		ctx,
 		s.repo.Owner,
 		s.repo.Name)
 
 	if err != nil {
-		return nil, err
+		return nil, toServiceError(err)
 	}
 
 	return git.SortLanguagesWithInts(languages), nil
@@ -111,24 +133,279 @@ func isAnonymousSecret(secret git.Secret) bool {
 		secret.SecretContent() == anonymousSecret.SecretContent()
 }
 
-func (s *RepositoryService) CheckCredentials() error {
-	_, _, err := s.client.Users.Get(context.Background(), "")
-	return err
+// CheckCredentials gets the authenticated user. The installation tokens of GitHub Apps don't belong to any user,
+// so the repositories accessible by the installation are listed instead.
+func (s *RepositoryService) CheckCredentials(ctx context.Context) error {
+	if s.secret.SecretType() == git.GitHubAppType {
+		_, _, err := s.client.Apps.ListRepos(ctx, &gogh.ListOptions{PerPage: 1})
+		return toServiceError(err)
+	}
+	_, _, err := s.client.Users.Get(ctx, "")
+	return toServiceError(err)
 }
 
-func (s *RepositoryService) CheckRepoAccessibility() error {
-	_, _, err := s.client.Repositories.Get(
-		context.Background(),
+func (s *RepositoryService) CheckRepoAccessibility(ctx context.Context) error {
+	_, _, err := s.getRepository(ctx)
+	return toServiceError(err)
+}
+
+// getRepository returns the repository fetched before or fetches it if it wasn't fetched yet
+func (s *RepositoryService) getRepository(ctx context.Context) (*gogh.Repository, *gogh.Response, error) {
+	if s.fetchedRepo != nil {
+		return s.fetchedRepo, s.fetchedResponse, nil
+	}
+	repo, resp, err := s.client.Repositories.Get(
+		ctx,
 		s.repo.Owner,
 		s.repo.Name)
-	return err
+	if err != nil {
+		return nil, resp, err
+	}
+	s.fetchedRepo, s.fetchedResponse = repo, resp
+	return repo, resp, nil
 }
 
-func (s *RepositoryService) CheckBranch() error {
+func (s *RepositoryService) CheckBranch(ctx context.Context) error {
 	_, _, err := s.client.Repositories.GetBranch(
-		context.Background(),
+		ctx,
 		s.repo.Owner,
 		s.repo.Name,
 		s.repo.Branch)
+	return toServiceError(err)
+}
+
+// CheckPermissions checks the scopes of the OAuth token sent in the X-OAuth-Scopes header and the permissions
+// of the account in the repository. Fine-grained tokens don't have any scopes so only the permissions are checked.
+// The permission to create webhooks is checked only if the GitSource requests a webhook.
+func (s *RepositoryService) CheckPermissions(ctx context.Context) ([]repository.MissingPermission, error) {
+	if isAnonymousSecret(s.secret) {
+		return nil, nil
+	}
+	repo, resp, err := s.getRepository(ctx)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	webhookRequested := git.IsWebhookRequested(s.gitSource)
+	var missing []repository.MissingPermission
+	if granted, ok := resp.Header["X-Oauth-Scopes"]; ok && s.secret.SecretType() == git.OauthTokenType {
+		scopes := repository.ParseScopes(strings.Join(granted, ","))
+		if repo.GetPrivate() && !scopes["repo"] {
+			missing = repository.AppendMissing(missing, repository.ReadContents,
+				"the token needs the repo scope to read a private repository")
+		}
+		if webhookRequested && !repository.HasAnyScope(scopes, "repo", "admin:repo_hook", "write:repo_hook") {
+			missing = repository.AppendMissing(missing, repository.CreateWebhooks,
+				"the token needs one of the scopes: repo, admin:repo_hook, write:repo_hook")
+		}
+	}
+	if repo.Permissions != nil {
+		permissions := *repo.Permissions
+		if !permissions["pull"] {
+			missing = repository.AppendMissing(missing, repository.ReadRepository,
+				"the account needs at least read access to the repository")
+		}
+		if webhookRequested && !permissions["admin"] {
+			missing = repository.AppendMissing(missing, repository.CreateWebhooks,
+				"the account needs admin access to the repository")
+		}
+	}
+	return missing, nil
+}
+
+// ListBranches returns the branches in the order provided by the GitHub API. The API cannot filter the branches,
+// so the prefix is applied on every page.
+func (s *RepositoryService) ListBranches(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	branches, resp, err := s.client.Repositories.ListBranches(
+		ctx,
+		s.repo.Owner,
+		s.repo.Name,
+		listOptions(options))
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+	var names []string
+	for _, branch := range branches {
+		names = append(names, branch.GetName())
+	}
+	return &repository.RefList{Names: options.FilterByPrefix(names), NextPage: resp.NextPage}, nil
+}
+
+// ListTags returns the tags in the order provided by the GitHub API. The API cannot filter the tags,
+// so the prefix is applied on every page.
+func (s *RepositoryService) ListTags(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	tags, resp, err := s.client.Repositories.ListTags(
+		ctx,
+		s.repo.Owner,
+		s.repo.Name,
+		listOptions(options))
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.GetName())
+	}
+	return &repository.RefList{Names: options.FilterByPrefix(names), NextPage: resp.NextPage}, nil
+}
+
+func listOptions(options repository.RefListOptions) *gogh.ListOptions {
+	return &gogh.ListOptions{Page: options.PageNumber(), PerPage: options.PageSize()}
+}
+
+// GetFileContent gets the file using the contents API. The API doesn't include content of files larger than 1MB
+// in the response, so such files are downloaded separately.
+func (s *RepositoryService) GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error) {
+	limit := repository.MaxFileSize(maxSize)
+	options := &gogh.RepositoryContentGetOptions{Ref: s.repo.Branch}
+	file, _, resp, err := s.client.Repositories.GetContents(
+		ctx,
+		s.repo.Owner,
+		s.repo.Name,
+		path,
+		options)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, &repository.FileNotFoundError{Path: path}
+		}
+		return nil, toServiceError(err)
+	}
+	if file == nil || file.GetType() != "file" {
+		// the API returns the listing of a directory or the details of a submodule or a symlink
+		return nil, &repository.FileNotFoundError{Path: path}
+	}
+	if int64(file.GetSize()) > limit {
+		return nil, &repository.FileTooLargeError{Path: path, Limit: limit}
+	}
+	if file.Content == nil || file.GetEncoding() == "none" {
+		reader, err := s.client.Repositories.DownloadContents(ctx, s.repo.Owner, s.repo.Name, path, options)
+		if err != nil {
+			return nil, toServiceError(err)
+		}
+		defer reader.Close()
+		return repository.ReadLimited(path, reader, limit)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+// GetMetadata reads the metadata from the repository details. GitHub provides the size of the repository in kilobytes.
+func (s *RepositoryService) GetMetadata(ctx context.Context) (*repository.Metadata, error) {
+	repo, _, err := s.client.Repositories.Get(
+		ctx,
+		s.repo.Owner,
+		s.repo.Name)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+	visibility := repository.Public
+	if repo.GetPrivate() {
+		visibility = repository.Private
+	}
+	metadata := &repository.Metadata{
+		Description:   repo.GetDescription(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Visibility:    visibility,
+		Archived:      repo.GetArchived(),
+		Fork:          repo.GetFork(),
+		Size:          int64(repo.GetSize()) * 1024,
+		Topics:        repo.Topics,
+		WebURL:        repo.GetHTMLURL(),
+	}
+	if repo.PushedAt != nil {
+		pushedAt := repo.PushedAt.Time
+		metadata.LastPushedAt = &pushedAt
+	}
+	return metadata, nil
+}
+
+// CreateWebhook registers a webhook sending push events as JSON payloads signed using the given secret
+func (s *RepositoryService) CreateWebhook(ctx context.Context, url, secret string) (string, error) {
+	if isAnonymousSecret(s.secret) {
+		return "", fmt.Errorf("a secret with credentials is required to create a webhook")
+	}
+	hook, _, err := s.client.Repositories.CreateHook(
+		ctx,
+		s.repo.Owner,
+		s.repo.Name,
+		&gogh.Hook{
+			Events: []string{"push"},
+			Active: gogh.Bool(true),
+			Config: map[string]interface{}{
+				"url":          url,
+				"content_type": "json",
+				"secret":       secret,
+				"insecure_ssl": "0",
+			},
+		})
+	if err != nil {
+		return "", toServiceError(err)
+	}
+	return strconv.FormatInt(hook.GetID(), 10), nil
+}
+
+func (s *RepositoryService) FindWebhook(ctx context.Context, url string) (string, error) {
+	options := &gogh.ListOptions{PerPage: 100}
+	for {
+		hooks, resp, err := s.client.Repositories.ListHooks(ctx, s.repo.Owner, s.repo.Name, options)
+		if err != nil {
+			return "", toServiceError(err)
+		}
+		for _, hook := range hooks {
+			if hook.Config["url"] == url {
+				return strconv.FormatInt(hook.GetID(), 10), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return "", nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+func (s *RepositoryService) WebhookExists(ctx context.Context, id string) (bool, error) {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid ID of the webhook %s: %s", id, err)
+	}
+	_, resp, err := s.client.Repositories.GetHook(ctx, s.repo.Owner, s.repo.Name, hookID)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, toServiceError(err)
+	}
+	return true, nil
+}
+
+func (s *RepositoryService) DeleteWebhook(ctx context.Context, id string) error {
+	hookID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ID of the webhook %s: %s", id, err)
+	}
+	resp, err := s.client.Repositories.DeleteHook(ctx, s.repo.Owner, s.repo.Name, hookID)
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return toServiceError(err)
+	}
+	return nil
+}
+
+// toServiceError converts the errors of the GitHub client to the ones shared by all services
+func toServiceError(err error) error {
+	switch ghErr := err.(type) {
+	case *gogh.RateLimitError:
+		return git.NewRateLimitError(ghErr.Rate.Reset.Time, ghErr.Message)
+	case *gogh.AbuseRateLimitError:
+		reset := time.Now()
+		if ghErr.RetryAfter != nil {
+			reset = reset.Add(*ghErr.RetryAfter)
+		}
+		return git.NewRateLimitError(reset, ghErr.Message)
+	case *gogh.ErrorResponse:
+		return repository.NewAPIError(ghErr.Response, ghErr.Error())
+	}
 	return err
 }
@@ -1,9 +1,11 @@
 package github_test
 
 import (
+	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/github"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
@@ -12,6 +14,7 @@ import (
 	"gopkg.in/h2non/gock.v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"testing"
+	"time"
 )
 
 const (
@@ -46,14 +49,14 @@ func TestRepositoryServiceForAllValidAuthMethodsSuccessful(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 2)
 		assert.Contains(t, filesInRootDir, "pom.xml")
 		assert.Contains(t, filesInRootDir, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 2)
 		assert.Contains(t, languageList, "Java")
@@ -127,12 +130,12 @@ func TestRepositoryServiceForWrongRepo(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "Not Found")
 		assert.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "Not Found")
 		require.Len(t, languageList, 0)
@@ -157,12 +160,12 @@ func TestRepositoryServiceReturningRateLimit(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "API rate limit exceeded")
 		require.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "API rate limit exceeded")
 		require.Len(t, languageList, 0)
@@ -182,7 +185,7 @@ func TestRepositoryServiceUsesHeadCallsWhenAnonymousSecretIsUsed(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		assert.Len(t, filesInRootDir, 0)
@@ -190,12 +193,12 @@ func TestRepositoryServiceUsesHeadCallsWhenAnonymousSecretIsUsed(t *testing.T) {
 		// and when
 		var files []string
 		for _, tool := range build.Tools {
-			files = append(files, checker.DetectFiles(tool)...)
+			files = append(files, checker.DetectFiles(context.Background(), tool)...)
 		}
 		assert.Len(t, files, 1)
 		assert.Contains(t, files, "pom.xml")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 0)
 	}
@@ -215,13 +218,68 @@ func TestRepositoryServiceCheckValidCredentials(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 
 		// then
 		assert.NoError(t, err)
 	}
 }
 
+func TestRepositoryServiceCheckCredentialsOfGitHubApp(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New(ghApiHost).
+		Post("app/installations/42/access_tokens").
+		MatchHeader("Authorization", "^Bearer .+").
+		Reply(201).
+		BodyString(`{"token": "installation-token", "expires_at": "` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`)
+	gock.New(ghApiHost).
+		Get("installation/repositories").
+		MatchHeader("Authorization", "installation-token").
+		Reply(200).
+		BodyString(`{"total_count": 0, "repositories": []}`)
+
+	secret := git.NewGitHubApp(1, test.PrivateWithoutPassphrase(t, pathToTestDir), repoIdentifier).WithInstallationID(42)
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(secret))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckCredentials(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
+func TestRepositoryServiceOfGitHubAppUsesConfiguredAPIURL(t *testing.T) {
+	// given
+	defer gock.OffAll()
+	gock.New("https://github.example.com").
+		Post("api/v3/app/installations/43/access_tokens").
+		Reply(201).
+		BodyString(`{"token": "installation-token", "expires_at": "` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`)
+	gock.New("https://github.example.com").
+		Get("api/v3/installation/repositories").
+		MatchHeader("Authorization", "installation-token").
+		Reply(200).
+		BodyString(`{"total_count": 0, "repositories": []}`)
+
+	secret := git.NewGitHubApp(1, test.PrivateWithoutPassphrase(t, pathToTestDir), repoIdentifier).
+		WithInstallationID(43).
+		WithAPIURL("https://github.example.com/api/v3")
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(secret))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckCredentials(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
+
 func TestRepositoryServiceCheckInvalidCredentials(t *testing.T) {
 	// given
 	defer gock.OffAll()
@@ -236,7 +294,7 @@ func TestRepositoryServiceCheckInvalidCredentials(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckCredentials()
+		err = service.CheckCredentials(context.Background())
 
 		// then
 		assert.Error(t, err)
@@ -257,7 +315,7 @@ func TestRepositoryServiceCheckAccessibleRepo(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -278,7 +336,7 @@ func TestRepositoryServiceCheckInaccessibleRepo(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assert.Error(t, err)
@@ -299,7 +357,7 @@ func TestRepositoryServiceCheckPresentBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -320,9 +378,410 @@ func TestRepositoryServiceCheckMissingBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.Error(t, err)
 	}
 }
+
+func TestRepositoryServiceCheckPermissionsOfTokenWithoutRepoScope(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "read:user, user:email").
+		BodyString(`{"private": true, "permissions": {"admin": true, "push": true, "pull": true}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 2)
+	assert.Equal(t, repository.ReadContents, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "repo scope")
+	assert.Equal(t, repository.CreateWebhooks, missing[1].Permission)
+}
+
+func TestRepositoryServiceCheckPermissionsOfTokenWithRepoScope(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "repo, read:user").
+		BodyString(`{"private": true, "permissions": {"admin": true, "push": true, "pull": true}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestRepositoryServiceCheckPermissionsOfAccountWithoutAdminAccess(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	for _, secret := range validSecrets {
+		// fine-grained tokens and passwords don't have any scopes so the header is not sent
+		gock.New(ghApiHost).
+			Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+			Reply(200).
+			BodyString(`{"private": false, "permissions": {"admin": false, "push": true, "pull": true}}`)
+
+		source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+		service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(secret))
+		require.NoError(t, err)
+
+		// when
+		missing, err := service.CheckPermissions(context.Background())
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, missing, 1)
+		assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+		assert.Contains(t, missing[0].Hint, "admin access")
+	}
+}
+
+func TestRepositoryServiceCheckPermissionsReportsEachMissingPermissionOnce(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "read:user").
+		BodyString(`{"private": false, "permissions": {"admin": false, "push": true, "pull": true}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "admin:repo_hook")
+	assert.Contains(t, missing[0].Hint, "admin access")
+}
+
+func TestRepositoryServiceCheckPermissionsWithoutRequestedWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "read:user").
+		BodyString(`{"private": false, "permissions": {"admin": false, "push": true, "pull": true}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestRepositoryServiceCheckPermissionsUsesRepositoryFetchedWhenCheckingAccessibility(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		SetHeader("X-OAuth-Scopes", "read:user").
+		BodyString(`{"private": true, "permissions": {"admin": true, "push": true, "pull": true}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+	require.NoError(t, service.CheckRepoAccessibility(context.Background()))
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.ReadContents, missing[0].Permission)
+	assert.True(t, gock.IsDone())
+	assert.False(t, gock.HasUnmatchedRequest())
+}
+
+func TestRepositoryServiceCreateWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Post(fmt.Sprintf("repos/%s/hooks", repoIdentifier)).
+		BodyString(`"events":\["push"\]`).
+		BodyString(`"secret":"some-secret"`).
+		Reply(201).
+		BodyString(`{"id": 123}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.(repository.WebhookService).CreateWebhook(context.Background(), "https://some.host/hook", "some-secret")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "123", id)
+}
+
+func TestRepositoryServiceFindWebhookOnNextPage(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/hooks", repoIdentifier)).
+		MatchParam("page", "2").
+		Reply(200).
+		BodyString(`[{"id": 124, "config": {"url": "https://some.host/hook"}}]`)
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/hooks", repoIdentifier)).
+		Reply(200).
+		SetHeader("Link", fmt.Sprintf(`<%s/repos/%s/hooks?page=2>; rel="next"`, ghApiHost, repoIdentifier)).
+		BodyString(`[{"id": 123, "config": {"url": "https://other.host/hook"}}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.(repository.WebhookService).FindWebhook(context.Background(), "https://some.host/hook")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "124", id)
+}
+
+func TestRepositoryServiceDeleteMissingWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Delete(fmt.Sprintf("repos/%s/hooks/123", repoIdentifier)).
+		Reply(404)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	err = service.(repository.WebhookService).DeleteWebhook(context.Background(), "123")
+
+	// then
+	assert.NoError(t, err)
+}
+
+func TestRepositoryServiceListBranches(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/branches", repoIdentifier)).
+		MatchParam("page", "2").
+		MatchParam("per_page", "3").
+		Reply(200).
+		SetHeader("Link", fmt.Sprintf(`<%s/repos/%s/branches?page=3&per_page=3>; rel="next"`, ghApiHost, repoIdentifier)).
+		BodyString(`[{"name": "feature-a"}, {"name": "fix-b"}, {"name": "feature-c"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	branches, err := service.ListBranches(context.Background(),
+		repository.RefListOptions{Page: 2, PerPage: 3, Prefix: "feature-"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature-a", "feature-c"}, branches.Names)
+	assert.Equal(t, 3, branches.NextPage)
+}
+
+func TestRepositoryServiceListTags(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/tags", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	tags, err := service.ListTags(context.Background(), repository.RefListOptions{})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.1.0", "v1.0.0"}, tags.Names)
+	assert.Equal(t, 0, tags.NextPage)
+}
+
+func TestRepositoryServiceGetFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/contents/docs/README.md", repoIdentifier)).
+		MatchParam("ref", "master").
+		Reply(200).
+		BodyString(`{"type": "file", "encoding": "base64", "size": 11, "path": "docs/README.md", ` +
+			`"content": "IyBTb21lIHJl\ncG8=\n"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	content, err := service.GetFileContent(context.Background(), "docs/README.md", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "# Some repo", string(content))
+}
+
+func TestRepositoryServiceGetFileContentOfLargeFile(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/contents/Dockerfile", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"type": "file", "encoding": "base64", "size": 2097152, "path": "Dockerfile", "content": ""}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "Dockerfile", 0)
+
+	// then
+	require.IsType(t, &repository.FileTooLargeError{}, err)
+}
+
+func TestRepositoryServiceGetMissingFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/contents/devfile.yaml", repoIdentifier)).
+		Reply(404).
+		BodyString(`{"message": "Not Found"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "devfile.yaml", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "devfile.yaml"}, err)
+}
+
+func TestRepositoryServiceGetFileContentOfDirectory(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/contents/docs", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"type": "file", "size": 11, "name": "README.md", "path": "docs/README.md"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "docs", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "docs"}, err)
+}
+
+func TestRepositoryServiceGetFileContentOfSubmodule(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s/contents/vendor/lib", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"type": "submodule", "size": 0, "name": "lib", "path": "vendor/lib", ` +
+			`"submodule_git_url": "https://github.com/some-org/lib.git"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "vendor/lib", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "vendor/lib"}, err)
+}
+
+func TestRepositoryServiceGetMetadata(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(ghApiHost).
+		Get(fmt.Sprintf("repos/%s", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"name": "some-repo", "description": "Some repo", "default_branch": "main", "private": true,
+			"archived": true, "fork": true, "size": 2, "pushed_at": "2019-04-10T09:45:12Z", "topics": ["go", "git"],
+			"html_url": "https://github.com/some-org/some-repo"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := github.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	metadata, err := service.(repository.MetadataService).GetMetadata(context.Background())
+
+	// then
+	require.NoError(t, err)
+	pushedAt := time.Date(2019, 4, 10, 9, 45, 12, 0, time.UTC)
+	assert.Equal(t, &repository.Metadata{
+		Description:   "Some repo",
+		DefaultBranch: "main",
+		Visibility:    repository.Private,
+		Archived:      true,
+		Fork:          true,
+		Size:          2048,
+		LastPushedAt:  &pushedAt,
+		Topics:        []string{"go", "git"},
+		WebURL:        "https://github.com/some-org/some-repo",
+	}, metadata)
+}
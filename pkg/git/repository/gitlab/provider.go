@@ -0,0 +1,12 @@
+package gitlab
+
+import "github.com/redhat-developer/devconsole-git/pkg/git/repository"
+
+func init() {
+	repository.Register(repository.Provider{
+		Name:           gitlabFlavor,
+		Priority:       20,
+		Hosts:          []string{gitlabHost},
+		ServiceCreator: NewRepoServiceIfMatches(),
+	})
+}
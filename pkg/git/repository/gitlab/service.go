@@ -1,12 +1,21 @@
 package gitlab
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	gogl "github.com/xanzy/go-gitlab"
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
 )
 
 const (
@@ -17,30 +26,32 @@ const (
 type RepositoryService struct {
 	clientInitializer *clientInitializer
 	repo              repository.StructuredIdentifier
+	webhookRequested  bool
 }
 
 // NewRepoServiceIfMatches returns function creating Github repository service if either host of the git repo URL is gitlab.com
 // or flavor of the given git source is gitlab then, nil otherwise
 func NewRepoServiceIfMatches() repository.ServiceCreator {
 	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secretProvider *git.SecretProvider) (repository.GitService, error) {
-		if secretProvider.SecretType() == git.SshKeyType {
+		if secretProvider.SecretType() == git.SshKeyType || secretProvider.SecretType() == git.GitHubAppType {
 			return nil, nil
 		}
-		endpoint, err := gittransport.NewEndpoint(gitSource.Spec.URL)
+		gitURL, err := giturl.Parse(gitSource.Spec.URL)
 		if err != nil {
 			return nil, err
 		}
 
-		if endpoint.Host == gitlabHost || gitSource.Spec.Flavor == gitlabFlavor {
+		if gitURL.Host == gitlabHost || gitSource.Spec.Flavor == gitlabFlavor {
 			secret := secretProvider.GetSecret(git.NewOauthToken([]byte("")))
-			return newGlService(gitSource, secret, endpoint)
+			return newGlService(gitSource, secret, secretProvider.TransportSettings(), gitURL)
 		}
 		return nil, nil
 	}
 }
 
-func newGlService(gitSource *v1alpha1.GitSource, secret git.Secret, endpoint *gittransport.Endpoint) (*RepositoryService, error) {
-	repo, err := repository.NewStructuredIdentifier(gitSource, endpoint)
+func newGlService(gitSource *v1alpha1.GitSource, secret git.Secret, settings *git.TransportSettings,
+	gitURL *giturl.URL) (*RepositoryService, error) {
+	repo, err := repository.NewNamespacedIdentifier(gitSource, gitURL)
 	if err != nil {
 		return nil, err
 	}
@@ -48,29 +59,37 @@ func newGlService(gitSource *v1alpha1.GitSource, secret git.Secret, endpoint *gi
 	return &RepositoryService{
 		clientInitializer: &clientInitializer{
 			secret:   secret,
-			endpoint: endpoint,
+			settings: settings,
+			baseURL:  gitURL.BaseURL(),
 		},
-		repo: repo,
+		repo:             repo,
+		webhookRequested: git.IsWebhookRequested(gitSource),
 	}, nil
 }
 
 type clientInitializer struct {
 	client   *gogl.Client
 	secret   git.Secret
-	endpoint *gittransport.Endpoint
+	settings *git.TransportSettings
+	baseURL  string
 }
 
 func (i *clientInitializer) init() (*gogl.Client, error) {
 	if i.client == nil {
-		client := gogl.NewClient(nil, i.secret.SecretContent())
-		err := client.SetBaseURL(getBaseUrl(i.endpoint))
+		httpClient := i.settings.HTTPClient()
+		client := gogl.NewClient(httpClient, i.secret.SecretContent())
+		if oauthToken, ok := i.secret.(*git.OauthToken); ok && oauthToken.IsRefreshable() {
+			// the client of the secret refreshes the token and sends it in the Authorization header
+			client = gogl.NewOAuthClient(oauthToken.Client(), "")
+		}
+		err := client.SetBaseURL(i.baseURL)
 		if err != nil {
 			return nil, err
 		}
 
 		if i.secret.SecretType() == git.UsernamePasswordType {
 			username, password := git.ParseUsernameAndPassword(i.secret.SecretContent())
-			client, err = gogl.NewBasicAuthClient(nil, getBaseUrl(i.endpoint), username, password)
+			client, err = gogl.NewBasicAuthClient(httpClient, i.baseURL, username, password)
 			if err != nil {
 				return nil, err
 			}
@@ -80,14 +99,7 @@ func (i *clientInitializer) init() (*gogl.Client, error) {
 	return i.client, nil
 }
 
-func getBaseUrl(endpoint *gittransport.Endpoint) string {
-	if endpoint.Protocol == "ssh" || endpoint.Protocol == "git" {
-		return "https://" + endpoint.Host
-	}
-	return endpoint.String()[:len(endpoint.String())-len(endpoint.Path)]
-}
-
-func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChecker, error) {
+func (s *RepositoryService) FileExistenceChecker(ctx context.Context) (repository.FileExistenceChecker, error) {
 	client, err := s.clientInitializer.init()
 	if err != nil {
 		return nil, err
@@ -96,9 +108,10 @@ func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChec
 		s.repo.OwnerWithName(),
 		&gogl.ListTreeOptions{
 			Ref: &s.repo.Branch,
-		})
+		},
+		gogl.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, toServiceError(err)
 	}
 	var filenames []string
 	for _, entry := range tree {
@@ -107,41 +120,358 @@ func (s *RepositoryService) FileExistenceChecker() (repository.FileExistenceChec
 	return repository.NewCheckerWithFetchedFiles(filenames), nil
 }
 
-func (s *RepositoryService) GetLanguageList() ([]string, error) {
+func (s *RepositoryService) GetLanguageList(ctx context.Context) ([]string, error) {
 	client, err := s.clientInitializer.init()
 	if err != nil {
 		return nil, err
 	}
-	languages, _, err := client.Projects.GetProjectLanguages(s.repo.OwnerWithName())
+	languages, _, err := client.Projects.GetProjectLanguages(s.repo.OwnerWithName(), gogl.WithContext(ctx))
 	if err != nil {
-		return nil, err
+		return nil, toServiceError(err)
 	}
 
 	return git.SortLanguagesWithFloats32(*languages), nil
 }
-func (s *RepositoryService) CheckCredentials() error {
+func (s *RepositoryService) CheckCredentials(ctx context.Context) error {
 	client, err := s.clientInitializer.init()
 	if err != nil {
 		return err
 	}
-	_, _, err = client.Users.CurrentUser()
-	return err
+	_, _, err = client.Users.CurrentUser(gogl.WithContext(ctx))
+	return toServiceError(err)
 }
 
-func (s *RepositoryService) CheckRepoAccessibility() error {
+func (s *RepositoryService) CheckRepoAccessibility(ctx context.Context) error {
 	client, err := s.clientInitializer.init()
 	if err != nil {
 		return err
 	}
-	_, _, err = client.Projects.GetProject(s.repo.OwnerWithName(), &gogl.GetProjectOptions{})
-	return err
+	_, _, err = client.Projects.GetProject(s.repo.OwnerWithName(), &gogl.GetProjectOptions{}, gogl.WithContext(ctx))
+	return toServiceError(err)
 }
 
-func (s *RepositoryService) CheckBranch() error {
+func (s *RepositoryService) CheckBranch(ctx context.Context) error {
 	client, err := s.clientInitializer.init()
 	if err != nil {
 		return err
 	}
-	_, _, err = client.Branches.GetBranch(s.repo.OwnerWithName(), s.repo.Branch)
+	_, _, err = client.Branches.GetBranch(s.repo.OwnerWithName(), s.repo.Branch, gogl.WithContext(ctx))
+	return toServiceError(err)
+}
+
+// CheckPermissions checks the scopes of the personal access token and the access level of the account in the project.
+// The scopes are not checked if the GitLab instance doesn't provide them (the endpoint is available since GitLab 15.5).
+// The permission to create webhooks is checked only if the GitSource requests a webhook.
+func (s *RepositoryService) CheckPermissions(ctx context.Context) ([]repository.MissingPermission, error) {
+	secret := s.clientInitializer.secret
+	if secret.SecretContent() == "" {
+		return nil, nil
+	}
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return nil, err
+	}
+	project, _, err := client.Projects.GetProject(s.repo.OwnerWithName(), &gogl.GetProjectOptions{}, gogl.WithContext(ctx))
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+
+	var missing []repository.MissingPermission
+	if secret.SecretType() == git.OauthTokenType {
+		scopes, err := tokenScopes(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		if scopes != nil {
+			if !repository.HasAnyScope(scopes, "api", "read_api", "read_repository") {
+				missing = repository.AppendMissing(missing, repository.ReadContents,
+					"the token needs one of the scopes: api, read_api, read_repository")
+			}
+			if s.webhookRequested && !scopes["api"] {
+				missing = repository.AppendMissing(missing, repository.CreateWebhooks, "the token needs the api scope")
+			}
+		}
+	}
+
+	if project.Permissions == nil {
+		return missing, nil
+	}
+	accessLevel := projectAccessLevel(project.Permissions)
+	if project.Visibility == gogl.PrivateVisibility && accessLevel < gogl.ReporterPermissions {
+		missing = repository.AppendMissing(missing, repository.ReadContents,
+			"the account needs at least the Reporter role in the project")
+	}
+	if s.webhookRequested && accessLevel < gogl.MaintainerPermissions {
+		missing = repository.AppendMissing(missing, repository.CreateWebhooks,
+			"the account needs at least the Maintainer role in the project")
+	}
+	return missing, nil
+}
+
+// tokenScopes returns the scopes of the used personal access token or nil if the GitLab instance doesn't provide them
+func tokenScopes(ctx context.Context, client *gogl.Client) (map[string]bool, error) {
+	req, err := client.NewRequest("GET", "personal_access_tokens/self", nil, []gogl.OptionFunc{gogl.WithContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	var token struct {
+		Scopes []string `json:"scopes"`
+	}
+	resp, err := client.Do(req, &token)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, toServiceError(err)
+	}
+	return repository.ParseScopes(strings.Join(token.Scopes, ",")), nil
+}
+
+// projectAccessLevel returns the higher of the access levels the account has in the project and in its group
+func projectAccessLevel(permissions *gogl.Permissions) gogl.AccessLevelValue {
+	var accessLevel gogl.AccessLevelValue
+	if permissions.ProjectAccess != nil {
+		accessLevel = permissions.ProjectAccess.AccessLevel
+	}
+	if groupAccess := permissions.GroupAccess; groupAccess != nil && groupAccess.AccessLevel > accessLevel {
+		accessLevel = groupAccess.AccessLevel
+	}
+	return accessLevel
+}
+
+// listRefsOptions are the query parameters of the GitLab endpoints listing branches and tags. The search parameter
+// starting with ^ matches the names starting with the rest of the value.
+type listRefsOptions struct {
+	Page    int    `url:"page,omitempty"`
+	PerPage int    `url:"per_page,omitempty"`
+	Search  string `url:"search,omitempty"`
+}
+
+// ListBranches returns the branches sorted by name. The prefix is passed to the GitLab API as a search parameter
+// and applied to the returned names as well, as older GitLab instances search for the value anywhere in the name.
+func (s *RepositoryService) ListBranches(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	var branches []*gogl.Branch
+	resp, err := s.listRefs(ctx, "branches", options, &branches)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, branch := range branches {
+		names = append(names, branch.Name)
+	}
+	return &repository.RefList{Names: options.FilterByPrefix(names), NextPage: resp.NextPage}, nil
+}
+
+// ListTags returns the tags sorted by the date of the last commit, the newest first
+func (s *RepositoryService) ListTags(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	var tags []*gogl.Tag
+	resp, err := s.listRefs(ctx, "tags", options, &tags)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return &repository.RefList{Names: options.FilterByPrefix(names), NextPage: resp.NextPage}, nil
+}
+
+func (s *RepositoryService) listRefs(ctx context.Context, refType string, options repository.RefListOptions,
+	refs interface{}) (*gogl.Response, error) {
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return nil, err
+	}
+	query := &listRefsOptions{Page: options.PageNumber(), PerPage: options.PageSize()}
+	if options.Prefix != "" {
+		query.Search = "^" + options.Prefix
+	}
+	path := fmt.Sprintf("projects/%s/repository/%s", url.PathEscape(s.repo.OwnerWithName()), refType)
+	req, err := client.NewRequest("GET", path, query, []gogl.OptionFunc{gogl.WithContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req, refs)
+	if err != nil {
+		return nil, toServiceError(err)
+	}
+	return resp, nil
+}
+
+// rawFileOptions are the query parameters of the GitLab endpoint returning raw content of a file
+type rawFileOptions struct {
+	Ref string `url:"ref"`
+}
+
+// GetFileContent downloads the file using the raw files API. The download is stopped as soon as the limit is exceeded.
+func (s *RepositoryService) GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error) {
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return nil, err
+	}
+	apiPath := fmt.Sprintf("projects/%s/repository/files/%s/raw",
+		url.PathEscape(s.repo.OwnerWithName()), url.PathEscape(path))
+	req, err := client.NewRequest("GET", apiPath, &rawFileOptions{Ref: s.repo.Branch},
+		[]gogl.OptionFunc{gogl.WithContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	content := repository.NewLimitedBuffer(path, repository.MaxFileSize(maxSize))
+	resp, err := client.Do(req, content)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, &repository.FileNotFoundError{Path: path}
+		}
+		return nil, toServiceError(err)
+	}
+	return content.Bytes(), nil
+}
+
+// projectOptions are the query parameters of the GitLab endpoint returning the project details
+type projectOptions struct {
+	Statistics bool `url:"statistics,omitempty"`
+}
+
+// project contains the fields of the project details that are used as metadata. Both the topics and the tag list are
+// decoded, as the tag list was renamed to topics in GitLab 14.0.
+type project struct {
+	Description       string           `json:"description"`
+	DefaultBranch     string           `json:"default_branch"`
+	Visibility        string           `json:"visibility"`
+	Archived          bool             `json:"archived"`
+	ForkedFromProject *json.RawMessage `json:"forked_from_project"`
+	LastActivityAt    *time.Time       `json:"last_activity_at"`
+	Topics            []string         `json:"topics"`
+	TagList           []string         `json:"tag_list"`
+	WebURL            string           `json:"web_url"`
+	Statistics        *struct {
+		RepositorySize int64 `json:"repository_size"`
+	} `json:"statistics"`
+}
+
+// GetMetadata reads the metadata from the project details. The statistics containing the size of the repository are
+// provided only to the members with at least the reporter role and GitLab doesn't track the time of the last push,
+// so the time of the last activity in the project is used instead.
+func (s *RepositoryService) GetMetadata(ctx context.Context) (*repository.Metadata, error) {
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("projects/%s", url.PathEscape(s.repo.OwnerWithName()))
+	req, err := client.NewRequest("GET", path, &projectOptions{Statistics: true}, []gogl.OptionFunc{gogl.WithContext(ctx)})
+	if err != nil {
+		return nil, err
+	}
+	var details project
+	if _, err := client.Do(req, &details); err != nil {
+		return nil, toServiceError(err)
+	}
+
+	metadata := &repository.Metadata{
+		Description:   details.Description,
+		DefaultBranch: details.DefaultBranch,
+		Visibility:    repository.Visibility(details.Visibility),
+		Archived:      details.Archived,
+		Fork:          details.ForkedFromProject != nil,
+		LastPushedAt:  details.LastActivityAt,
+		Topics:        details.Topics,
+		WebURL:        details.WebURL,
+	}
+	if len(metadata.Topics) == 0 {
+		metadata.Topics = details.TagList
+	}
+	if details.Statistics != nil {
+		metadata.Size = details.Statistics.RepositorySize
+	}
+	return metadata, nil
+}
+
+// CreateWebhook registers a webhook sending push events with the given secret in the X-Gitlab-Token header
+func (s *RepositoryService) CreateWebhook(ctx context.Context, url, secret string) (string, error) {
+	if s.clientInitializer.secret.SecretContent() == "" {
+		return "", fmt.Errorf("a secret with credentials is required to create a webhook")
+	}
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return "", err
+	}
+	hook, _, err := client.Projects.AddProjectHook(
+		s.repo.OwnerWithName(),
+		&gogl.AddProjectHookOptions{
+			URL:                   gogl.String(url),
+			PushEvents:            gogl.Bool(true),
+			Token:                 gogl.String(secret),
+			EnableSSLVerification: gogl.Bool(true),
+		},
+		gogl.WithContext(ctx))
+	if err != nil {
+		return "", toServiceError(err)
+	}
+	return strconv.Itoa(hook.ID), nil
+}
+
+func (s *RepositoryService) FindWebhook(ctx context.Context, url string) (string, error) {
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return "", err
+	}
+	options := &gogl.ListProjectHooksOptions{PerPage: 100}
+	for {
+		hooks, resp, err := client.Projects.ListProjectHooks(s.repo.OwnerWithName(), options, gogl.WithContext(ctx))
+		if err != nil {
+			return "", toServiceError(err)
+		}
+		for _, hook := range hooks {
+			if hook.URL == url {
+				return strconv.Itoa(hook.ID), nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return "", nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+func (s *RepositoryService) WebhookExists(ctx context.Context, id string) (bool, error) {
+	hookID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("invalid ID of the webhook %s: %s", id, err)
+	}
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return false, err
+	}
+	_, resp, err := client.Projects.GetProjectHook(s.repo.OwnerWithName(), hookID, gogl.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, toServiceError(err)
+	}
+	return true, nil
+}
+
+func (s *RepositoryService) DeleteWebhook(ctx context.Context, id string) error {
+	hookID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid ID of the webhook %s: %s", id, err)
+	}
+	client, err := s.clientInitializer.init()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Projects.DeleteProjectHook(s.repo.OwnerWithName(), hookID, gogl.WithContext(ctx))
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return toServiceError(err)
+	}
+	return nil
+}
+
+// toServiceError converts the errors of the GitLab client to the ones shared by all services
+func toServiceError(err error) error {
+	if glErr, ok := err.(*gogl.ErrorResponse); ok {
+		return repository.NewAPIError(glErr.Response, glErr.Error())
+	}
 	return err
 }
@@ -1,10 +1,12 @@
 package gitlab_test
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository/gitlab"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
@@ -14,9 +16,11 @@ import (
 	"golang.org/x/oauth2"
 	"gopkg.in/h2non/gock.v1"
 	"math/rand"
+	"net/http"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -51,14 +55,14 @@ func TestRepositoryServiceForAllValidAuthMethodsSuccessful(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 2)
 		assert.Contains(t, filesInRootDir, "pom.xml")
 		assert.Contains(t, filesInRootDir, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 2)
 		assert.Contains(t, languageList, "Java")
@@ -137,12 +141,12 @@ func TestRepositoryServiceForWrongRepo(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "Not Found")
 		require.Nil(t, checker)
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "Not Found")
 		require.Len(t, languageList, 0)
@@ -167,14 +171,14 @@ func TestRepositoryServiceForPrivateInstance(t *testing.T) {
 		// then
 		require.NoError(t, err)
 
-		checker, err := service.FileExistenceChecker()
+		checker, err := service.FileExistenceChecker(context.Background())
 		require.NoError(t, err)
 		filesInRootDir := checker.GetListOfFoundFiles()
 		require.Len(t, filesInRootDir, 2)
 		assert.Contains(t, filesInRootDir, "pom.xml")
 		assert.Contains(t, filesInRootDir, "mvnw")
 
-		languageList, err := service.GetLanguageList()
+		languageList, err := service.GetLanguageList(context.Background())
 		require.NoError(t, err)
 		require.Len(t, languageList, 2)
 		assert.Contains(t, languageList, "Java")
@@ -197,12 +201,53 @@ func TestRepositoryServiceCheckValidToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	assert.NoError(t, err)
 }
 
+func TestRepositoryServiceCheckValidTokenRefreshedWhenExpired(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Post("/oauth/token").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"access_token":  "refreshed-token",
+			"refresh_token": "new-refresh-token",
+			"token_type":    "bearer",
+			"expires_in":    7200})
+	gock.New(glHost).
+		Get("/api/v4/user").
+		MatchHeader("Authorization", "Bearer refreshed-token").
+		Reply(200).
+		BodyString("{}")
+
+	var refreshed *oauth2.Token
+	token := git.NewRefreshableOauthToken(
+		&oauth2.Token{AccessToken: "expired-token", RefreshToken: "some-refresh-token", Expiry: time.Now().Add(-time.Hour)},
+		&oauth2.Config{ClientID: "some-client", Endpoint: oauth2.Endpoint{TokenURL: glHost + "oauth/token"}},
+		func(token *oauth2.Token) error {
+			refreshed = token
+			return nil
+		})
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(token))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckCredentials(context.Background())
+
+	// then
+	assert.NoError(t, err)
+	require.NotNil(t, refreshed)
+	assert.Equal(t, "refreshed-token", refreshed.AccessToken)
+	assert.Equal(t, "new-refresh-token", refreshed.RefreshToken)
+	assert.Equal(t, "refreshed-token", token.SecretContent())
+}
+
 func TestRepositoryServiceCheckInvalidToken(t *testing.T) {
 	// given
 	defer gock.OffAll()
@@ -218,7 +263,7 @@ func TestRepositoryServiceCheckInvalidToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	assert.Error(t, err)
@@ -240,7 +285,7 @@ func TestRepositoryServiceCheckValidUsernameAndPassword(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	assert.NoError(t, err)
@@ -260,7 +305,7 @@ func TestRepositoryServiceCheckInvalidUsernameAndPassword(t *testing.T) {
 	require.NoError(t, err)
 
 	// when
-	err = service.CheckCredentials()
+	err = service.CheckCredentials(context.Background())
 
 	// then
 	assert.Error(t, err)
@@ -282,7 +327,7 @@ func TestRepositoryServiceCheckAccessibleRepo(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -304,7 +349,7 @@ func TestRepositoryServiceCheckInaccessibleRepo(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckRepoAccessibility()
+		err = service.CheckRepoAccessibility(context.Background())
 
 		// then
 		assert.Error(t, err)
@@ -327,7 +372,7 @@ func TestRepositoryServiceCheckPresentBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.NoError(t, err)
@@ -349,13 +394,36 @@ func TestRepositoryServiceCheckMissingBranch(t *testing.T) {
 		require.NoError(t, err)
 
 		// when
-		err = service.CheckBranch()
+		err = service.CheckBranch(context.Background())
 
 		// then
 		assert.Error(t, err)
 	}
 }
 
+func TestRepositoryServiceCheckBranchOfProjectInSubgroup(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get("/api/v4/projects/some-org/some-subgroup/some-repo/repository/branches/dev").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return strings.Contains(req.URL.RequestURI(), "/projects/some-org%2Fsome-subgroup%2Fsome-repo/"), nil
+		}).
+		Reply(200).
+		BodyString("{}")
+
+	source := test.NewGitSource(test.WithURL(glHost+"some-org/some-subgroup/some-repo"), test.WithRef("dev"))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(usernamePassword))
+	require.NoError(t, err)
+
+	// when
+	err = service.CheckBranch(context.Background())
+
+	// then
+	assert.NoError(t, err)
+}
+
 func mockTokenCall(t *testing.T) {
 	token := &oauth2.Token{
 		AccessToken: "some-token",
@@ -415,3 +483,292 @@ func sha(files ...string) *string {
 func String(value string) *string {
 	return &value
 }
+
+func TestRepositoryServiceCheckPermissionsOfTokenWithReadScope(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"visibility": "private", "permissions": {"project_access": {"access_level": 40}}}`)
+	gock.New(glHost).
+		Get("/api/v4/personal_access_tokens/self").
+		Reply(200).
+		BodyString(`{"scopes": ["read_repository", "read_user"]}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "api scope")
+}
+
+func TestRepositoryServiceCheckPermissionsOfDeveloperInPrivateProject(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"visibility": "private", "permissions": {"project_access": {"access_level": 10}, ` +
+			`"group_access": {"access_level": 30}}}`)
+	gock.New(glHost).
+		Get("/api/v4/personal_access_tokens/self").
+		Reply(200).
+		BodyString(`{"scopes": ["api"]}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	assert.Equal(t, repository.CreateWebhooks, missing[0].Permission)
+	assert.Contains(t, missing[0].Hint, "Maintainer")
+}
+
+func TestRepositoryServiceCheckPermissionsWhenTokenScopesAreNotAvailable(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s", repoIdentifier)).
+		Reply(200).
+		BodyString(`{"visibility": "private", "permissions": {"project_access": {"access_level": 50}}}`)
+	gock.New(glHost).
+		Get("/api/v4/personal_access_tokens/self").
+		Reply(404).
+		BodyString(`{"error":"404 Not Found"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithAnnotation(git.WebhookAnnotation, "true"))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	missing, err := service.CheckPermissions(context.Background())
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+}
+
+func TestRepositoryServiceCreateWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Post(fmt.Sprintf("/api/v4/projects/%s/hooks", repoIdentifier)).
+		BodyString(`"push_events":true`).
+		BodyString(`"token":"some-secret"`).
+		Reply(201).
+		BodyString(`{"id": 7}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.(repository.WebhookService).CreateWebhook(context.Background(), "https://some.host/hook", "some-secret")
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "7", id)
+}
+
+func TestRepositoryServiceFindMissingWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/hooks", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"id": 7, "url": "https://other.host/hook"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	id, err := service.(repository.WebhookService).FindWebhook(context.Background(), "https://some.host/hook")
+
+	// then
+	require.NoError(t, err)
+	assert.Empty(t, id)
+}
+
+func TestRepositoryServiceCheckRemovedWebhook(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/hooks/7", repoIdentifier)).
+		Reply(404).
+		BodyString(`{"message":"404 Not found"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	exists, err := service.(repository.WebhookService).WebhookExists(context.Background(), "7")
+
+	// then
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRepositoryServiceListBranchesWithPrefix(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/repository/branches", repoIdentifier)).
+		MatchParam("search", `\^feature-`).
+		MatchParam("per_page", "100").
+		Reply(200).
+		SetHeader("X-Next-Page", "2").
+		BodyString(`[{"name": "feature-a"}, {"name": "feature-b"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	branches, err := service.ListBranches(context.Background(), repository.RefListOptions{Prefix: "feature-"})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature-a", "feature-b"}, branches.Names)
+	assert.Equal(t, 2, branches.NextPage)
+}
+
+func TestRepositoryServiceListTags(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/repository/tags", repoIdentifier)).
+		Reply(200).
+		BodyString(`[{"name": "v1.1.0"}, {"name": "v1.0.0"}]`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	tags, err := service.ListTags(context.Background(), repository.RefListOptions{})
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.1.0", "v1.0.0"}, tags.Names)
+	assert.Equal(t, 0, tags.NextPage)
+}
+
+func TestRepositoryServiceGetFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/repository/files/docs/README.md/raw", repoIdentifier)).
+		MatchParam("ref", "dev").
+		Reply(200).
+		BodyString("# Some repo")
+
+	source := test.NewGitSource(test.WithURL(repoURL), test.WithRef("dev"))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	content, err := service.GetFileContent(context.Background(), "docs/README.md", 0)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "# Some repo", string(content))
+}
+
+func TestRepositoryServiceGetFileContentExceedingLimit(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/repository/files/README.md/raw", repoIdentifier)).
+		Reply(200).
+		BodyString("# Some repo")
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "README.md", 5)
+
+	// then
+	assert.Equal(t, &repository.FileTooLargeError{Path: "README.md", Limit: 5}, err)
+}
+
+func TestRepositoryServiceGetMissingFileContent(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s/repository/files/devfile.yaml/raw", repoIdentifier)).
+		Reply(404).
+		BodyString(`{"message":"404 File Not Found"}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	_, err = service.GetFileContent(context.Background(), "devfile.yaml", 0)
+
+	// then
+	assert.Equal(t, &repository.FileNotFoundError{Path: "devfile.yaml"}, err)
+}
+
+func TestRepositoryServiceGetMetadata(t *testing.T) {
+	// given
+	defer gock.OffAll()
+
+	gock.New(glHost).
+		Get(fmt.Sprintf("/api/v4/projects/%s", repoIdentifier)).
+		MatchParam("statistics", "true").
+		Reply(200).
+		BodyString(`{"description": "Some repo", "default_branch": "master", "visibility": "internal",
+			"archived": false, "forked_from_project": {"id": 5}, "last_activity_at": "2019-04-10T09:45:12Z",
+			"tag_list": ["go"], "web_url": "https://gitlab.com/some-org/some-repo",
+			"statistics": {"repository_size": 1500}}`)
+
+	source := test.NewGitSource(test.WithURL(repoURL))
+	service, err := gitlab.NewRepoServiceIfMatches()(logger, source, git.NewSecretProvider(oauthToken))
+	require.NoError(t, err)
+
+	// when
+	metadata, err := service.(repository.MetadataService).GetMetadata(context.Background())
+
+	// then
+	require.NoError(t, err)
+	pushedAt := time.Date(2019, 4, 10, 9, 45, 12, 0, time.UTC)
+	assert.Equal(t, &repository.Metadata{
+		Description:   "Some repo",
+		DefaultBranch: "master",
+		Visibility:    repository.Internal,
+		Fork:          true,
+		Size:          1500,
+		LastPushedAt:  &pushedAt,
+		Topics:        []string{"go"},
+		WebURL:        "https://gitlab.com/some-org/some-repo",
+	}, metadata)
+}
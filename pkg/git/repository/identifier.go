@@ -4,51 +4,76 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"strings"
 )
 
 const Master = "master"
 
-// StructuredIdentifier is an identifier of git repository that consist of a owner, name and branch
+// StructuredIdentifier is an identifier of git repository that consist of a owner, name and branch.
+// The owner can consist of multiple segments separated by slashes (e.g. nested GitLab groups).
 type StructuredIdentifier struct {
 	Owner  string
 	Name   string
 	Branch string
 }
 
-// NewStructuredIdentifier returns an instance of the StructuredIdentifier for the given v1alpha1.GitSource
-func NewStructuredIdentifier(gitSource *v1alpha1.GitSource, endpoint *gittransport.Endpoint) (StructuredIdentifier, error) {
-	var repo StructuredIdentifier
-	branch := Master
+// gitLabRouteSegment separates the path of a GitLab project from the routes of the web UI (e.g. /-/tree/master)
+const gitLabRouteSegment = "-"
+
+// NewStructuredIdentifier returns an instance of the StructuredIdentifier for the given v1alpha1.GitSource.
+// The owner and the name are the first two segments of the path, the rest of the path (e.g. a page of the web UI)
+// is ignored. For the <organization>/<project>/_git/<repository> paths the owner consists of the organization
+// and the project.
+func NewStructuredIdentifier(gitSource *v1alpha1.GitSource, gitURL *giturl.URL) (StructuredIdentifier, error) {
+	owner, name, err := gitURL.OwnerAndName()
+	if err != nil {
+		return StructuredIdentifier{}, err
+	}
+	return newStructuredIdentifier(gitSource, owner, name), nil
+}
 
+// NewNamespacedIdentifier returns an instance of the StructuredIdentifier for the given v1alpha1.GitSource pointing
+// to a repository that can be nested in groups and subgroups (GitLab). The owner is the full path of the namespace -
+// all segments of the path except the last one. The routes of the web UI following the "/-/" segment are ignored.
+func NewNamespacedIdentifier(gitSource *v1alpha1.GitSource, gitURL *giturl.URL) (StructuredIdentifier, error) {
+	segments, err := pathSegments(gitURL)
+	if err != nil {
+		return StructuredIdentifier{}, err
+	}
+	for i, segment := range segments {
+		if segment == gitLabRouteSegment {
+			segments = segments[:i]
+			break
+		}
+	}
+	if len(segments) < 2 {
+		return StructuredIdentifier{}, errors.New("url is invalid")
+	}
+	last := len(segments) - 1
+	return newStructuredIdentifier(gitSource, strings.Join(segments[:last], "/"), segments[last]), nil
+}
+
+func newStructuredIdentifier(gitSource *v1alpha1.GitSource, owner, name string) StructuredIdentifier {
+	branch := Master
 	if gitSource.Spec.Ref != "" {
 		branch = gitSource.Spec.Ref
 	}
-	path := endpoint.Path
-	if strings.HasSuffix(path, ".git") {
-		path = path[:len(path)-4]
+	return StructuredIdentifier{
+		Owner:  owner,
+		Name:   name,
+		Branch: branch,
 	}
+}
 
-	switch urlSegments := strings.Split(path, "/"); len(urlSegments) {
-	case 0, 1:
-		return repo, errors.New("url is invalid")
-	case 2:
-		if urlSegments[0] == "" {
-			return repo, errors.New("url is invalid")
-		}
-		return StructuredIdentifier{
-			Owner:  urlSegments[0],
-			Name:   urlSegments[1],
-			Branch: branch,
-		}, nil
-	default:
-		return StructuredIdentifier{
-			Owner:  urlSegments[1],
-			Name:   urlSegments[2],
-			Branch: branch,
-		}, nil
+// pathSegments returns the non-empty segments of the canonical path of the repository.
+// At least two segments (the owner and the name) are required.
+func pathSegments(gitURL *giturl.URL) ([]string, error) {
+	segments := gitURL.Segments()
+	if len(segments) < 2 {
+		return nil, errors.New("url is invalid")
 	}
+	return segments, nil
 }
 
 // OwnerWithName joins owner and name by a slash
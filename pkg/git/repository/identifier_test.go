@@ -1,22 +1,22 @@
 package repository_test
 
 import (
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	gittransport "gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"testing"
 )
 
 func TestNewStructuredIdentifierEmptyBranchAndStandardUrl(t *testing.T) {
 	// given
 	source := test.NewGitSource()
-	endpoint, err := gittransport.NewEndpoint("https://github.com/fabric8-services/fabric8-tenant")
+	gitURL, err := giturl.Parse("https://github.com/fabric8-services/fabric8-tenant")
 	require.NoError(t, err)
 
 	// when
-	identifier, err := repository.NewStructuredIdentifier(source, endpoint)
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
 
 	// then
 	require.NoError(t, err)
@@ -28,11 +28,11 @@ func TestNewStructuredIdentifierEmptyBranchAndStandardUrl(t *testing.T) {
 func TestNewStructuredIdentifierDevBranchAndHttpsCloneUrl(t *testing.T) {
 	// given
 	source := test.NewGitSource(test.WithRef("dev"))
-	endpoint, err := gittransport.NewEndpoint("https://github.com/fabric8-services/fabric8-tenant.git")
+	gitURL, err := giturl.Parse("https://github.com/fabric8-services/fabric8-tenant.git")
 	require.NoError(t, err)
 
 	// when
-	identifier, err := repository.NewStructuredIdentifier(source, endpoint)
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
 
 	// then
 	require.NoError(t, err)
@@ -44,11 +44,11 @@ func TestNewStructuredIdentifierDevBranchAndHttpsCloneUrl(t *testing.T) {
 func TestNewStructuredIdentifierEmptyBranchAndSshCloneUrl(t *testing.T) {
 	// given
 	source := test.NewGitSource()
-	endpoint, err := gittransport.NewEndpoint("git@github.com:fabric8-services/fabric8-tenant.git")
+	gitURL, err := giturl.Parse("git@github.com:fabric8-services/fabric8-tenant.git")
 	require.NoError(t, err)
 
 	// when
-	identifier, err := repository.NewStructuredIdentifier(source, endpoint)
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
 
 	// then
 	require.NoError(t, err)
@@ -60,11 +60,11 @@ func TestNewStructuredIdentifierEmptyBranchAndSshCloneUrl(t *testing.T) {
 func TestNewStructuredIdentifierEmptyBranchAndSshClonePath(t *testing.T) {
 	// given
 	source := test.NewGitSource()
-	endpoint, err := gittransport.NewEndpoint("git@github.com:/fabric8-services/fabric8-tenant.git")
+	gitURL, err := giturl.Parse("git@github.com:/fabric8-services/fabric8-tenant.git")
 	require.NoError(t, err)
 
 	// when
-	identifier, err := repository.NewStructuredIdentifier(source, endpoint)
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
 
 	// then
 	require.NoError(t, err)
@@ -76,11 +76,11 @@ func TestNewStructuredIdentifierEmptyBranchAndSshClonePath(t *testing.T) {
 func TestNewStructuredIdentifierEmptyBranchAndWrongPathInHttpsUrl(t *testing.T) {
 	// given
 	source := test.NewGitSource()
-	endpoint, err := gittransport.NewEndpoint("https://github.com/fabric8-services-fabric8-tenant")
+	gitURL, err := giturl.Parse("https://github.com/fabric8-services-fabric8-tenant")
 	require.NoError(t, err)
 
 	// when
-	_, err = repository.NewStructuredIdentifier(source, endpoint)
+	_, err = repository.NewStructuredIdentifier(source, gitURL)
 
 	// then
 	require.Error(t, err)
@@ -89,11 +89,80 @@ func TestNewStructuredIdentifierEmptyBranchAndWrongPathInHttpsUrl(t *testing.T)
 func TestNewStructuredIdentifierEmptyBranchAndWrongPathInSshUrl(t *testing.T) {
 	// given
 	source := test.NewGitSource()
-	endpoint, err := gittransport.NewEndpoint("git@github.com:/fabric8-services-fabric8-tenant")
+	gitURL, err := giturl.Parse("git@github.com:/fabric8-services-fabric8-tenant")
 	require.NoError(t, err)
 
 	// when
-	_, err = repository.NewStructuredIdentifier(source, endpoint)
+	_, err = repository.NewStructuredIdentifier(source, gitURL)
+
+	// then
+	require.Error(t, err)
+}
+
+func TestNewStructuredIdentifierWithAzureReposUrl(t *testing.T) {
+	// given
+	source := test.NewGitSource()
+	gitURL, err := giturl.Parse("https://dev.azure.com/some-org/some-project/_git/some-repo")
+	require.NoError(t, err)
+
+	// when
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "some-org/some-project", identifier.Owner)
+	assert.Equal(t, "some-repo", identifier.Name)
+	assert.Equal(t, "master", identifier.Branch)
+}
+
+func TestNewStructuredIdentifierIgnoresWebUIPath(t *testing.T) {
+	// given
+	source := test.NewGitSource()
+	gitURL, err := giturl.Parse("https://github.com/fabric8-services/fabric8-tenant/tree/master")
+	require.NoError(t, err)
+
+	// when
+	identifier, err := repository.NewStructuredIdentifier(source, gitURL)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, "fabric8-services", identifier.Owner)
+	assert.Equal(t, "fabric8-tenant", identifier.Name)
+}
+
+func TestNewNamespacedIdentifierWithSubgroups(t *testing.T) {
+	// given
+	source := test.NewGitSource(test.WithRef("dev"))
+
+	for _, url := range []string{
+		"https://gitlab.com/group/subgroup/project",
+		"https://gitlab.com/group/subgroup/project.git",
+		"https://gitlab.com/group/subgroup/project/-/tree/master",
+		"git@gitlab.com:group/subgroup/project.git"} {
+
+		gitURL, err := giturl.Parse(url)
+		require.NoError(t, err, url)
+
+		// when
+		identifier, err := repository.NewNamespacedIdentifier(source, gitURL)
+
+		// then
+		require.NoError(t, err, url)
+		assert.Equal(t, "group/subgroup", identifier.Owner, url)
+		assert.Equal(t, "project", identifier.Name, url)
+		assert.Equal(t, "dev", identifier.Branch, url)
+		assert.Equal(t, "group/subgroup/project", identifier.OwnerWithName(), url)
+	}
+}
+
+func TestNewNamespacedIdentifierWithoutNamespace(t *testing.T) {
+	// given
+	source := test.NewGitSource()
+	gitURL, err := giturl.Parse("https://gitlab.com/project/-/tree/master")
+	require.NoError(t, err)
+
+	// when
+	_, err = repository.NewNamespacedIdentifier(source, gitURL)
 
 	// then
 	require.Error(t, err)
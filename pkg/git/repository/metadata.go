@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+// Visibility says who can see the repository
+type Visibility string
+
+const (
+	// Public repository can be seen by anyone
+	Public Visibility = "public"
+	// Internal repository can be seen by any signed-in user of the git server (GitLab only)
+	Internal Visibility = "internal"
+	// Private repository can be seen only by the users that were granted an access to it
+	Private Visibility = "private"
+)
+
+// Metadata describes the repository as provided by the API of the git server.
+// The values the git server doesn't provide are left empty.
+type Metadata struct {
+	Description   string
+	DefaultBranch string
+	Visibility    Visibility
+	Archived      bool
+	Fork          bool
+	// Size of the repository in bytes
+	Size int64
+	// LastPushedAt is time of the last push to the repository (or of the last update if the server doesn't track pushes)
+	LastPushedAt *time.Time
+	Topics       []string
+	// WebURL is the URL of the repository page in the web UI of the git server
+	WebURL string
+}
+
+// MetadataService reads the metadata of the git repository
+type MetadataService interface {
+	// GetMetadata returns the metadata of the repository
+	GetMetadata(ctx context.Context) (*Metadata, error)
+}
+
+// MetadataServiceCreator creates an instance of MetadataService for the given v1alpha1.GitSource
+type MetadataServiceCreator func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider) (MetadataService, error)
+
+// NewMetadataServiceCreator returns a MetadataServiceCreator that uses the given ServiceCreator.
+// The created GitService is used if it reads metadata, nil is returned otherwise.
+func NewMetadataServiceCreator(creator ServiceCreator) MetadataServiceCreator {
+	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider) (MetadataService, error) {
+		service, err := creator(log, gitSource, secret)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		if metadataService, ok := service.(MetadataService); ok {
+			return metadataService, nil
+		}
+		return nil, nil
+	}
+}
+
+// NewMetadataService returns an instance of MetadataService for the given v1alpha1.GitSource. If no service is matched then returns nil
+func NewMetadataService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider,
+	serviceCreators []MetadataServiceCreator) (MetadataService, error) {
+
+	for _, creator := range serviceCreators {
+		service, err := creator(log, gitSource, secret)
+		if err != nil {
+			return nil, err
+		}
+		if service != nil {
+			return service, nil
+		}
+	}
+	return nil, nil
+}
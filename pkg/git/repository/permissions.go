@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission is an operation with the repository that the operator expects the credentials to allow
+type Permission string
+
+const (
+	// ReadRepository is a permission to read the information about the repository
+	ReadRepository Permission = "read the repository"
+	// ReadContents is a permission to read the files stored in the repository
+	ReadContents Permission = "read the contents"
+	// CreateWebhooks is a permission to register webhooks notifying about changes in the repository
+	CreateWebhooks Permission = "create webhooks"
+)
+
+// MissingPermission is a permission that the credentials don't grant together with a hint how to get it
+type MissingPermission struct {
+	Permission Permission
+	Hint       string
+}
+
+func (p MissingPermission) String() string {
+	return fmt.Sprintf("%s (%s)", p.Permission, p.Hint)
+}
+
+// AppendMissing appends the permission with the given hint to the missing ones. A permission that is already missing
+// is not appended again - the hint is added to its hints instead.
+func AppendMissing(missing []MissingPermission, permission Permission, hint string) []MissingPermission {
+	for i := range missing {
+		if missing[i].Permission == permission {
+			missing[i].Hint += "; " + hint
+			return missing
+		}
+	}
+	return append(missing, MissingPermission{Permission: permission, Hint: hint})
+}
+
+// ParseScopes parses a list of scopes separated by commas or by spaces as it is sent in the headers of the APIs
+func ParseScopes(value string) map[string]bool {
+	scopes := map[string]bool{}
+	for _, scope := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		scopes[scope] = true
+	}
+	return scopes
+}
+
+// HasAnyScope returns true if at least one of the expected scopes is present in the given scopes
+func HasAnyScope(scopes map[string]bool, expected ...string) bool {
+	for _, scope := range expected {
+		if scopes[scope] {
+			return true
+		}
+	}
+	return false
+}
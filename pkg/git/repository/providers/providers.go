@@ -0,0 +1,12 @@
+// Package providers registers the git service providers built into the operator in the default registry.
+// It is imported only for its side effects - the packages using the registry import it to make sure that
+// the providers are registered. Additional providers can be registered by importing their packages the same way.
+package providers
+
+import (
+	// the providers register themselves in their init functions
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/bitbucket"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/gitea"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/github"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/gitlab"
+)
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultRefsPerPage is the number of branches or tags returned in one page when no other size is requested
+const DefaultRefsPerPage = 100
+
+// RefListOptions specifies which page of branches or tags should be listed
+type RefListOptions struct {
+	// Page is the number of the page starting with 1 - the first page is listed when not set
+	Page int
+	// PerPage is the maximal number of names in the page - DefaultRefsPerPage is used when not set
+	PerPage int
+	// Prefix filters the names - only the ones starting with the prefix are listed
+	Prefix string
+}
+
+// RefList is a page of names of branches or tags
+type RefList struct {
+	// Names of the branches or tags (without the refs/heads/ or refs/tags/ prefix)
+	Names []string
+	// NextPage is the number of the next page, 0 if this is the last one
+	NextPage int
+}
+
+// PageNumber returns the number of the requested page, 1 if not set
+func (o RefListOptions) PageNumber() int {
+	if o.Page < 1 {
+		return 1
+	}
+	return o.Page
+}
+
+// PageSize returns the requested size of the page, DefaultRefsPerPage if not set
+func (o RefListOptions) PageSize() int {
+	if o.PerPage < 1 {
+		return DefaultRefsPerPage
+	}
+	return o.PerPage
+}
+
+// FilterByPrefix returns only the names starting with the prefix of the options. Services that cannot filter
+// the names on the server side filter every page - such a page can contain fewer names than requested.
+func (o RefListOptions) FilterByPrefix(names []string) []string {
+	if o.Prefix == "" {
+		return names
+	}
+	var filtered []string
+	for _, name := range names {
+		if strings.HasPrefix(name, o.Prefix) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// NewRefList filters the given names by the prefix, sorts them and returns the requested page.
+// It is used by services that get all names from the server at once.
+func NewRefList(names []string, options RefListOptions) *RefList {
+	filtered := options.FilterByPrefix(names)
+	sorted := make([]string, len(filtered))
+	copy(sorted, filtered)
+	sort.Strings(sorted)
+
+	start := (options.PageNumber() - 1) * options.PageSize()
+	if start >= len(sorted) {
+		return &RefList{}
+	}
+	end := start + options.PageSize()
+	refList := &RefList{}
+	if end < len(sorted) {
+		refList.NextPage = options.PageNumber() + 1
+	} else {
+		end = len(sorted)
+	}
+	refList.Names = sorted[start:end]
+	return refList
+}
@@ -0,0 +1,44 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRefListSortsAndPaginatesNames(t *testing.T) {
+	// given
+	names := []string{"master", "dev", "feature-b", "feature-a", "release"}
+
+	// when
+	first := repository.NewRefList(names, repository.RefListOptions{PerPage: 2})
+	last := repository.NewRefList(names, repository.RefListOptions{Page: 3, PerPage: 2})
+
+	// then
+	assert.Equal(t, []string{"dev", "feature-a"}, first.Names)
+	assert.Equal(t, 2, first.NextPage)
+	assert.Equal(t, []string{"release"}, last.Names)
+	assert.Equal(t, 0, last.NextPage)
+}
+
+func TestNewRefListFiltersByPrefix(t *testing.T) {
+	// given
+	names := []string{"master", "feature-b", "dev", "feature-a"}
+
+	// when
+	refList := repository.NewRefList(names, repository.RefListOptions{Prefix: "feature-"})
+
+	// then
+	assert.Equal(t, []string{"feature-a", "feature-b"}, refList.Names)
+	assert.Equal(t, 0, refList.NextPage)
+}
+
+func TestNewRefListReturnsEmptyPageAfterTheLastOne(t *testing.T) {
+	// when
+	refList := repository.NewRefList([]string{"master"}, repository.RefListOptions{Page: 2})
+
+	// then
+	assert.Empty(t, refList.Names)
+	assert.Equal(t, 0, refList.NextPage)
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+// Provider is a git service (GitHub, GitLab, ...) whose API can be used to access the repositories hosted on it.
+// The providers register themselves in the Registry, so additional ones can be added by importing their packages.
+type Provider struct {
+	// Name of the provider - it is also the flavor of the GitSources the provider is used for
+	Name string
+	// Priority defines the order the providers are tried in, the ones with a higher priority are tried first
+	Priority int
+	// Hosts of the instances of the git service - either host names or patterns like *.example.com
+	Hosts []string
+	// FlavorAliases are other flavors of GitSources the provider is used for
+	FlavorAliases []string
+	// ServiceCreator creates the GitService of the provider. It is used to create the webhook and metadata services
+	// as well unless the dedicated creators are set.
+	ServiceCreator ServiceCreator
+	// WebhookServiceCreator creates the WebhookService of the provider
+	WebhookServiceCreator WebhookServiceCreator
+	// MetadataServiceCreator creates the MetadataService of the provider
+	MetadataServiceCreator MetadataServiceCreator
+}
+
+// Registry holds the registered providers together with the hosts of their self-hosted instances.
+// A provider is used for a GitSource if either the flavor of the GitSource is the name (or an alias) of the provider
+// or the repository URL points to one of the hosts of the provider.
+type Registry struct {
+	mutex     sync.RWMutex
+	providers map[string]*Provider
+	disabled  map[string]bool
+	hosts     map[string]string
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: map[string]*Provider{},
+		disabled:  map[string]bool{},
+		hosts:     map[string]string{},
+	}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register registers the provider in the default registry. It is meant to be called from the init function
+// of the package implementing the provider - panics if the provider cannot be registered.
+func Register(provider Provider) {
+	if err := defaultRegistry.Register(provider); err != nil {
+		panic(err)
+	}
+}
+
+// DefaultRegistry returns the registry all providers built into the operator are registered in
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds the provider to the registry. Fails if the name or an alias of the provider is already taken.
+func (r *Registry) Register(provider Provider) error {
+	if provider.Name == "" {
+		return fmt.Errorf("the name of the provider is missing")
+	}
+	if provider.ServiceCreator == nil && provider.WebhookServiceCreator == nil && provider.MetadataServiceCreator == nil {
+		return fmt.Errorf("the provider %s doesn't create any service", provider.Name)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, flavor := range append([]string{provider.Name}, provider.FlavorAliases...) {
+		if registered := r.providerForFlavor(flavor); registered != nil {
+			return fmt.Errorf("the flavor %s is already used by the provider %s", flavor, registered.Name)
+		}
+	}
+	r.providers[provider.Name] = &provider
+	return nil
+}
+
+// Disable stops using the providers with the given names
+func (r *Registry) Disable(names ...string) error {
+	return r.setDisabled(names, true)
+}
+
+// Enable starts using the providers with the given names again
+func (r *Registry) Enable(names ...string) error {
+	return r.setDisabled(names, false)
+}
+
+func (r *Registry) setDisabled(names []string, disabled bool) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, name := range names {
+		if _, ok := r.providers[name]; !ok {
+			return fmt.Errorf("there is no git service provider %s", name)
+		}
+	}
+	for _, name := range names {
+		r.disabled[name] = disabled
+	}
+	return nil
+}
+
+// AddHost maps the host of a self-hosted instance of a git service to the flavor (name or alias) of its provider
+func (r *Registry) AddHost(host, flavor string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	provider := r.providerForFlavor(flavor)
+	if provider == nil {
+		return fmt.Errorf("there is no git service provider for the flavor %s", flavor)
+	}
+	r.hosts[strings.ToLower(host)] = provider.Name
+	return nil
+}
+
+// FlavorForHost returns the name of the enabled provider of the git service hosted on the given host
+// or an empty string if the host is unknown
+func (r *Registry) FlavorForHost(host string) string {
+	for _, provider := range r.enabledProviders() {
+		if r.matchesHost(provider, host) {
+			return provider.Name
+		}
+	}
+	return ""
+}
+
+// IsKnownFlavor returns true if there is an enabled provider supporting the given flavor
+func (r *Registry) IsKnownFlavor(flavor string) bool {
+	for _, provider := range r.enabledProviders() {
+		if matchesFlavor(provider, flavor) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceCreators returns creators of the git services of the enabled providers ordered by their priority
+func (r *Registry) ServiceCreators() []ServiceCreator {
+	var creators []ServiceCreator
+	for _, provider := range r.enabledProviders() {
+		if provider.ServiceCreator != nil {
+			creators = append(creators, r.serviceCreator(provider))
+		}
+	}
+	return creators
+}
+
+// WebhookServiceCreators returns creators of the webhook services of the enabled providers ordered by their priority
+func (r *Registry) WebhookServiceCreators() []WebhookServiceCreator {
+	var creators []WebhookServiceCreator
+	for _, provider := range r.enabledProviders() {
+		creator := provider.WebhookServiceCreator
+		if creator == nil && provider.ServiceCreator != nil {
+			creator = NewWebhookServiceCreator(provider.ServiceCreator)
+		}
+		if creator == nil {
+			continue
+		}
+		provider := provider
+		creators = append(creators, func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+			secret *git.SecretProvider) (WebhookService, error) {
+			if !r.matches(provider, gitSource) {
+				return nil, nil
+			}
+			return creator(log, withFlavor(gitSource, provider.Name), secret)
+		})
+	}
+	return creators
+}
+
+// MetadataServiceCreators returns creators of the metadata services of the enabled providers ordered by their priority
+func (r *Registry) MetadataServiceCreators() []MetadataServiceCreator {
+	var creators []MetadataServiceCreator
+	for _, provider := range r.enabledProviders() {
+		creator := provider.MetadataServiceCreator
+		if creator == nil && provider.ServiceCreator != nil {
+			creator = NewMetadataServiceCreator(provider.ServiceCreator)
+		}
+		if creator == nil {
+			continue
+		}
+		provider := provider
+		creators = append(creators, func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource,
+			secret *git.SecretProvider) (MetadataService, error) {
+			if !r.matches(provider, gitSource) {
+				return nil, nil
+			}
+			return creator(log, withFlavor(gitSource, provider.Name), secret)
+		})
+	}
+	return creators
+}
+
+// serviceCreator returns a creator calling the ServiceCreator of the provider only for the GitSources it matches
+func (r *Registry) serviceCreator(provider *Provider) ServiceCreator {
+	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider) (GitService, error) {
+		if !r.matches(provider, gitSource) {
+			return nil, nil
+		}
+		return provider.ServiceCreator(log, withFlavor(gitSource, provider.Name), secret)
+	}
+}
+
+// enabledProviders returns the enabled providers sorted by their priority (and by their names if the priority
+// is the same)
+func (r *Registry) enabledProviders() []*Provider {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var providers []*Provider
+	for name, provider := range r.providers {
+		if !r.disabled[name] {
+			providers = append(providers, provider)
+		}
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		if providers[i].Priority != providers[j].Priority {
+			return providers[i].Priority > providers[j].Priority
+		}
+		return providers[i].Name < providers[j].Name
+	})
+	return providers
+}
+
+// matches returns true if the flavor of the GitSource belongs to the provider or if the repository is hosted
+// on one of the hosts of the provider
+func (r *Registry) matches(provider *Provider, gitSource *v1alpha1.GitSource) bool {
+	if matchesFlavor(provider, gitSource.Spec.Flavor) {
+		return true
+	}
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	return err == nil && gitURL.Host != "" && r.matchesHost(provider, gitURL.Host)
+}
+
+func (r *Registry) matchesHost(provider *Provider, host string) bool {
+	host = strings.ToLower(host)
+	r.mutex.RLock()
+	mapped := r.hosts[host]
+	r.mutex.RUnlock()
+	if mapped != "" {
+		return mapped == provider.Name
+	}
+	for _, pattern := range provider.Hosts {
+		if matched, err := path.Match(strings.ToLower(pattern), host); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesFlavor(provider *Provider, flavor string) bool {
+	if flavor == "" {
+		return false
+	}
+	if flavor == provider.Name {
+		return true
+	}
+	for _, alias := range provider.FlavorAliases {
+		if flavor == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// providerForFlavor returns the registered provider (either enabled or disabled) supporting the flavor.
+// The caller has to hold the lock.
+func (r *Registry) providerForFlavor(flavor string) *Provider {
+	for _, provider := range r.providers {
+		if matchesFlavor(provider, flavor) {
+			return provider
+		}
+	}
+	return nil
+}
+
+// withFlavor returns the GitSource with the flavor set to the name of the provider, so the services of the provider
+// match the GitSource even if it uses an alias of the flavor or a host mapped to the provider
+func withFlavor(gitSource *v1alpha1.GitSource, flavor string) *v1alpha1.GitSource {
+	if gitSource.Spec.Flavor == flavor {
+		return gitSource
+	}
+	withFlavor := gitSource.DeepCopy()
+	withFlavor.Spec.Flavor = flavor
+	return withFlavor
+}
@@ -0,0 +1,170 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRegistry(t *testing.T) *repository.Registry {
+	registry := repository.NewRegistry()
+	require.NoError(t, registry.Register(repository.Provider{
+		Name:           "github",
+		Priority:       20,
+		Hosts:          []string{"github.com", "*.github.example.com"},
+		ServiceCreator: ghServiceCreator,
+	}))
+	require.NoError(t, registry.Register(repository.Provider{
+		Name:           "gitlab",
+		Priority:       10,
+		Hosts:          []string{"gitlab.com"},
+		FlavorAliases:  []string{"gitlab-ee"},
+		ServiceCreator: glServiceCreator,
+	}))
+	return registry
+}
+
+func TestRegistryUsesProviderMatchingHost(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+
+	for url, expected := range map[string]string{
+		"https://github.com/some-org/some-repo":                 "github",
+		"git@GitHub.com:some-org/some-repo.git":                 "github",
+		"https://git.github.example.com/some-org/some-repo.git": "github",
+		"https://gitlab.com/group/subgroup/some-repo":           "gitlab"} {
+
+		source := test.NewGitSource(test.WithURL(url))
+
+		// when
+		service, err := repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+
+		// then
+		require.NoError(t, err, url)
+		require.NotNil(t, service, url)
+		checker, err := service.FileExistenceChecker(context.Background())
+		require.NoError(t, err, url)
+		assert.Equal(t, []string{expected}, checker.GetListOfFoundFiles(), url)
+	}
+	assert.Equal(t, "github", registry.FlavorForHost("git.github.example.com"))
+	assert.Equal(t, "gitlab", registry.FlavorForHost("gitlab.com"))
+	assert.Empty(t, registry.FlavorForHost("git.example.com"))
+}
+
+func TestRegistryUsesProviderMatchingFlavorAlias(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+	source := test.NewGitSource(test.WithURL("https://git.example.com/some-org/some-repo"), test.WithFlavor("gitlab-ee"))
+
+	// when
+	service, err := repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, service)
+	checker, err := service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gitlab"}, checker.GetListOfFoundFiles())
+	assert.Equal(t, "gitlab-ee", source.Spec.Flavor)
+	assert.True(t, registry.IsKnownFlavor("gitlab-ee"))
+}
+
+func TestRegistryUsesProviderOfAddedHost(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+	require.NoError(t, registry.AddHost("Git.Example.com", "gitlab-ee"))
+	source := test.NewGitSource(test.WithURL("https://git.example.com/some-org/some-repo"))
+
+	// when
+	service, err := repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, service)
+	checker, err := service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gitlab"}, checker.GetListOfFoundFiles())
+	assert.Equal(t, "gitlab", registry.FlavorForHost("git.example.com"))
+}
+
+func TestRegistryOrdersProvidersByPriority(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+	require.NoError(t, registry.Register(repository.Provider{
+		Name:           "bitbucket",
+		Priority:       30,
+		ServiceCreator: bbServiceCreator,
+	}))
+	source := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("bitbucket"))
+
+	// when
+	service, err := repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, service)
+	checker, err := service.FileExistenceChecker(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bitbucket"}, checker.GetListOfFoundFiles())
+}
+
+func TestRegistryDoesNotUseDisabledProvider(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+	source := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))
+
+	// when
+	err := registry.Disable("github")
+
+	// then
+	require.NoError(t, err)
+	service, err := repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+	require.NoError(t, err)
+	assert.Nil(t, service)
+	assert.False(t, registry.IsKnownFlavor("github"))
+	assert.Empty(t, registry.FlavorForHost("github.com"))
+
+	// and when
+	err = registry.Enable("github")
+
+	// then
+	require.NoError(t, err)
+	service, err = repository.NewGitService(logger, source, nil, registry.ServiceCreators())
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+	assert.True(t, registry.IsKnownFlavor("github"))
+}
+
+func TestRegistryCreatesMetadataServicesUsingServiceCreators(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+	source := test.NewGitSource(test.WithURL("https://gitlab.com/some-org/some-repo"))
+
+	// when
+	service, err := repository.NewMetadataService(logger, source, nil, registry.MetadataServiceCreators())
+
+	// then
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+}
+
+func TestRegistryRejectsInvalidConfiguration(t *testing.T) {
+	// given
+	registry := newTestRegistry(t)
+
+	// then
+	assert.Error(t, registry.Register(repository.Provider{Name: "gitea"}))
+	assert.Error(t, registry.Register(repository.Provider{Name: "gitlab-ee", ServiceCreator: glServiceCreator}))
+	assert.Error(t, registry.Register(repository.Provider{
+		Name:           "other",
+		FlavorAliases:  []string{"github"},
+		ServiceCreator: ghServiceCreator,
+	}))
+	assert.Error(t, registry.Disable("github", "gitfoo"))
+	assert.True(t, registry.IsKnownFlavor("github"))
+	assert.Error(t, registry.AddHost("git.example.com", "gitfoo"))
+}
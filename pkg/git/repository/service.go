@@ -1,30 +1,45 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
 	"github.com/redhat-developer/devconsole-git/pkg/git/detector/build"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 )
 
+// GitService communicates with the git server. All calls made to the server are bound to the given context
+// so they are cancelled when its deadline is exceeded.
 type GitService interface {
 	// FileExistenceChecker returns an instance of checker for existence of files in the root directory
-	FileExistenceChecker() (FileExistenceChecker, error)
+	FileExistenceChecker(ctx context.Context) (FileExistenceChecker, error)
 	// GetLanguageList returns list of detected languages in the sorted order where the first one is the most used
-	GetLanguageList() ([]string, error)
+	GetLanguageList(ctx context.Context) ([]string, error)
 	// CheckCredentials tries to get user  information associated with the attached secret from the git server
-	CheckCredentials() error
+	CheckCredentials(ctx context.Context) error
 	// Tries to connect to the git repository with the attached secret
-	CheckRepoAccessibility() error
+	CheckRepoAccessibility(ctx context.Context) error
 	// Checks if the branch exists in the git repository
-	CheckBranch() error
+	CheckBranch(ctx context.Context) error
+	// CheckPermissions returns the permissions required by the operator that the attached secret doesn't grant.
+	// If the permissions cannot be determined, then an empty list is returned.
+	CheckPermissions(ctx context.Context) ([]MissingPermission, error)
+	// ListBranches returns the requested page of names of branches in the repository sorted by the git server
+	ListBranches(ctx context.Context, options RefListOptions) (*RefList, error)
+	// ListTags returns the requested page of names of tags in the repository sorted by the git server
+	ListTags(ctx context.Context, options RefListOptions) (*RefList, error)
+	// GetFileContent returns the content of the file with the given path relative to the root directory of the branch.
+	// FileNotFoundError is returned if the file doesn't exist and FileTooLargeError if it is larger than maxSize
+	// (DefaultMaxFileSize if not positive).
+	GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error)
 }
 
 type FileExistenceChecker interface {
 	// GetListOfFoundFiles returns list of filenames present in the root directory
 	GetListOfFoundFiles() []string
 	// DetectFiles detects if any of the build tool files are present in the root directory
-	DetectFiles(buildTool build.Tool) []string
+	DetectFiles(ctx context.Context, buildTool build.Tool) []string
 }
 
 // ServiceCreator creates an instance of GitService for the given v1alpha1.GitSource
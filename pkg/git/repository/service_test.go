@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
 	"github.com/redhat-developer/devconsole-git/pkg/log"
 	"github.com/redhat-developer/devconsole-git/pkg/test"
@@ -27,7 +28,7 @@ func TestNewServiceReturnsCorrectService(t *testing.T) {
 	// then
 	require.NoError(t, err)
 	require.NotNil(t, service)
-	checker, _ := service.FileExistenceChecker()
+	checker, _ := service.FileExistenceChecker(context.Background())
 	assert.Equal(t, "bitbucket", checker.GetListOfFoundFiles()[0])
 }
 
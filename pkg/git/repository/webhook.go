@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/log"
+)
+
+// WebhookService manages the webhooks notifying about pushes to the git repository
+type WebhookService interface {
+	// CreateWebhook registers a webhook sending push events to the given URL signed (or authenticated) using the given
+	// secret and returns its ID
+	CreateWebhook(ctx context.Context, url, secret string) (string, error)
+	// FindWebhook returns the ID of a webhook sending events to the given URL or an empty string if there is none
+	FindWebhook(ctx context.Context, url string) (string, error)
+	// WebhookExists returns true if the webhook with the given ID is still registered in the repository
+	WebhookExists(ctx context.Context, id string) (bool, error)
+	// DeleteWebhook removes the webhook with the given ID. It doesn't fail if the webhook doesn't exist anymore.
+	DeleteWebhook(ctx context.Context, id string) error
+}
+
+// WebhookServiceCreator creates an instance of WebhookService for the given v1alpha1.GitSource
+type WebhookServiceCreator func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider) (WebhookService, error)
+
+// NewWebhookServiceCreator returns a WebhookServiceCreator that uses the given ServiceCreator.
+// The created GitService is used if it manages webhooks, nil is returned otherwise.
+func NewWebhookServiceCreator(creator ServiceCreator) WebhookServiceCreator {
+	return func(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider) (WebhookService, error) {
+		service, err := creator(log, gitSource, secret)
+		if err != nil || service == nil {
+			return nil, err
+		}
+		if webhookService, ok := service.(WebhookService); ok {
+			return webhookService, nil
+		}
+		return nil, nil
+	}
+}
+
+// NewWebhookService returns an instance of WebhookService for the given v1alpha1.GitSource. If no service is matched then returns nil
+func NewWebhookService(log *log.GitSourceLogger, gitSource *v1alpha1.GitSource, secret *git.SecretProvider,
+	serviceCreators []WebhookServiceCreator) (WebhookService, error) {
+
+	for _, creator := range serviceCreators {
+		service, err := creator(log, gitSource, secret)
+		if err != nil {
+			return nil, err
+		}
+		if service != nil {
+			return service, nil
+		}
+	}
+	return nil, nil
+}
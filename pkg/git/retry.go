@@ -0,0 +1,218 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy defines how many times and how long to wait before a failed request is sent again
+type RetryPolicy struct {
+	// MaxRetries is the maximal number of retries of a single request
+	MaxRetries int
+	// InitialBackoff is the time to wait before the first retry - it is doubled with every other retry
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximal time to wait between two retries of a failed request
+	MaxBackoff time.Duration
+	// MaxRateLimitWait is the maximal time to wait for a reset of an exhausted rate limit. If the limit is reset
+	// later, then a RateLimitError is returned instead of waiting.
+	MaxRateLimitWait time.Duration
+}
+
+// NewDefaultRetryPolicy returns a RetryPolicy with the values used for all connections to git servers
+func NewDefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxRetries:       3,
+		InitialBackoff:   500 * time.Millisecond,
+		MaxBackoff:       10 * time.Second,
+		MaxRateLimitWait: 10 * time.Second,
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 0; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// RateLimitError is returned when the rate limit of the git server is exhausted and it is not reset soon enough
+type RateLimitError struct {
+	// Reset is the time when the rate limit is going to be reset
+	Reset   time.Time
+	message string
+}
+
+// NewRateLimitError creates a RateLimitError with the given time of the reset and the message describing the limit
+func NewRateLimitError(reset time.Time, message string) *RateLimitError {
+	return &RateLimitError{Reset: reset, message: message}
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s, it will be reset at %s", e.message, e.Reset.Format(time.RFC3339))
+}
+
+// AsRateLimitError returns the RateLimitError if the given error is or wraps one, nil otherwise
+func AsRateLimitError(err error) *RateLimitError {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if rateLimitErr, ok := err.(*RateLimitError); ok {
+		return rateLimitErr
+	}
+	return nil
+}
+
+// retryingRoundTripper sends the idempotent requests again when they fail because of a transient error or because
+// of an exhausted rate limit that is reset soon enough
+type retryingRoundTripper struct {
+	base   http.RoundTripper
+	policy *RetryPolicy
+}
+
+func (t *retryingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	retryable := isIdempotent(req)
+	for attempt := 0; ; attempt++ {
+		canRetry := retryable && attempt < t.policy.MaxRetries
+		resp, err := base.RoundTrip(req)
+		if err != nil {
+			if !canRetry || req.Context().Err() != nil || !isTransientError(err) {
+				return nil, err
+			}
+			if err := sleep(req.Context(), t.policy.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if reset, limited := rateLimitReset(resp, t.policy.backoff(attempt)); limited {
+			discard(resp)
+			wait := reset.Sub(time.Now())
+			if !canRetry || wait > t.policy.MaxRateLimitWait || exceedsDeadline(req.Context(), wait) {
+				return nil, NewRateLimitError(reset, fmt.Sprintf("rate limit exceeded (%s)", resp.Status))
+			}
+			if err := sleep(req.Context(), wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if canRetry && isTransientStatus(resp.StatusCode) {
+			discard(resp)
+			if err := sleep(req.Context(), t.policy.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return resp, nil
+	}
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+func isTransientError(err error) bool {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if netErr, ok := err.(net.Error); ok && (netErr.Temporary() || netErr.Timeout()) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "connection reset by peer") || strings.Contains(message, "broken pipe")
+}
+
+func isTransientStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rateLimitReset returns the time when the rate limit is reset if the response says that the limit is exhausted.
+// GitHub responds with 403 and X-RateLimit-Remaining set to 0 (or with Retry-After when an abuse limit is hit),
+// GitLab and Bitbucket respond with 429 and optionally with Retry-After or RateLimit-Reset headers.
+func rateLimitReset(resp *http.Response, defaultWait time.Duration) (time.Time, bool) {
+	now := time.Now()
+	retryAfter, hasRetryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusForbidden &&
+		(hasRetryAfter || remainingHeader(resp.Header) == "0"):
+	default:
+		return time.Time{}, false
+	}
+	if hasRetryAfter {
+		return retryAfter, true
+	}
+	for _, header := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		if epoch, err := strconv.ParseInt(resp.Header.Get(header), 10, 64); err == nil {
+			return time.Unix(epoch, 0), true
+		}
+	}
+	return now.Add(defaultWait), true
+}
+
+func remainingHeader(header http.Header) string {
+	if remaining := header.Get("X-RateLimit-Remaining"); remaining != "" {
+		return remaining
+	}
+	return header.Get("RateLimit-Remaining")
+}
+
+// parseRetryAfter parses value of the Retry-After header that contains either number of seconds or a HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+func exceedsDeadline(ctx context.Context, wait time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Now().Add(wait).After(deadline)
+}
+
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// discard reads the rest of the body so the connection can be reused
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,168 @@
+package git_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetries = &git.RetryPolicy{
+	MaxRetries:       3,
+	InitialBackoff:   time.Millisecond,
+	MaxBackoff:       10 * time.Millisecond,
+	MaxRateLimitWait: time.Second,
+}
+
+func TestRetryingTransportRetriesTransientFailures(t *testing.T) {
+	// given
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	resp, err := client.Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestRetryingTransportGivesUpAfterMaxRetries(t *testing.T) {
+	// given
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	resp, err := client.Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestRetryingTransportDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	// given
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader("body"))
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestRetryingTransportWaitsForRateLimitReset(t *testing.T) {
+	// given
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// GitHub way of saying that the rate limit is exhausted
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	resp, err := client.Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestRetryingTransportReturnsRateLimitErrorWhenResetIsTooFar(t *testing.T) {
+	// given
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	_, err := client.Get(server.URL)
+
+	// then
+	require.Error(t, err)
+	rateLimitErr := git.AsRateLimitError(err)
+	require.NotNil(t, rateLimitErr)
+	assert.True(t, reset.Equal(rateLimitErr.Reset))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestRetryingTransportReturnsRateLimitErrorWhenResetExceedsDeadline(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	// when
+	_, err = client.Do(req.WithContext(ctx))
+
+	// then
+	require.Error(t, err)
+	assert.NotNil(t, git.AsRateLimitError(err))
+}
+
+func TestAsRateLimitErrorOfOtherErrors(t *testing.T) {
+	// given
+	client := git.NewTransportSettings().WithRetryPolicy(fastRetries).HTTPClient()
+
+	// when
+	_, err := client.Get("http://localhost:0")
+
+	// then
+	require.Error(t, err)
+	assert.Nil(t, git.AsRateLimitError(err))
+}
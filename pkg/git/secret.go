@@ -5,9 +5,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/config"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
 	"k8s.io/apimachinery/pkg/types"
-	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"golang.org/x/crypto/ssh"
@@ -20,20 +22,45 @@ import (
 )
 
 type SecretProvider struct {
-	secret Secret
+	secret     Secret
+	settings   *TransportSettings
+	secretName string
 }
 
 func NewSecretProvider(secret Secret) *SecretProvider {
 	return &SecretProvider{secret: secret}
 }
 
+// NewSecretProviderWithSettings returns a SecretProvider that applies the given transport settings
+// to all secrets it provides
+func NewSecretProviderWithSettings(secret Secret, settings *TransportSettings) *SecretProvider {
+	if secret != nil {
+		secret.SetTransportSettings(settings)
+	}
+	return &SecretProvider{secret: secret, settings: settings}
+}
+
 func (p *SecretProvider) GetSecret(defaultSecret Secret) Secret {
 	if p.secret == nil {
+		if defaultSecret != nil && p.settings != nil {
+			defaultSecret.SetTransportSettings(p.settings)
+		}
 		return defaultSecret
 	}
 	return p.secret
 }
 
+// TransportSettings returns settings of the connections that should be used together with the provided secrets
+func (p *SecretProvider) TransportSettings() *TransportSettings {
+	return p.settings
+}
+
+// SecretName returns the name of the secret the credentials or the transport settings were read from - either
+// the one referenced by the GitSource or the one matching its URL. Returns an empty string if no secret is used.
+func (p *SecretProvider) SecretName() string {
+	return p.secretName
+}
+
 func (p *SecretProvider) SecretType() string {
 	if p.secret == nil {
 		return ""
@@ -50,11 +77,14 @@ type Secret interface {
 	SecretType() string
 	// SecretContent returns an actual content of the secret
 	SecretContent() string
+	// SetTransportSettings sets settings of the connections made using the secret
+	SetTransportSettings(settings *TransportSettings)
 }
 
 type commonSecretInfo struct {
 	secretType    string
 	secretContent []byte
+	settings      *TransportSettings
 }
 
 func (k *commonSecretInfo) SecretType() string {
@@ -65,6 +95,20 @@ func (k *commonSecretInfo) SecretContent() string {
 	return string(k.secretContent)
 }
 
+// SetTransportSettings sets settings of the connections made using the secret. If no host key policy is set,
+// then all ssh host keys are rejected.
+func (k *commonSecretInfo) SetTransportSettings(settings *TransportSettings) {
+	k.settings = settings
+}
+
+func (k *commonSecretInfo) hostKeyCallbackHelper() (gitssh.HostKeyCallbackHelper, error) {
+	callback, err := k.settings.HostKeyPolicy().HostKeyCallback()
+	if err != nil {
+		return gitssh.HostKeyCallbackHelper{}, err
+	}
+	return gitssh.HostKeyCallbackHelper{HostKeyCallback: callback}, nil
+}
+
 const (
 	SshKeyType           = "SshKey"
 	OauthTokenType       = "OauthToken"
@@ -86,10 +130,6 @@ func NewSshKey(sshKey []byte, passphrase []byte) *SshKey {
 	}
 }
 
-var allowAll = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
-	return nil
-}
-
 func (k *SshKey) GitAuthMethod() (transport.AuthMethod, error) {
 	var signer ssh.Signer
 	var err error
@@ -105,12 +145,15 @@ func (k *SshKey) GitAuthMethod() (transport.AuthMethod, error) {
 		}
 	}
 
+	callbackHelper, err := k.hostKeyCallbackHelper()
+	if err != nil {
+		return nil, err
+	}
+
 	return &gitssh.PublicKeys{
-		User:   "git",
-		Signer: signer,
-		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
-			HostKeyCallback: allowAll,
-		},
+		User:                  "git",
+		Signer:                signer,
+		HostKeyCallbackHelper: callbackHelper,
 	}, nil
 }
 
@@ -120,6 +163,8 @@ func (k *SshKey) Client() *http.Client {
 
 type OauthToken struct {
 	*commonSecretInfo
+	// refresher is set only if the token can be refreshed
+	refresher *tokenRefresher
 }
 
 func NewOauthToken(token []byte) *OauthToken {
@@ -131,19 +176,48 @@ func NewOauthToken(token []byte) *OauthToken {
 	}
 }
 
+// SecretContent returns the current access token. An expired token is not refreshed by this method.
+func (t *OauthToken) SecretContent() string {
+	if t.refresher != nil {
+		return t.refresher.accessToken()
+	}
+	return string(t.secretContent)
+}
+
+// IsRefreshable returns true if the token is refreshed when it expires
+func (t *OauthToken) IsRefreshable() bool {
+	return t.refresher != nil
+}
+
 func (t *OauthToken) GitAuthMethod() (transport.AuthMethod, error) {
+	callbackHelper, err := t.hostKeyCallbackHelper()
+	if err != nil {
+		return nil, err
+	}
+	accessToken := string(t.secretContent)
+	if t.refresher != nil {
+		token, err := t.refresher.token(t.settings.HTTPClient())
+		if err != nil {
+			return nil, err
+		}
+		accessToken = token.AccessToken
+	}
 	return &gitssh.Password{
-		Password: string(t.secretContent),
-		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
-			HostKeyCallback: allowAll,
-		}}, nil
+		Password:              accessToken,
+		HostKeyCallbackHelper: callbackHelper,
+	}, nil
 }
 
+// Client returns a client sending the token in the Authorization header. The refreshable tokens are refreshed
+// by the client when they expire.
 func (t *OauthToken) Client() *http.Client {
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: string(t.secretContent)},
-	)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, t.settings.HTTPClient())
+	var ts oauth2.TokenSource = &refreshingTokenSource{oauthToken: t}
+	if t.refresher == nil {
+		ts = oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: string(t.secretContent)},
+		)
+	}
 	return oauth2.NewClient(ctx, ts)
 
 }
@@ -166,16 +240,19 @@ func NewUsernamePassword(username, password string) *UsernamePassword {
 }
 
 func (t *UsernamePassword) GitAuthMethod() (transport.AuthMethod, error) {
+	callbackHelper, err := t.hostKeyCallbackHelper()
+	if err != nil {
+		return nil, err
+	}
 	return &gitssh.Password{
-		User:     t.username,
-		Password: t.password,
-		HostKeyCallbackHelper: gitssh.HostKeyCallbackHelper{
-			HostKeyCallback: allowAll,
-		}}, nil
+		User:                  t.username,
+		Password:              t.password,
+		HostKeyCallbackHelper: callbackHelper,
+	}, nil
 }
 
 func (t *UsernamePassword) Client() *http.Client {
-	return &http.Client{}
+	return t.settings.HTTPClient()
 }
 
 func ParseUsernameAndPassword(secret string) (string, string) {
@@ -187,13 +264,54 @@ func ParseUsernameAndPassword(secret string) (string, string) {
 }
 
 // NewGitSecretProvider retrieves secret using the given client
-// and stores it to a new instance of GitSecretProvider that is then returned
+// and stores it to a new instance of GitSecretProvider that is then returned.
+// If the GitSource doesn't reference any secret, then the secret whose source-secret-match-uri annotation
+// matches the URL of the GitSource is used (if there is any).
+// The provider applies the transport settings defined in the secret and in the cluster-wide configuration.
 func NewGitSecretProvider(client client.Client, namespace string, gitSource *v1alpha1.GitSource) (*SecretProvider, error) {
-	secret, err := NewGitSecret(client, namespace, gitSource)
+	clusterConfig, err := config.LoadCluster(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the cluster configuration: %s", err)
+	}
+	var coreSecret *corev1.Secret
+	if gitSource.Spec.SecretRef == nil {
+		coreSecret, err = findMatchingSecret(client, namespace, gitSource)
+		if err != nil {
+			return nil, err
+		}
+		if coreSecret == nil || !hasCredentials(coreSecret) {
+			// the matching secret can hold only a CA bundle or the known hosts - the repository is accessed
+			// anonymously using the transport settings of such a secret
+			settings, err := newTransportSettings(clusterConfig, coreSecret, gitSource)
+			if err != nil {
+				return nil, err
+			}
+			secretProvider := NewSecretProviderWithSettings(nil, settings)
+			if coreSecret != nil {
+				secretProvider.secretName = coreSecret.Name
+			}
+			return secretProvider, nil
+		}
+	} else {
+		coreSecret = &corev1.Secret{}
+		namespacedSecretName := types.NamespacedName{Namespace: namespace, Name: gitSource.Spec.SecretRef.Name}
+		err = client.Get(context.TODO(), namespacedSecretName, coreSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the secret object")
+		}
+	}
+
+	secret, err := newSecret(client, coreSecret, gitSource)
+	if err != nil {
+		return nil, err
+	}
+	settings, err := newTransportSettings(clusterConfig, coreSecret, gitSource)
 	if err != nil {
 		return nil, err
 	}
-	return NewSecretProvider(secret), nil
+	secretProvider := NewSecretProviderWithSettings(secret, settings)
+	secretProvider.secretName = coreSecret.Name
+	return secretProvider, nil
 }
 
 // NewGitSecret retrieves a secret using the given client
@@ -201,26 +319,127 @@ func NewGitSecret(client client.Client, namespace string, gitSource *v1alpha1.Gi
 	if gitSource.Spec.SecretRef == nil {
 		return nil, nil
 	}
-	coreSecret := &corev1.Secret{}
-	namespacedSecretName := types.NamespacedName{Namespace: namespace, Name: gitSource.Spec.SecretRef.Name}
-	err := client.Get(context.TODO(), namespacedSecretName, coreSecret)
+	secretProvider, err := NewGitSecretProvider(client, namespace, gitSource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch the secret object")
+		return nil, err
 	}
+	return secretProvider.secret, nil
+}
 
+func newSecret(client client.Client, coreSecret *corev1.Secret, gitSource *v1alpha1.GitSource) (Secret, error) {
+	if appID := string(coreSecret.Data[GitHubAppIDKey]); appID != "" {
+		return newGitHubApp(coreSecret, gitSource)
+	}
 	username := string(coreSecret.Data[corev1.BasicAuthUsernameKey])
 	password := string(coreSecret.Data[corev1.BasicAuthPasswordKey])
 	sshKey := string(coreSecret.Data[corev1.SSHAuthPrivateKey])
-	var secret Secret
 	if username != "" {
-		secret = NewUsernamePassword(username, password)
+		return NewUsernamePassword(username, password), nil
+	} else if len(coreSecret.Data[OauthRefreshTokenKey]) > 0 {
+		return newRefreshableOauthToken(client, coreSecret, gitSource)
 	} else if password != "" {
-		secret = NewOauthToken([]byte(password))
+		return NewOauthToken([]byte(password)), nil
 	} else if sshKey != "" {
-		secret = NewSshKey([]byte(sshKey), coreSecret.Data["passphrase"])
+		return NewSshKey([]byte(sshKey), coreSecret.Data["passphrase"]), nil
+	}
+	return nil, fmt.Errorf("the provided secret does not contain any of the required parameters: [%s,%s,%s] or they are empty",
+		corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey, corev1.SSHAuthPrivateKey)
+}
+
+// hasCredentials returns true if the secret contains any of the parameters newSecret creates the credentials from
+func hasCredentials(coreSecret *corev1.Secret) bool {
+	for _, key := range []string{GitHubAppIDKey, corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey,
+		corev1.SSHAuthPrivateKey, OauthRefreshTokenKey} {
+		if len(coreSecret.Data[key]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// newGitHubApp creates a GitHub App secret for the repository of the given GitSource
+func newGitHubApp(coreSecret *corev1.Secret, gitSource *v1alpha1.GitSource) (*GitHubApp, error) {
+	appID, err := strconv.ParseInt(string(coreSecret.Data[GitHubAppIDKey]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("the ID of the GitHub App is not a number: %s", err)
+	}
+	privateKey := coreSecret.Data[GitHubAppPrivateKeyKey]
+	if len(bytes.TrimSpace(privateKey)) == 0 {
+		return nil, fmt.Errorf("the provided secret does not contain the private key of the GitHub App: %s",
+			GitHubAppPrivateKeyKey)
+	}
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	if err != nil {
+		return nil, err
+	}
+	owner, name, err := gitURL.OwnerAndName()
+	if err != nil {
+		return nil, err
+	}
+	app := NewGitHubApp(appID, privateKey, owner+"/"+name)
+	if installationID := string(coreSecret.Data[GitHubAppInstallationIDKey]); installationID != "" {
+		id, err := strconv.ParseInt(installationID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("the ID of the installation of the GitHub App is not a number: %s", err)
+		}
+		app.WithInstallationID(id)
+	}
+	if apiURL := string(coreSecret.Data[GitHubAPIURLKey]); apiURL != "" {
+		app.WithAPIURL(apiURL)
+	}
+	return app, nil
+}
+
+// newTransportSettings creates settings from the cluster-wide configuration, the given secret (if not nil)
+// and the annotations of the given GitSource. The values defined in the secret and the GitSource take precedence.
+func newTransportSettings(clusterConfig *config.Cluster, coreSecret *corev1.Secret, gitSource *v1alpha1.GitSource) (*TransportSettings, error) {
+	var secretData map[string][]byte
+	if coreSecret != nil {
+		secretData = coreSecret.Data
+	}
+	settings := NewTransportSettings()
+
+	if string(secretData[InsecureIgnoreHostKeyKey]) == "true" {
+		settings.WithHostKeyPolicy(NewInsecureHostKeyPolicy())
+	} else if knownHosts := secretData[KnownHostsKey]; len(knownHosts) > 0 {
+		settings.WithHostKeyPolicy(NewKnownHostsPolicy(knownHosts))
 	} else {
-		return nil, fmt.Errorf("the provided secret does not contain any of the required parameters: [%s,%s,%s] or they are empty",
-			corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey, corev1.SSHAuthPrivateKey)
+		settings.WithHostKeyPolicy(NewKnownHostsPolicy(clusterConfig.KnownHosts()))
+	}
+
+	for _, caBundle := range [][]byte{clusterConfig.CABundle(), secretData[CABundleKey]} {
+		if len(bytes.TrimSpace(caBundle)) > 0 {
+			if _, err := settings.WithCABundle(caBundle); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if string(secretData[InsecureSkipTLSVerifyKey]) == "true" || clusterConfig.InsecureSkipTLSVerify() {
+		settings.WithInsecureSkipTLSVerify()
+	}
+
+	proxy := &ProxyConfig{
+		HTTPProxy:  annotationOrDefault(gitSource, HTTPProxyAnnotation, clusterConfig.HTTPProxy()),
+		HTTPSProxy: annotationOrDefault(gitSource, HTTPSProxyAnnotation, clusterConfig.HTTPSProxy()),
+		NoProxy:    annotationOrDefault(gitSource, NoProxyAnnotation, clusterConfig.NoProxy()),
+	}
+	if err := proxy.Validate(); err != nil {
+		return nil, err
+	}
+	settings.WithProxy(proxy)
+
+	operationTimeout, err := clusterConfig.OperationTimeout()
+	if err != nil {
+		return nil, err
+	}
+	settings.WithOperationTimeout(operationTimeout)
+	settings.WithRetryPolicy(NewDefaultRetryPolicy())
+	return settings, nil
+}
+
+func annotationOrDefault(gitSource *v1alpha1.GitSource, annotation, defaultValue string) string {
+	if value, ok := gitSource.Annotations[annotation]; ok {
+		return value
 	}
-	return secret, nil
+	return defaultValue
 }
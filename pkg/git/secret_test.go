@@ -283,3 +283,121 @@ func TestGetGitSecretWithSshKey(t *testing.T) {
 		assert.NotEmpty(t, secret.SecretContent())
 	}
 }
+
+func TestGetGitSecretWithKnownHosts(t *testing.T) {
+	//given
+	sec := test.NewSecret(corev1.SecretTypeSSHAuth, map[string][]byte{
+		"ssh-privatekey": test.PrivateWithoutPassphrase(t, pathToTestDir),
+		"known_hosts":    test.KnownHosts()})
+
+	gs := test.NewGitSource()
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secret, err := git.NewGitSecret(client, test.Namespace, gs)
+
+	//then
+	require.NoError(t, err)
+	authMethod, err := secret.GitAuthMethod()
+	require.NoError(t, err)
+	keys, ok := authMethod.(*gitssh.PublicKeys)
+	require.True(t, ok)
+	_, _, hostKey, _, _, err := ssh.ParseKnownHosts(test.KnownHosts())
+	require.NoError(t, err)
+	assert.NoError(t, keys.HostKeyCallback("localhost:2222", testServerAddr, hostKey))
+	_, _, otherKey, _, _, err := ssh.ParseKnownHosts(test.MismatchingKnownHosts())
+	require.NoError(t, err)
+	assert.Error(t, keys.HostKeyCallback("localhost:2222", testServerAddr, otherKey))
+}
+
+func TestGetGitSecretWithCABundle(t *testing.T) {
+	//given
+	server := test.RunTLSServer(okHandler)
+	defer server.Close()
+	sec := test.NewSecret(corev1.SecretTypeBasicAuth, map[string][]byte{
+		"password": []byte("some-token"),
+		"ca.crt":   test.CACert(server)})
+
+	gs := test.NewGitSource()
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	require.NoError(t, err)
+	resp, err := secretProvider.GetSecret(defaultToken).Client().Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	resp, err = secretProvider.TransportSettings().HTTPClient().Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestGetGitSecretWithInvalidCABundle(t *testing.T) {
+	//given
+	sec := test.NewSecret(corev1.SecretTypeBasicAuth, map[string][]byte{
+		"password": []byte("some-token"),
+		"ca.crt":   []byte("not a certificate")})
+
+	gs := test.NewGitSource()
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	assert.Error(t, err)
+	assert.Nil(t, secretProvider)
+}
+
+func TestGetGitSecretWithProxyAnnotations(t *testing.T) {
+	//given
+	proxy := test.RunProxy()
+	defer proxy.Close()
+	server := test.RunTLSServer(okHandler)
+	defer server.Close()
+	sec := test.NewSecret(corev1.SecretTypeBasicAuth, map[string][]byte{
+		"password": []byte("some-token"),
+		"ca.crt":   test.CACert(server)})
+
+	gs := test.NewGitSource(
+		test.WithAnnotation(git.HTTPSProxyAnnotation, proxy.URL),
+		test.WithAnnotation(git.NoProxyAnnotation, "internal.example.com"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	client, _ := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, sec))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	require.NoError(t, err)
+	resp, err := secretProvider.GetSecret(defaultToken).Client().Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, 1, proxy.Requests())
+}
+
+func TestGetGitSecretWithInvalidProxyAnnotation(t *testing.T) {
+	//given
+	gs := test.NewGitSource(test.WithAnnotation(git.HTTPSProxyAnnotation, "socks5://proxy:1080"))
+	client, _ := test.PrepareClient(test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))
+
+	//when
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+
+	//then
+	assert.Error(t, err)
+	assert.Nil(t, secretProvider)
+}
@@ -0,0 +1,179 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SourceSecretMatchURIAnnotationPrefix is a prefix of annotations of secrets holding patterns of the repository
+	// URLs the secret should be used for when a GitSource doesn't reference any secret. It is the same annotation
+	// OpenShift uses to pick the source secrets of BuildConfigs, e.g.:
+	// build.openshift.io/source-secret-match-uri-1: https://*.example.com/*
+	SourceSecretMatchURIAnnotationPrefix = "build.openshift.io/source-secret-match-uri-"
+	// MatchedSecretAnnotation is an annotation of GitSource holding the name of the secret matched by its URL
+	// (if the GitSource doesn't reference any secret). The annotation is used until the status of GitSource
+	// contains such a field.
+	MatchedSecretAnnotation = "devconsole.openshift.io/matched-source-secret"
+	// BuilderServiceAccount is the service account running the builds - the secrets linked to it are preferred
+	// if more secrets match the URL equally
+	BuilderServiceAccount = "builder"
+)
+
+// the patterns have the form scheme://host/path where the scheme can be *, the host can be * or start with *.
+// and the path can contain * matching any characters
+var uriPatternRegexp = regexp.MustCompile(`^(\*|git|http|https|ssh)://(\*|(?:\*\.)?[^@/*]+)(/.*)$`)
+
+// uriPattern is a parsed pattern of the source-secret-match-uri annotation
+type uriPattern struct {
+	secret string
+	scheme string
+	host   string
+	path   string
+	// linked is true if the secret is linked to the builder service account
+	linked bool
+	hostRe *regexp.Regexp
+	pathRe *regexp.Regexp
+}
+
+func newURIPattern(secret, pattern string, linked bool) (*uriPattern, error) {
+	match := uriPatternRegexp.FindStringSubmatch(pattern)
+	if match == nil {
+		return nil, fmt.Errorf("invalid URI pattern %s", pattern)
+	}
+	p := &uriPattern{
+		secret: secret,
+		scheme: match[1],
+		host:   strings.ToLower(match[2]),
+		path:   match[3],
+		linked: linked,
+	}
+	switch {
+	case p.host == "*":
+		p.hostRe = regexp.MustCompile(`^.*$`)
+	case strings.HasPrefix(p.host, "*."):
+		p.hostRe = regexp.MustCompile(`^[^/@]+\.` + regexp.QuoteMeta(p.host[2:]) + `$`)
+	default:
+		p.hostRe = regexp.MustCompile(`^` + regexp.QuoteMeta(p.host) + `$`)
+	}
+	p.pathRe = regexp.MustCompile(`^` + strings.Replace(regexp.QuoteMeta(p.path), `\*`, `.*`, -1) + `$`)
+	return p, nil
+}
+
+// matches returns true if the pattern matches the repository URL. The path is matched both with and without
+// the .git suffix.
+func (p *uriPattern) matches(gitURL *giturl.URL) bool {
+	if p.scheme != "*" && p.scheme != gitURL.Protocol {
+		return false
+	}
+	host := gitURL.Host
+	if gitURL.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(gitURL.Port))
+	}
+	if !p.hostRe.MatchString(host) {
+		return false
+	}
+	return p.pathRe.MatchString("/"+gitURL.Path) || p.pathRe.MatchString(gitURL.Endpoint().Path)
+}
+
+// hostSpecificity ranks the exact hosts before the wildcard subdomains and those before the * wildcard
+func (p *uriPattern) hostSpecificity() int {
+	switch {
+	case p.host == "*":
+		return 0
+	case strings.HasPrefix(p.host, "*."):
+		return 1
+	}
+	return 2
+}
+
+// moreSpecificThan orders the patterns the same way as OpenShift does - by the host, the length of the path
+// and the scheme. The secrets linked to the builder service account and then the names of the secrets
+// decide if the patterns are equal.
+func (p *uriPattern) moreSpecificThan(other *uriPattern) bool {
+	if p.hostSpecificity() != other.hostSpecificity() {
+		return p.hostSpecificity() > other.hostSpecificity()
+	}
+	if len(p.host) != len(other.host) {
+		return len(p.host) > len(other.host)
+	}
+	if len(p.path) != len(other.path) {
+		return len(p.path) > len(other.path)
+	}
+	if (p.scheme == "*") != (other.scheme == "*") {
+		return other.scheme == "*"
+	}
+	if p.linked != other.linked {
+		return p.linked
+	}
+	return p.secret < other.secret
+}
+
+// findMatchingSecret returns the secret in the namespace whose source-secret-match-uri annotation matches the URL
+// of the GitSource most specifically, nil if there is no such secret. The invalid patterns are ignored.
+func findMatchingSecret(cl client.Client, namespace string, gitSource *v1alpha1.GitSource) (*corev1.Secret, error) {
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	if err != nil || gitURL.Protocol == "file" {
+		return nil, nil
+	}
+	secrets := &corev1.SecretList{}
+	if err := cl.List(context.TODO(), &client.ListOptions{Namespace: namespace}, secrets); err != nil {
+		return nil, fmt.Errorf("failed to list the secrets matching the repository URL: %s", err)
+	}
+	linkedSecrets, err := builderSecrets(cl, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*uriPattern
+	secretsByName := map[string]*corev1.Secret{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		for annotation, value := range secret.Annotations {
+			if !strings.HasPrefix(annotation, SourceSecretMatchURIAnnotationPrefix) {
+				continue
+			}
+			pattern, err := newURIPattern(secret.Name, value, linkedSecrets[secret.Name])
+			if err == nil && pattern.matches(gitURL) {
+				matching = append(matching, pattern)
+				secretsByName[secret.Name] = secret
+			}
+		}
+	}
+	if len(matching) == 0 {
+		return nil, nil
+	}
+	sort.Slice(matching, func(i, j int) bool {
+		return matching[i].moreSpecificThan(matching[j])
+	})
+	return secretsByName[matching[0].secret], nil
+}
+
+// builderSecrets returns the names of the secrets linked to the builder service account (if it exists)
+func builderSecrets(cl client.Client, namespace string) (map[string]bool, error) {
+	serviceAccount := &corev1.ServiceAccount{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: BuilderServiceAccount}, serviceAccount)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the %s service account: %s", BuilderServiceAccount, err)
+	}
+	linked := map[string]bool{}
+	for _, secret := range serviceAccount.Secrets {
+		linked[secret.Name] = true
+	}
+	return linked, nil
+}
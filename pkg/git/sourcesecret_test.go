@@ -0,0 +1,118 @@
+package git_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSecretMatchingURIs(name, token string, patterns ...string) *corev1.Secret {
+	annotations := map[string]string{}
+	for i, pattern := range patterns {
+		annotations[git.SourceSecretMatchURIAnnotationPrefix+strconv.Itoa(i)] = pattern
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   test.Namespace,
+			Annotations: annotations,
+		},
+		Type: corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{"password": []byte(token)},
+	}
+}
+
+func findSecretProvider(t *testing.T, url string, objects ...test.GvkObject) *git.SecretProvider {
+	gs := test.NewGitSource(test.WithURL(url))
+	client, _ := test.PrepareClient(append(objects, test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, gs))...)
+
+	secretProvider, err := git.NewGitSecretProvider(client, test.Namespace, gs)
+	require.NoError(t, err)
+	return secretProvider
+}
+
+func TestGetGitSecretMatchingURLMostSpecifically(t *testing.T) {
+	// given
+	secrets := []test.GvkObject{
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("any-host", "token-1", "*://*/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("subdomain", "token-2", "https://*.example.com/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("host", "token-3",
+			"https://git.example.com/*", "ssh://git.example.com/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("org", "token-4",
+			"*://git.example.com/some-org/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("other-org", "token-5",
+			"https://git.example.com/other-org/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("invalid", "token-6",
+			"git.example.com/some-org/some-repo")),
+	}
+
+	for url, expected := range map[string]string{
+		"https://git.example.com/some-org/some-repo.git": "org",
+		"git@git.example.com:some-org/some-repo.git":     "org",
+		"ssh://git@git.example.com/my-org/some-repo":     "host",
+		"https://git.example.com/my-org/some-repo":       "host",
+		"http://git.example.com/my-org/some-repo":        "any-host",
+		"https://other.git.example.com/my-org/some-repo": "subdomain",
+		"https://github.com/some-org/some-repo":          "any-host"} {
+
+		// when
+		secretProvider := findSecretProvider(t, url, secrets...)
+
+		// then
+		assert.Equal(t, expected, secretProvider.SecretName(), url)
+		assert.Equal(t, git.OauthTokenType, secretProvider.SecretType(), url)
+	}
+}
+
+func TestGetGitSecretMatchingURLPrefersSecretsOfBuilder(t *testing.T) {
+	// given
+	builder := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: test.Namespace},
+		Secrets:    []corev1.ObjectReference{{Name: "linked"}},
+	}
+
+	// when
+	secretProvider := findSecretProvider(t, "https://github.com/some-org/some-repo",
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("a-secret", "token-1", "https://github.com/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("linked", "token-2", "https://github.com/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, builder))
+
+	// then
+	assert.Equal(t, "linked", secretProvider.SecretName())
+	assert.Equal(t, "token-2", secretProvider.GetSecret(nil).SecretContent())
+}
+
+func TestGetGitSecretWithoutMatchingSecret(t *testing.T) {
+	// when
+	secretProvider := findSecretProvider(t, "https://github.com/some-org/some-repo",
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, newSecretMatchingURIs("gitlab", "token-1", "https://gitlab.com/*")),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, test.NewSecret(corev1.SecretTypeOpaque,
+			map[string][]byte{"password": []byte("token-2")})))
+
+	// then
+	assert.Empty(t, secretProvider.SecretName())
+	assert.Empty(t, secretProvider.SecretType())
+}
+
+func TestGetGitSecretMatchingURLWithoutCredentials(t *testing.T) {
+	// given
+	withoutCredentials := newSecretMatchingURIs("known-hosts", "", "https://github.com/*")
+	withoutCredentials.Data = map[string][]byte{"known_hosts": []byte("github.com ssh-rsa AAAA")}
+
+	// when
+	secretProvider := findSecretProvider(t, "https://github.com/some-org/some-repo",
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, withoutCredentials))
+
+	// then
+	assert.Equal(t, "known-hosts", secretProvider.SecretName())
+	assert.Empty(t, secretProvider.SecretType())
+	assert.Nil(t, secretProvider.GetSecret(nil))
+	assert.NotNil(t, secretProvider.TransportSettings())
+}
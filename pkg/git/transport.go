@@ -0,0 +1,205 @@
+package git
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/config"
+)
+
+const (
+	// CABundleKey is a key in a secret holding PEM encoded certificates of authorities that should be trusted
+	// in addition to the system ones
+	CABundleKey = config.CABundleKey
+	// InsecureSkipTLSVerifyKey is a key in a secret that turns off verification of TLS certificates when set to "true"
+	InsecureSkipTLSVerifyKey = config.InsecureSkipTLSVerifyKey
+
+	// DefaultOperationTimeout is the maximal duration of an operation talking to a git server used when
+	// no other is configured
+	DefaultOperationTimeout = 60 * time.Second
+)
+
+// TransportSettings holds settings of the connections made to git servers that are not related to credentials
+type TransportSettings struct {
+	hostKeyPolicy         *HostKeyPolicy
+	rootCAs               *x509.CertPool
+	insecureSkipTLSVerify bool
+	proxy                 *ProxyConfig
+	operationTimeout      time.Duration
+	retryPolicy           *RetryPolicy
+}
+
+// NewTransportSettings returns an instance of TransportSettings with the default values
+func NewTransportSettings() *TransportSettings {
+	return &TransportSettings{}
+}
+
+// WithHostKeyPolicy sets the policy used for verification of ssh host keys
+func (s *TransportSettings) WithHostKeyPolicy(policy *HostKeyPolicy) *TransportSettings {
+	s.hostKeyPolicy = policy
+	return s
+}
+
+// WithCABundle adds the PEM encoded certificates to the trusted certificate authorities. The system ones are
+// kept trusted as well.
+func (s *TransportSettings) WithCABundle(caBundle []byte) (*TransportSettings, error) {
+	if s.rootCAs == nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		s.rootCAs = rootCAs
+	}
+	if !s.rootCAs.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("the CA bundle doesn't contain any valid PEM encoded certificate")
+	}
+	return s, nil
+}
+
+// WithInsecureSkipTLSVerify turns off verification of certificates presented by HTTPS servers
+func (s *TransportSettings) WithInsecureSkipTLSVerify() *TransportSettings {
+	s.insecureSkipTLSVerify = true
+	return s
+}
+
+// WithProxy sets the proxies that should be used for the connections
+func (s *TransportSettings) WithProxy(proxy *ProxyConfig) *TransportSettings {
+	s.proxy = proxy
+	return s
+}
+
+// WithOperationTimeout sets the maximal duration of an operation talking to a git server
+func (s *TransportSettings) WithOperationTimeout(timeout time.Duration) *TransportSettings {
+	s.operationTimeout = timeout
+	return s
+}
+
+// WithRetryPolicy sets the policy used for retries of the failed requests sent to git servers
+func (s *TransportSettings) WithRetryPolicy(policy *RetryPolicy) *TransportSettings {
+	s.retryPolicy = policy
+	return s
+}
+
+// OperationTimeout returns the maximal duration of an operation talking to a git server
+func (s *TransportSettings) OperationTimeout() time.Duration {
+	if s == nil || s.operationTimeout <= 0 {
+		return DefaultOperationTimeout
+	}
+	return s.operationTimeout
+}
+
+// HostKeyPolicy returns the policy used for verification of ssh host keys
+func (s *TransportSettings) HostKeyPolicy() *HostKeyPolicy {
+	if s == nil {
+		return nil
+	}
+	return s.hostKeyPolicy
+}
+
+// RoundTripper returns a http.RoundTripper respecting the settings. If a retry policy is set, then the returned
+// round tripper retries the transient failures using the customized transport (or the http.DefaultTransport).
+// If there is nothing customized, then nil is returned so the http.DefaultTransport is used.
+func (s *TransportSettings) RoundTripper() http.RoundTripper {
+	if s == nil {
+		return nil
+	}
+	var transport http.RoundTripper
+	if customized := s.transport(); customized != nil {
+		transport = customized
+	}
+	if s.retryPolicy != nil {
+		return &retryingRoundTripper{base: transport, policy: s.retryPolicy}
+	}
+	return transport
+}
+
+func (s *TransportSettings) transport() *http.Transport {
+	if s.rootCAs == nil && !s.insecureSkipTLSVerify && s.proxy.isEmpty() {
+		return nil
+	}
+	// the same values as the ones used by http.DefaultTransport
+	return &http.Transport{
+		Proxy:                 s.proxyForRequest,
+		DialContext:           newDialer().DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       s.tlsConfig(),
+	}
+}
+
+func (s *TransportSettings) proxyForRequest(req *http.Request) (*url.URL, error) {
+	if s.proxy.isEmpty() {
+		return http.ProxyFromEnvironment(req)
+	}
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), defaultPorts[req.URL.Scheme])
+	}
+	return s.proxy.ProxyURL(req.URL.Scheme, addr)
+}
+
+func (s *TransportSettings) tlsConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:            s.rootCAs,
+		InsecureSkipVerify: s.insecureSkipTLSVerify,
+	}
+}
+
+// DialContext opens a connection to the given address (host:port) of a git server accessed using the given protocol.
+// If there is a proxy defined for the address, then the connection is tunneled through the proxy.
+func (s *TransportSettings) DialContext(ctx context.Context, protocol, addr string) (net.Conn, error) {
+	if s != nil {
+		proxyURL, err := s.proxy.ProxyURL(protocol, addr)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL != nil {
+			return dialThroughProxy(ctx, newDialer(), proxyURL, addr, s.tlsConfig())
+		}
+	}
+	return newDialer().DialContext(ctx, "tcp", addr)
+}
+
+// IsTimeout returns true if the given error was caused by exceeding the deadline of the given context
+// or by a timeout of a network operation
+func IsTimeout(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if ctx != nil && ctx.Err() == context.DeadlineExceeded {
+		return true
+	}
+	if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
+		return true
+	}
+	// the clients of the providers' APIs don't keep the original error
+	return strings.Contains(err.Error(), context.DeadlineExceeded.Error())
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ssh":   "22",
+	"git":   "9418",
+}
+
+func newDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+}
+
+// HTTPClient returns an instance of http.Client respecting the settings
+func (s *TransportSettings) HTTPClient() *http.Client {
+	return &http.Client{Transport: s.RoundTripper()}
+}
@@ -0,0 +1,113 @@
+package git_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportSettingsWithDefaultValuesUseDefaultTransport(t *testing.T) {
+	// given
+	var nilSettings *git.TransportSettings
+
+	for _, settings := range []*git.TransportSettings{git.NewTransportSettings(), nilSettings} {
+		// when
+		client := settings.HTTPClient()
+
+		// then
+		require.NotNil(t, client)
+		assert.Nil(t, client.Transport)
+	}
+}
+
+func TestTransportSettingsWithCABundleTrustsServer(t *testing.T) {
+	// given
+	server := test.RunTLSServer(okHandler)
+	defer server.Close()
+	settings, err := git.NewTransportSettings().WithCABundle(test.CACert(server))
+	require.NoError(t, err)
+
+	// when
+	resp, err := settings.HTTPClient().Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestTransportSettingsWithoutCABundleDoesNotTrustServer(t *testing.T) {
+	// given
+	server := test.RunTLSServer(okHandler)
+	defer server.Close()
+
+	// when
+	_, err := git.NewTransportSettings().HTTPClient().Get(server.URL)
+
+	// then
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "certificate signed by unknown authority")
+}
+
+func TestTransportSettingsWithInsecureSkipTLSVerifyTrustsServer(t *testing.T) {
+	// given
+	server := test.RunTLSServer(okHandler)
+	defer server.Close()
+	settings := git.NewTransportSettings().WithInsecureSkipTLSVerify()
+
+	// when
+	resp, err := settings.HTTPClient().Get(server.URL)
+
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+}
+
+func TestTransportSettingsWithInvalidCABundleFails(t *testing.T) {
+	// when
+	settings, err := git.NewTransportSettings().WithCABundle([]byte("not a certificate"))
+
+	// then
+	require.Error(t, err)
+	assert.Nil(t, settings)
+}
+
+func TestTransportSettingsOperationTimeout(t *testing.T) {
+	// given
+	var nilSettings *git.TransportSettings
+
+	// then
+	assert.Equal(t, git.DefaultOperationTimeout, nilSettings.OperationTimeout())
+	assert.Equal(t, git.DefaultOperationTimeout, git.NewTransportSettings().OperationTimeout())
+	assert.Equal(t, 5*time.Second, git.NewTransportSettings().WithOperationTimeout(5*time.Second).OperationTimeout())
+}
+
+func TestIsTimeoutWhenDeadlineIsExceeded(t *testing.T) {
+	// given
+	server := test.RunUnresponsiveHTTPServer()
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	// when
+	_, err = git.NewTransportSettings().HTTPClient().Do(req.WithContext(ctx))
+
+	// then
+	require.Error(t, err)
+	assert.True(t, git.IsTimeout(ctx, err))
+	assert.False(t, git.IsTimeout(context.Background(), fmt.Errorf("connection refused")))
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,66 @@
+package git
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strings"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+)
+
+const (
+	// WebhookAnnotation is an annotation of GitSource requesting a push webhook to be registered in the repository
+	// when set to "true"
+	WebhookAnnotation = "devconsole.openshift.io/webhook"
+	// WebhookURLAnnotation is an annotation of GitSource overriding the URL the push webhook sends the events to
+	WebhookURLAnnotation = "devconsole.openshift.io/webhook-url"
+	// WebhookFinalizer is a finalizer of GitSource making sure that the registered webhook is removed
+	// before the GitSource is deleted
+	WebhookFinalizer = "webhook.devconsole.openshift.io"
+	// LastPushedCommitAnnotation is an annotation of GitSource holding SHA of the latest commit pushed to the branch
+	// the GitSource points to, as reported by the push webhook. The annotation is used until the status of GitSource
+	// contains such a field.
+	LastPushedCommitAnnotation = "devconsole.openshift.io/last-pushed-commit"
+
+	// WebhookSecretKey is a key of the webhook secret holding the generated secret used for signing the events
+	WebhookSecretKey = "secret"
+	// WebhookIDKey is a key of the webhook secret holding ID of the webhook registered in the repository
+	WebhookIDKey = "id"
+	// WebhookURLKey is a key of the webhook secret holding the URL the registered webhook sends the events to
+	WebhookURLKey = "url"
+
+	// webhookGitSourceParam is a query parameter of the URL of a webhook holding the GitSource it is registered for
+	webhookGitSourceParam = "gitsource"
+)
+
+// IsWebhookRequested returns true if the given GitSource requests a push webhook
+func IsWebhookRequested(gitSource *v1alpha1.GitSource) bool {
+	return gitSource.Annotations[WebhookAnnotation] == "true"
+}
+
+// WebhookSecretName returns name of the secret holding the webhook secret and the ID of the webhook
+// registered for the given GitSource
+func WebhookSecretName(gitSource *v1alpha1.GitSource) string {
+	return gitSource.Name + "-webhook"
+}
+
+// WebhookTargetURL returns the URL the webhook registered for the given GitSource sends the events to. The GitSource
+// is added to the query of the given URL, so the webhooks of GitSources pointing to the same repository can be told
+// apart - the receiver ignores the parameter.
+func WebhookTargetURL(webhookURL string, gitSource *v1alpha1.GitSource) string {
+	separator := "?"
+	if strings.Contains(webhookURL, "?") {
+		separator = "&"
+	}
+	return webhookURL + separator + webhookGitSourceParam + "=" + url.QueryEscape(gitSource.Namespace+"/"+gitSource.Name)
+}
+
+// NewWebhookSecret generates a random secret used for signing the events sent by a webhook
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,173 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// provider identifies the git server that sent the event
+type provider string
+
+const (
+	githubProvider    provider = "github"
+	gitlabProvider    provider = "gitlab"
+	bitbucketProvider provider = "bitbucket"
+	giteaProvider     provider = "gitea"
+
+	branchRefPrefix = "refs/heads/"
+	nullCommit      = "0000000000000000000000000000000000000000"
+)
+
+// Event is a push of commits to a branch of a repository
+type Event struct {
+	// RepositoryURLs are the URLs of the repository the commits were pushed to (eg. http and ssh clone URLs)
+	RepositoryURLs []string
+	// Branch is the name of the branch the commits were pushed to
+	Branch string
+	// Commit is SHA of the latest pushed commit
+	Commit string
+}
+
+// errNotPush is returned when the received event is valid but it isn't a push event (eg. a ping)
+var errNotPush = fmt.Errorf("the event is not a push event")
+
+// providerOf returns the git server that sent the event with the given headers together with the type of the event
+func providerOf(header http.Header) (provider, string, error) {
+	// Gitea sends the GitHub header as well so it has to be checked first
+	if event := header.Get("X-Gitea-Event"); event != "" {
+		return giteaProvider, event, nil
+	}
+	if event := header.Get("X-GitHub-Event"); event != "" {
+		return githubProvider, event, nil
+	}
+	if event := header.Get("X-Gitlab-Event"); event != "" {
+		return gitlabProvider, event, nil
+	}
+	if event := header.Get("X-Event-Key"); event != "" {
+		return bitbucketProvider, event, nil
+	}
+	return "", "", fmt.Errorf("unknown sender of the event - none of the GitHub, GitLab, Bitbucket or Gitea headers is set")
+}
+
+// parseEvents parses the payload of the push event sent by the given provider. A single Bitbucket event
+// can contain pushes to several branches. Pushes deleting a branch and pushes of tags are skipped.
+func parseEvents(p provider, eventType string, payload []byte) ([]Event, error) {
+	switch p {
+	case githubProvider, giteaProvider:
+		if eventType != "push" {
+			return nil, errNotPush
+		}
+		return parseGitHubEvent(payload)
+	case gitlabProvider:
+		if eventType != "Push Hook" {
+			return nil, errNotPush
+		}
+		return parseGitLabEvent(payload)
+	case bitbucketProvider:
+		if eventType != "repo:push" {
+			return nil, errNotPush
+		}
+		return parseBitbucketEvent(payload)
+	}
+	return nil, fmt.Errorf("unknown provider %s", p)
+}
+
+type gitHubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+		SSHURL   string `json:"ssh_url"`
+		HTMLURL  string `json:"html_url"`
+	} `json:"repository"`
+}
+
+func parseGitHubEvent(payload []byte) ([]Event, error) {
+	var push gitHubPush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return nil, fmt.Errorf("unable to parse the push event: %s", err)
+	}
+	repo := push.Repository
+	return newEvents(push.Ref, push.After, repo.CloneURL, repo.SSHURL, repo.HTMLURL), nil
+}
+
+type gitLabPush struct {
+	Ref         string `json:"ref"`
+	After       string `json:"after"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Project     struct {
+		GitHTTPURL string `json:"git_http_url"`
+		GitSSHURL  string `json:"git_ssh_url"`
+		WebURL     string `json:"web_url"`
+	} `json:"project"`
+}
+
+func parseGitLabEvent(payload []byte) ([]Event, error) {
+	var push gitLabPush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return nil, fmt.Errorf("unable to parse the push event: %s", err)
+	}
+	commit := push.CheckoutSHA
+	if commit == "" {
+		commit = push.After
+	}
+	project := push.Project
+	return newEvents(push.Ref, commit, project.GitHTTPURL, project.GitSSHURL, project.WebURL), nil
+}
+
+type bitbucketPush struct {
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Type   string `json:"type"`
+				Name   string `json:"name"`
+				Target struct {
+					Hash string `json:"hash"`
+				} `json:"target"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	} `json:"repository"`
+}
+
+func parseBitbucketEvent(payload []byte) ([]Event, error) {
+	var push bitbucketPush
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return nil, fmt.Errorf("unable to parse the push event: %s", err)
+	}
+	var events []Event
+	for _, change := range push.Push.Changes {
+		if change.New == nil || change.New.Type != "branch" {
+			continue
+		}
+		events = append(events, newEvents(branchRefPrefix+change.New.Name, change.New.Target.Hash,
+			push.Repository.Links.HTML.Href)...)
+	}
+	return events, nil
+}
+
+// newEvents returns the push event if the given ref is a branch and the commit is not null, no event otherwise
+func newEvents(ref, commit string, urls ...string) []Event {
+	if !strings.HasPrefix(ref, branchRefPrefix) || commit == "" || commit == nullCommit {
+		return nil
+	}
+	var repositoryURLs []string
+	for _, url := range urls {
+		if url != "" {
+			repositoryURLs = append(repositoryURLs, url)
+		}
+	}
+	return []Event{{
+		RepositoryURLs: repositoryURLs,
+		Branch:         strings.TrimPrefix(ref, branchRefPrefix),
+		Commit:         commit,
+	}}
+}
@@ -0,0 +1,204 @@
+package push
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/controller/status"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+const (
+	// maxPayloadSize is the maximal size of the accepted event payload - GitHub limits the payloads to 25MB
+	maxPayloadSize = 25 * 1024 * 1024
+	defaultBranch  = "master"
+)
+
+var receiverLogger = logf.Log.WithName("push_receiver")
+
+// Receiver is an http.Handler receiving push events sent by webhooks registered for GitSources. For every GitSource
+// pointing to the pushed branch of the repository and having a webhook secret the event is signed with, the latest
+// pushed commit is recorded and all GitSourceAnalyses of the GitSource are reset so they are analyzed again.
+// The events that are not applied to any GitSource are accepted as well, so the callers cannot find out which
+// repositories are tracked - the reason is only logged.
+type Receiver struct {
+	client client.Client
+	log    logr.Logger
+}
+
+// NewReceiver creates a Receiver that uses the given client for reading and updating the GitSources
+func NewReceiver(client client.Client) *Receiver {
+	return &Receiver{client: client, log: receiverLogger}
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+	p, eventType, err := providerOf(req.Header)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, maxPayloadSize))
+	if err != nil {
+		http.Error(w, "unable to read the payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := parseEvents(p, eventType, payload)
+	if err == errNotPush {
+		r.log.Info("Ignoring event that is not a push", "provider", p, "event", eventType)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(events) == 0 {
+		r.log.Info("Ignoring push event that doesn't update any branch", "provider", p)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	gitSources := &v1alpha1.GitSourceList{}
+	if err := r.client.List(context.TODO(), &client.ListOptions{}, gitSources); err != nil {
+		r.log.Error(err, "Unable to list GitSources")
+		http.Error(w, "unable to list GitSources", http.StatusInternalServerError)
+		return
+	}
+
+	matched, verified := false, false
+	for _, event := range events {
+		for i := range gitSources.Items {
+			gitSource := &gitSources.Items[i]
+			if !matches(gitSource, event) {
+				continue
+			}
+			matched = true
+			if !verify(p, req.Header, payload, r.webhookSecret(gitSource)) {
+				r.log.Info("The signature of the push event doesn't match the webhook secret",
+					"namespace", gitSource.Namespace, "git-source", gitSource.Name)
+				continue
+			}
+			verified = true
+			if err := r.recordPush(gitSource, event); err != nil {
+				r.log.Error(err, "Unable to record the push event",
+					"namespace", gitSource.Namespace, "git-source", gitSource.Name)
+				http.Error(w, "unable to record the push event", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	switch {
+	case !matched:
+		r.log.Info("Ignoring push event that doesn't match any GitSource", "provider", p)
+		w.WriteHeader(http.StatusAccepted)
+	case !verified:
+		r.log.Info("Ignoring push event without a valid signature", "provider", p)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// webhookSecret returns the secret the events sent by the webhook of the given GitSource are signed with
+func (r *Receiver) webhookSecret(gitSource *v1alpha1.GitSource) string {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: git.WebhookSecretName(gitSource)}
+	if err := r.client.Get(context.TODO(), key, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			r.log.Error(err, "Unable to get the webhook secret", "namespace", key.Namespace, "name", key.Name)
+		}
+		return ""
+	}
+	return string(secret.Data[git.WebhookSecretKey])
+}
+
+// recordPush stores the pushed commit in the GitSource and resets the status of all its GitSourceAnalyses
+// so they are analyzed again. Nothing is done when the commit is already recorded.
+// The status of GitSource (defined by the devconsole-api module) has no field for the pushed commit yet, so it is
+// stored in the LastPushedCommitAnnotation - annotations cannot be written through the status subresource, so
+// the whole GitSource is updated. It should move to the status once the API contains such a field.
+func (r *Receiver) recordPush(gitSource *v1alpha1.GitSource, event Event) error {
+	if gitSource.Annotations[git.LastPushedCommitAnnotation] == event.Commit {
+		return nil
+	}
+	key := types.NamespacedName{Namespace: gitSource.Namespace, Name: gitSource.Name}
+	err := status.UpdateWithRetry(r.client, key, gitSource, status.Object, func(obj runtime.Object) error {
+		gs := obj.(*v1alpha1.GitSource)
+		if gs.Annotations == nil {
+			gs.Annotations = map[string]string{}
+		}
+		gs.Annotations[git.LastPushedCommitAnnotation] = event.Commit
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.log.Info("Recorded pushed commit", "namespace", gitSource.Namespace, "git-source", gitSource.Name,
+		"commit", event.Commit)
+
+	analyses := &v1alpha1.GitSourceAnalysisList{}
+	if err := r.client.List(context.TODO(), &client.ListOptions{Namespace: gitSource.Namespace}, analyses); err != nil {
+		return err
+	}
+	for i := range analyses.Items {
+		analysis := &analyses.Items[i]
+		if analysis.Spec.GitSourceRef.Name != gitSource.Name {
+			continue
+		}
+		analysisKey := types.NamespacedName{Namespace: analysis.Namespace, Name: analysis.Name}
+		err := status.UpdateWithRetry(r.client, analysisKey, analysis, status.Status, func(obj runtime.Object) error {
+			obj.(*v1alpha1.GitSourceAnalysis).Status = v1alpha1.GitSourceAnalysisStatus{}
+			return nil
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches returns true if the given GitSource points to the repository and the branch of the push event
+func matches(gitSource *v1alpha1.GitSource, event Event) bool {
+	branch := strings.TrimPrefix(gitSource.Spec.Ref, branchRefPrefix)
+	if branch == "" {
+		branch = defaultBranch
+	}
+	if branch != event.Branch {
+		return false
+	}
+	gitSourceKey := repositoryKey(gitSource.Spec.URL)
+	if gitSourceKey == "" {
+		return false
+	}
+	for _, url := range event.RepositoryURLs {
+		if repositoryKey(url) == gitSourceKey {
+			return true
+		}
+	}
+	return false
+}
+
+// repositoryKey returns an identifier of the repository that is the same for http, https and ssh URLs
+// of the repository - lowercase host name followed by the path without the .git suffix
+func repositoryKey(url string) string {
+	gitURL, err := giturl.Parse(url)
+	if err != nil || gitURL.Host == "" {
+		return ""
+	}
+	return gitURL.Key()
+}
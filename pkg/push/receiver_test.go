@@ -0,0 +1,248 @@
+package push_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis"
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/push"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	pathToTestDir = "../test"
+	webhookSecret = "some-secret"
+)
+
+func TestReceiverRecordsGitHubPushAndResetsAnalysis(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "github_push")
+	gitSource := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))
+	server, cl := newServer(t, gitSource, newAnalyzedGitSourceAnalysis())
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": "sha256=" + sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+		getGitSource(t, cl).Annotations[git.LastPushedCommitAnnotation])
+	assert.False(t, getGitSourceAnalysis(t, cl).Status.Analyzed)
+}
+
+func TestReceiverMatchesGitSourceWithSshURL(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "gitlab_push")
+	gitSource := test.NewGitSource(test.WithURL("git@gitlab.com:some-org/some-repo.git"), test.WithRef("develop"))
+	server, cl := newServer(t, gitSource, newAnalyzedGitSourceAnalysis())
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-Gitlab-Event": "Push Hook",
+		"X-Gitlab-Token": webhookSecret,
+	})
+
+	// then
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		getGitSource(t, cl).Annotations[git.LastPushedCommitAnnotation])
+	assert.False(t, getGitSourceAnalysis(t, cl).Status.Analyzed)
+}
+
+func TestReceiverRecordsBitbucketPush(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "bitbucket_push")
+	gitSource := test.NewGitSource(test.WithURL("https://bitbucket.org/some-org/some-repo.git"))
+	server, cl := newServer(t, gitSource)
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-Event-Key":     "repo:push",
+		"X-Hub-Signature": "sha256=" + sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "b5b5b1da7f0ad3c0d6f2c2e8b3f0b8a1ca3e4f91",
+		getGitSource(t, cl).Annotations[git.LastPushedCommitAnnotation])
+}
+
+func TestReceiverRecordsGiteaPush(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "gitea_push")
+	gitSource := test.NewGitSource(test.WithURL("https://gitea.com/some-org/some-repo"), test.WithFlavor("gitea"))
+	server, cl := newServer(t, gitSource)
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-Gitea-Event":     "push",
+		"X-GitHub-Event":    "push",
+		"X-Gitea-Signature": sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "bffeb74224043ba2feb48d137756c8a9331c449a",
+		getGitSource(t, cl).Annotations[git.LastPushedCommitAnnotation])
+}
+
+func TestReceiverIgnoresInvalidSignature(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "github_push")
+	gitSource := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))
+	server, cl := newServer(t, gitSource, newAnalyzedGitSourceAnalysis())
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": "sha256=" + sign([]byte("some-other-payload")),
+	})
+
+	// then
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.NotContains(t, getGitSource(t, cl).Annotations, git.LastPushedCommitAnnotation)
+	assert.True(t, getGitSourceAnalysis(t, cl).Status.Analyzed)
+}
+
+func TestReceiverIgnoresPushToOtherBranch(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "github_push")
+	gitSource := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithRef("dev"))
+	server, cl := newServer(t, gitSource)
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": "sha256=" + sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.NotContains(t, getGitSource(t, cl).Annotations, git.LastPushedCommitAnnotation)
+}
+
+func TestReceiverIgnoresTagPush(t *testing.T) {
+	// given
+	payload := test.PushPayload(t, pathToTestDir, "github_tag_push")
+	gitSource := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))
+	server, cl := newServer(t, gitSource)
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-GitHub-Event":      "push",
+		"X-Hub-Signature-256": "sha256=" + sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.NotContains(t, getGitSource(t, cl).Annotations, git.LastPushedCommitAnnotation)
+}
+
+func TestReceiverIgnoresPingEvent(t *testing.T) {
+	// given
+	payload := []byte(`{"zen": "Keep it logically awesome.", "hook_id": 42}`)
+	server, _ := newServer(t, test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo")))
+	defer server.Close()
+
+	// when
+	resp := post(t, server, payload, map[string]string{
+		"X-GitHub-Event":      "ping",
+		"X-Hub-Signature-256": "sha256=" + sign(payload),
+	})
+
+	// then
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestReceiverRejectsUnknownSender(t *testing.T) {
+	// given
+	server, _ := newServer(t, test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo")))
+	defer server.Close()
+
+	// when
+	resp := post(t, server, []byte(`{}`), map[string]string{})
+
+	// then
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func newServer(t *testing.T, gitSource *v1alpha1.GitSource, analyses ...*v1alpha1.GitSourceAnalysis) (*httptest.Server, client.Client) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: git.WebhookSecretName(gitSource), Namespace: test.Namespace},
+		Data:       map[string][]byte{git.WebhookSecretKey: []byte(webhookSecret)},
+	}
+	v1alpha1Objects := []runtime.Object{gitSource}
+	for _, analysis := range analyses {
+		v1alpha1Objects = append(v1alpha1Objects, analysis)
+	}
+	cl, s := test.PrepareClient(
+		test.RegisterGvkObject(v1alpha1.SchemeGroupVersion, v1alpha1Objects...),
+		test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+	require.NoError(t, apis.AddToScheme(s))
+
+	return httptest.NewServer(push.NewReceiver(cl)), cl
+}
+
+func newAnalyzedGitSourceAnalysis() *v1alpha1.GitSourceAnalysis {
+	analysis := test.NewGitSourceAnalysis(test.GitSourceName)
+	analysis.Status.Analyzed = true
+	return analysis
+}
+
+func post(t *testing.T, server *httptest.Server, payload []byte, headers map[string]string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, server.URL+push.Path, bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func sign(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(webhookSecret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func getGitSource(t *testing.T, cl client.Client) *v1alpha1.GitSource {
+	gitSource := &v1alpha1.GitSource{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: test.Namespace, Name: test.GitSourceName}, gitSource)
+	require.NoError(t, err)
+	return gitSource
+}
+
+func getGitSourceAnalysis(t *testing.T, cl client.Client) *v1alpha1.GitSourceAnalysis {
+	analysis := &v1alpha1.GitSourceAnalysis{}
+	err := cl.Get(context.TODO(), types.NamespacedName{Namespace: test.Namespace, Name: test.GitSourceAnalysisName}, analysis)
+	require.NoError(t, err)
+	return analysis
+}
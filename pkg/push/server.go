@@ -0,0 +1,50 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// Path the push events are received on
+	Path            = "/push"
+	shutdownTimeout = 10 * time.Second
+)
+
+// Options holds the configuration of the push receiver server
+type Options struct {
+	// Port the push receiver server listens on
+	Port int32
+}
+
+// AddToManager creates a server receiving push events and adds it to the Manager, so it is started and stopped
+// together with the controllers
+func AddToManager(mgr manager.Manager, options Options) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, NewReceiver(mgr.GetClient()))
+	server := &http.Server{Addr: fmt.Sprintf(":%d", options.Port), Handler: mux}
+
+	return mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		errs := make(chan error, 1)
+		go func() {
+			receiverLogger.Info("Starting the push receiver", "port", options.Port, "path", Path)
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errs <- err
+			}
+			close(errs)
+		}()
+
+		select {
+		case err := <-errs:
+			return err
+		case <-stop:
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			return server.Shutdown(ctx)
+		}
+	}))
+}
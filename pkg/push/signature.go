@@ -0,0 +1,49 @@
+package push
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// verify checks that the event was sent by a webhook knowing the given secret. GitHub, Bitbucket and Gitea sign
+// the payload using HMAC, GitLab sends the secret itself in the X-Gitlab-Token header.
+func verify(p provider, header http.Header, payload []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	switch p {
+	case githubProvider, bitbucketProvider:
+		if signature := header.Get("X-Hub-Signature-256"); signature != "" {
+			return strings.HasPrefix(signature, "sha256=") &&
+				validMAC(sha256.New, payload, secret, strings.TrimPrefix(signature, "sha256="))
+		}
+		signature := header.Get("X-Hub-Signature")
+		switch {
+		case strings.HasPrefix(signature, "sha256="):
+			return validMAC(sha256.New, payload, secret, strings.TrimPrefix(signature, "sha256="))
+		case strings.HasPrefix(signature, "sha1="):
+			return validMAC(sha1.New, payload, secret, strings.TrimPrefix(signature, "sha1="))
+		}
+		return false
+	case giteaProvider:
+		return validMAC(sha256.New, payload, secret, header.Get("X-Gitea-Signature"))
+	case gitlabProvider:
+		return hmac.Equal([]byte(header.Get("X-Gitlab-Token")), []byte(secret))
+	}
+	return false
+}
+
+func validMAC(newHash func() hash.Hash, payload []byte, secret, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -1,7 +1,12 @@
 package test
 
 import (
+	"context"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -56,3 +61,37 @@ func NewSecret(secretType corev1.SecretType, data map[string][]byte) *corev1.Sec
 		Data: data,
 	}
 }
+
+// ConflictingClient wraps a client and makes the first Conflicts status updates fail with a conflict error.
+// Before each failure the OnConflict function (if set) is called so a concurrent change of the object can be simulated.
+type ConflictingClient struct {
+	client.Client
+	Conflicts     int
+	OnConflict    func(cl client.Client)
+	StatusUpdates int
+}
+
+func (c *ConflictingClient) Status() client.StatusWriter {
+	return &conflictingStatusWriter{client: c}
+}
+
+type conflictingStatusWriter struct {
+	client *ConflictingClient
+}
+
+func (w *conflictingStatusWriter) Update(ctx context.Context, obj runtime.Object) error {
+	c := w.client
+	c.StatusUpdates++
+	if c.Conflicts > 0 {
+		c.Conflicts--
+		if c.OnConflict != nil {
+			c.OnConflict(c.Client)
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		return errors.NewConflict(schema.GroupResource{}, accessor.GetName(), fmt.Errorf("the object has been modified"))
+	}
+	return c.Client.Status().Update(ctx, obj)
+}
@@ -0,0 +1,44 @@
+package test
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RunGitDaemon starts git daemon serving the git repository located at the given path using the git protocol.
+// Returns the URL of the repository together with a function stopping the daemon.
+func RunGitDaemon(t *testing.T, repoPath string) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().(*net.TCPAddr)
+	require.NoError(t, listener.Close())
+
+	daemon := exec.Command("git", "daemon", "--export-all", "--reuseaddr", "--listen=127.0.0.1",
+		fmt.Sprintf("--port=%d", addr.Port), "--base-path="+filepath.Dir(repoPath), filepath.Dir(repoPath))
+	require.NoError(t, daemon.Start())
+	stop := func() {
+		daemon.Process.Kill()
+		daemon.Wait()
+	}
+
+	// wait until the daemon accepts connections
+	for attempt := 0; ; attempt++ {
+		conn, err := net.Dial("tcp", addr.String())
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if attempt == 50 {
+			stop()
+			require.NoError(t, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Sprintf("git://%s/%s", addr.String(), filepath.Base(repoPath)), stop
+}
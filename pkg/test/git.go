@@ -82,6 +82,25 @@ func (r *DummyGitRepo) Commit(fileNames ...string) string {
 	return hash.String()
 }
 
+// CommitWithContent creates the files with the given content, commits and pushes them
+func (r *DummyGitRepo) CommitWithContent(files map[string]string) string {
+	for fileName, content := range files {
+		file, err := r.workTree.Filesystem.Create(fileName)
+		require.NoError(r.t, err)
+		_, err = file.Write([]byte(content))
+		require.NoError(r.t, err)
+		require.NoError(r.t, file.Close())
+		_, err = r.workTree.Add(fileName)
+		require.NoError(r.t, err)
+	}
+
+	hash, err := r.workTree.Commit(r.t.Name(), newCommitOptions())
+	require.NoError(r.t, err)
+	err = r.repo.Push(&gogit.PushOptions{})
+	require.NoError(r.t, err)
+	return hash.String()
+}
+
 func newCommitOptions() *gogit.CommitOptions {
 	return &gogit.CommitOptions{
 		Author: &object.Signature{
@@ -99,3 +118,26 @@ func (r *DummyGitRepo) CheckoutBranch(branchName string) {
 	})
 	require.NoError(r.t, err)
 }
+
+// Tag creates a lightweight tag pointing to the current HEAD and pushes it
+func (r *DummyGitRepo) Tag(tagName string) {
+	head, err := r.repo.Head()
+	require.NoError(r.t, err)
+	err = r.repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(tagName), head.Hash()))
+	require.NoError(r.t, err)
+	err = r.repo.Push(&gogit.PushOptions{RefSpecs: []config.RefSpec{"refs/tags/*:refs/tags/*"}})
+	require.NoError(r.t, err)
+}
+
+// AllowPartialClone configures the remote repository to accept fetches filtering out the blobs and fetches
+// of the objects requested by their hashes
+func (r *DummyGitRepo) AllowPartialClone() {
+	remote, err := gogit.PlainOpen(r.Path)
+	require.NoError(r.t, err)
+	cfg, err := remote.Config()
+	require.NoError(r.t, err)
+	uploadPack := cfg.Raw.Section("uploadpack")
+	uploadPack.SetOption("allowFilter", "true")
+	uploadPack.SetOption("allowAnySHA1InWant", "true")
+	require.NoError(r.t, remote.Storer.SetConfig(cfg))
+}
@@ -31,6 +31,15 @@ func WithRef(ref string) GitSourceModifier {
 	}
 }
 
+func WithAnnotation(key, value string) GitSourceModifier {
+	return func(gitSource *v1alpha1.GitSource) {
+		if gitSource.Annotations == nil {
+			gitSource.Annotations = map[string]string{}
+		}
+		gitSource.Annotations[key] = value
+	}
+}
+
 func NewGitSource(modifiers ...GitSourceModifier) *v1alpha1.GitSource {
 	gitSource := &v1alpha1.GitSource{
 		ObjectMeta: v1.ObjectMeta{
@@ -1,12 +1,14 @@
 package test
 
 import (
+	"bytes"
 	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	gogh "github.com/google/go-github/github"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/h2non/gock.v1"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/pktline"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -99,3 +101,21 @@ func TurnOffAllGockWhenMatched() gock.Matcher {
 	})
 	return matcher
 }
+
+// SmartInfoRefs returns the response of the info/refs endpoint of a git server supporting the smart HTTP protocol
+// advertising the given references
+func SmartInfoRefs(refNames ...string) string {
+	var infoRefs bytes.Buffer
+	encoder := pktline.NewEncoder(&infoRefs)
+	encoder.EncodeString("# service=git-upload-pack\n")
+	encoder.Flush()
+	for i, refName := range refNames {
+		line := fmt.Sprintf("%x %s", sha1.Sum([]byte(refName)), refName)
+		if i == 0 {
+			line += "\x00multi_ack side-band-64k ofs-delta shallow no-progress"
+		}
+		encoder.EncodeString(line + "\n")
+	}
+	encoder.Flush()
+	return infoRefs.String()
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+)
+
+// Proxy is a stand-in of a HTTP proxy that forwards plain http requests and tunnels other connections
+// using HTTP CONNECT method
+type Proxy struct {
+	*httptest.Server
+	requests int32
+}
+
+// RunProxy starts a new Proxy
+func RunProxy() *Proxy {
+	proxy := &Proxy{}
+	proxy.Server = httptest.NewServer(http.HandlerFunc(proxy.handle))
+	return proxy
+}
+
+// Requests returns number of requests and tunnels that went through the proxy
+func (p *Proxy) Requests() int {
+	return int(atomic.LoadInt32(&p.requests))
+}
+
+func (p *Proxy) handle(w http.ResponseWriter, r *http.Request) {
+	atomic.AddInt32(&p.requests, 1)
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	forwarded, err := http.NewRequest(r.Method, r.URL.String(), r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	forwarded.Header = r.Header
+	resp, err := http.DefaultTransport.RoundTrip(forwarded)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	target, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	go pipe(target, conn)
+	go pipe(conn, target)
+}
+
+func pipe(dst io.WriteCloser, src io.Reader) {
+	defer dst.Close()
+	io.Copy(dst, src)
+}
@@ -0,0 +1,14 @@
+package test
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"testing"
+)
+
+// PushPayload returns the recorded payload of a push event stored in the data/push directory
+func PushPayload(t *testing.T, pathToTestDir string, name string) []byte {
+	content, err := ioutil.ReadFile(pathToTestDir + "/data/push/" + name + ".json")
+	require.NoError(t, err)
+	return content
+}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
 	"github.com/redhat-developer/devconsole-git/pkg/git"
@@ -37,13 +38,17 @@ func NewDummyService(flavor string, shouldFail bool, files, langs SliceOfStrings
 }
 
 type DummyService struct {
-	Files, Langs    []string
-	shouldFail      bool
-	Flavor          string
-	UseFilesChecker bool
+	Files, Langs       []string
+	shouldFail         bool
+	Flavor             string
+	UseFilesChecker    bool
+	MissingPermissions []repository.MissingPermission
+	Branches, Tags     []string
+	FileContents       map[string]string
+	Metadata           *repository.Metadata
 }
 
-func (s *DummyService) FileExistenceChecker() (repository.FileExistenceChecker, error) {
+func (s *DummyService) FileExistenceChecker(ctx context.Context) (repository.FileExistenceChecker, error) {
 	if s.Files == nil {
 		return nil, fmt.Errorf("failing files")
 	}
@@ -59,7 +64,7 @@ func (s *DummyService) FileExistenceChecker() (repository.FileExistenceChecker,
 		return repository.NewCheckerWithFetchedFiles(s.Files), nil
 	}
 }
-func (s *DummyService) GetLanguageList() ([]string, error) {
+func (s *DummyService) GetLanguageList(ctx context.Context) ([]string, error) {
 	if s.Langs == nil {
 		return nil, fmt.Errorf("failing languages")
 	}
@@ -78,12 +83,37 @@ func (s *DummyService) Creator() repository.ServiceCreator {
 	}
 }
 
-func (s *DummyService) CheckCredentials() error {
+func (s *DummyService) CheckCredentials(ctx context.Context) error {
 	return nil
 }
-func (s *DummyService) CheckRepoAccessibility() error {
+func (s *DummyService) CheckRepoAccessibility(ctx context.Context) error {
 	return nil
 }
-func (s *DummyService) CheckBranch() error {
+func (s *DummyService) CheckBranch(ctx context.Context) error {
 	return nil
 }
+func (s *DummyService) CheckPermissions(ctx context.Context) ([]repository.MissingPermission, error) {
+	return s.MissingPermissions, nil
+}
+func (s *DummyService) ListBranches(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	return repository.NewRefList(s.Branches, options), nil
+}
+func (s *DummyService) ListTags(ctx context.Context, options repository.RefListOptions) (*repository.RefList, error) {
+	return repository.NewRefList(s.Tags, options), nil
+}
+func (s *DummyService) GetFileContent(ctx context.Context, path string, maxSize int64) ([]byte, error) {
+	content, ok := s.FileContents[path]
+	if !ok {
+		return nil, &repository.FileNotFoundError{Path: path}
+	}
+	if limit := repository.MaxFileSize(maxSize); int64(len(content)) > limit {
+		return nil, &repository.FileTooLargeError{Path: path, Limit: limit}
+	}
+	return []byte(content), nil
+}
+func (s *DummyService) GetMetadata(ctx context.Context) (*repository.Metadata, error) {
+	if s.Metadata == nil {
+		return nil, fmt.Errorf("failing metadata")
+	}
+	return s.Metadata, nil
+}
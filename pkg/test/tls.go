@@ -0,0 +1,48 @@
+package test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RunTLSGitServer starts a HTTPS server serving the git repository located at the given path using
+// the smart HTTP protocol (git http-backend). Returns the server together with the URL of the repository.
+// The server uses a self-signed certificate - use CACert to get the certificate that should be trusted
+func RunTLSGitServer(t *testing.T, repoPath string) (*httptest.Server, string) {
+	server := httptest.NewTLSServer(gitHTTPBackend(t, repoPath))
+	return server, server.URL + "/" + filepath.Base(repoPath)
+}
+
+// RunGitServer starts a plain HTTP server serving the git repository located at the given path using
+// the smart HTTP protocol (git http-backend). Returns the server together with the URL of the repository.
+func RunGitServer(t *testing.T, repoPath string) (*httptest.Server, string) {
+	server := httptest.NewServer(gitHTTPBackend(t, repoPath))
+	return server, server.URL + "/" + filepath.Base(repoPath)
+}
+
+func gitHTTPBackend(t *testing.T, repoPath string) http.Handler {
+	gitBinary, err := exec.LookPath("git")
+	require.NoError(t, err)
+	return &cgi.Handler{
+		Path: gitBinary,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(repoPath), "GIT_HTTP_EXPORT_ALL=1"},
+	}
+}
+
+// RunTLSServer starts a HTTPS server with a self-signed certificate handling all requests using the given handler
+func RunTLSServer(handler http.HandlerFunc) *httptest.Server {
+	return httptest.NewTLSServer(handler)
+}
+
+// CACert returns the PEM encoded certificate of the given TLS server
+func CACert(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
@@ -0,0 +1,58 @@
+package test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// RunUnresponsiveHTTPServer starts a HTTP server that never responds - every request is blocked until the client
+// gives up or the server is closed
+func RunUnresponsiveHTTPServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+}
+
+// UnresponsiveListener accepts TCP connections but never writes anything to them
+type UnresponsiveListener struct {
+	net.Listener
+	mux   sync.Mutex
+	conns []net.Conn
+}
+
+// RunUnresponsiveListener starts a new UnresponsiveListener on a random local port
+func RunUnresponsiveListener(t *testing.T) *UnresponsiveListener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unresponsive := &UnresponsiveListener{Listener: listener}
+	go unresponsive.accept()
+	return unresponsive
+}
+
+func (l *UnresponsiveListener) accept() {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		l.mux.Lock()
+		l.conns = append(l.conns, conn)
+		l.mux.Unlock()
+	}
+}
+
+// Close stops the listener and closes all accepted connections
+func (l *UnresponsiveListener) Close() error {
+	err := l.Listener.Close()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	return err
+}
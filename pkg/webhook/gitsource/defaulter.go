@@ -0,0 +1,50 @@
+package gitsource
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	_ "github.com/redhat-developer/devconsole-git/pkg/git/repository/providers"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// Defaulter sets the flavor of a GitSource based on the host of the repository URL when the flavor is not set
+type Defaulter struct {
+	decoder admissiontypes.Decoder
+}
+
+var _ admission.Handler = &Defaulter{}
+var _ inject.Decoder = &Defaulter{}
+
+func (d *Defaulter) Handle(ctx context.Context, req admissiontypes.Request) admissiontypes.Response {
+	gitSource := &v1alpha1.GitSource{}
+	if err := d.decoder.Decode(req, gitSource); err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	defaulted := gitSource.DeepCopy()
+	setDefaultFlavor(defaulted)
+	return admission.PatchResponse(gitSource, defaulted)
+}
+
+func setDefaultFlavor(gitSource *v1alpha1.GitSource) {
+	if gitSource.Spec.Flavor != "" {
+		return
+	}
+	// an unparsable URL is rejected by the Validator
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	if err != nil {
+		return
+	}
+	gitSource.Spec.Flavor = repository.FlavorForHost(gitURL.Host)
+}
+
+// InjectDecoder injects the decoder into the Defaulter
+func (d *Defaulter) InjectDecoder(decoder admissiontypes.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
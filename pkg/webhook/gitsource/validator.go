@@ -0,0 +1,117 @@
+package gitsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/git/giturl"
+	"github.com/redhat-developer/devconsole-git/pkg/git/repository"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/runtime/inject"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+// Validator rejects GitSources with an invalid URL, an unknown flavor, an invalid proxy address
+// or a reference to a non-existing secret. The flavor and the secret are checked only when they are set or changed,
+// so the existing GitSources are not rejected when their provider is disabled or their secret is deleted.
+// The GitSources being deleted are not validated at all, so their finalizers can always be removed.
+type Validator struct {
+	client  client.Client
+	decoder admissiontypes.Decoder
+}
+
+var _ admission.Handler = &Validator{}
+var _ inject.Client = &Validator{}
+var _ inject.Decoder = &Validator{}
+
+func (v *Validator) Handle(ctx context.Context, req admissiontypes.Request) admissiontypes.Response {
+	gitSource := &v1alpha1.GitSource{}
+	if err := v.decoder.Decode(req, gitSource); err != nil {
+		return admission.ErrorResponse(http.StatusBadRequest, err)
+	}
+	if gitSource.DeletionTimestamp != nil {
+		return admission.ValidationResponse(true, "")
+	}
+	var oldGitSource *v1alpha1.GitSource
+	if req.AdmissionRequest.Operation == admissionv1beta1.Update && len(req.AdmissionRequest.OldObject.Raw) > 0 {
+		oldGitSource = &v1alpha1.GitSource{}
+		if err := json.Unmarshal(req.AdmissionRequest.OldObject.Raw, oldGitSource); err != nil {
+			return admission.ErrorResponse(http.StatusBadRequest, err)
+		}
+	}
+	namespace := gitSource.Namespace
+	if namespace == "" {
+		namespace = req.AdmissionRequest.Namespace
+	}
+
+	reason, err := v.validate(ctx, namespace, gitSource, oldGitSource)
+	if err != nil {
+		return admission.ErrorResponse(http.StatusInternalServerError, err)
+	}
+	return admission.ValidationResponse(reason == "", reason)
+}
+
+// validate returns the reason why the GitSource is rejected or an empty string if it is valid. The old GitSource
+// is nil when the GitSource is being created.
+func (v *Validator) validate(ctx context.Context, namespace string, gitSource, oldGitSource *v1alpha1.GitSource) (string, error) {
+	gitURL, err := giturl.Parse(gitSource.Spec.URL)
+	if err != nil {
+		return fmt.Sprintf("unable to parse the URL %s: %s", gitSource.Spec.URL, err), nil
+	}
+	if gitURL.Host == "" {
+		return fmt.Sprintf("the URL %s doesn't contain host", gitSource.Spec.URL), nil
+	}
+	// the repositories of the generic git service (e.g. git://host/repo.git) don't need to have any owner
+	if gitSource.Spec.Flavor != "" || repository.FlavorForHost(gitURL.Host) != "" {
+		if _, err := repository.NewStructuredIdentifier(gitSource, gitURL); err != nil {
+			return fmt.Sprintf("the URL %s doesn't point to a repository: %s", gitSource.Spec.URL, err), nil
+		}
+	}
+
+	flavorChanged := oldGitSource == nil || oldGitSource.Spec.Flavor != gitSource.Spec.Flavor
+	if flavorChanged && gitSource.Spec.Flavor != "" && !repository.IsKnownFlavor(gitSource.Spec.Flavor) {
+		return fmt.Sprintf("the flavor %s is not supported", gitSource.Spec.Flavor), nil
+	}
+
+	proxy := &git.ProxyConfig{
+		HTTPProxy:  gitSource.Annotations[git.HTTPProxyAnnotation],
+		HTTPSProxy: gitSource.Annotations[git.HTTPSProxyAnnotation],
+	}
+	if err := proxy.Validate(); err != nil {
+		return err.Error(), nil
+	}
+
+	if gitSource.Spec.SecretRef != nil && (oldGitSource == nil || oldGitSource.Spec.SecretRef == nil ||
+		oldGitSource.Spec.SecretRef.Name != gitSource.Spec.SecretRef.Name) {
+		secret := &corev1.Secret{}
+		err := v.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: gitSource.Spec.SecretRef.Name}, secret)
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("the secret %s doesn't exist in the namespace %s", gitSource.Spec.SecretRef.Name, namespace), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// InjectClient injects the client into the Validator
+func (v *Validator) InjectClient(c client.Client) error {
+	v.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder into the Validator
+func (v *Validator) InjectDecoder(d admissiontypes.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,38 @@
+package gitsource
+
+import (
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission/builder"
+)
+
+// NewWebhooks creates the defaulting and validating admission webhooks for GitSources
+func NewWebhooks(mgr manager.Manager) ([]*admission.Webhook, error) {
+	defaulting, err := builder.NewWebhookBuilder().
+		Name("defaulting.gitsource.devconsole.openshift.io").
+		Mutating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		WithManager(mgr).
+		ForType(&v1alpha1.GitSource{}).
+		Handlers(&Defaulter{}).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	validating, err := builder.NewWebhookBuilder().
+		Name("validating.gitsource.devconsole.openshift.io").
+		Validating().
+		Operations(admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update).
+		WithManager(mgr).
+		ForType(&v1alpha1.GitSource{}).
+		Handlers(&Validator{}).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return []*admission.Webhook{defaulting, validating}, nil
+}
@@ -0,0 +1,245 @@
+package gitsource_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redhat-developer/devconsole-api/pkg/apis"
+	"github.com/redhat-developer/devconsole-api/pkg/apis/devconsole/v1alpha1"
+	"github.com/redhat-developer/devconsole-git/pkg/git"
+	"github.com/redhat-developer/devconsole-git/pkg/test"
+	"github.com/redhat-developer/devconsole-git/pkg/webhook/gitsource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	admissiontypes "sigs.k8s.io/controller-runtime/pkg/webhook/admission/types"
+)
+
+func TestValidatorAcceptsValidGitSource(t *testing.T) {
+	// given
+	secret := test.NewSecret(corev1.SecretTypeOpaque, map[string][]byte{"password": []byte("some-token")})
+	gs := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("github"))
+	gs.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	server := newValidatingServer(t, test.RegisterGvkObject(corev1.SchemeGroupVersion, secret))
+	defer server.Close()
+
+	// when
+	response := review(t, server, gs)
+
+	// then
+	assert.True(t, response.Allowed)
+}
+
+func TestValidatorRejectsInvalidGitSources(t *testing.T) {
+	// given
+	server := newValidatingServer(t)
+	defer server.Close()
+
+	invalidGitSources := map[string]*v1alpha1.GitSource{
+		"unparsable URL":      test.NewGitSource(test.WithURL("https://github.com:port/some-org/some-repo")),
+		"URL without host":    test.NewGitSource(test.WithURL("some-org/some-repo")),
+		"URL without repo":    test.NewGitSource(test.WithURL("https://github.com/some-repo")),
+		"unknown flavor":      test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("gitfoo")),
+		"non-existing secret": withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))),
+		"invalid proxy": test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"),
+			test.WithAnnotation(git.HTTPSProxyAnnotation, "socks5://proxy:1080")),
+	}
+
+	for name, gs := range invalidGitSources {
+		// when
+		response := review(t, server, gs)
+
+		// then
+		assert.False(t, response.Allowed, name)
+		require.NotNil(t, response.Result, name)
+		assert.NotEmpty(t, response.Result.Reason, name)
+	}
+}
+
+func TestValidatorAcceptsRepositoriesOfGenericGitServices(t *testing.T) {
+	// given
+	server := newValidatingServer(t)
+	defer server.Close()
+
+	for _, url := range []string{
+		"git://my.git.com/some-repo.git",
+		"https://review.example.com/some-project",
+		"git://127.0.0.1:9418/some-repo"} {
+
+		// when
+		response := review(t, server, test.NewGitSource(test.WithURL(url)))
+
+		// then
+		assert.True(t, response.Allowed, url)
+	}
+}
+
+func TestValidatorAcceptsUpdateKeepingDeletedSecretAndDisabledFlavor(t *testing.T) {
+	// given
+	server := newValidatingServer(t)
+	defer server.Close()
+	oldGs := withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("gitfoo")))
+	gs := withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("gitfoo")))
+	gs.Spec.Ref = "develop"
+
+	// when
+	response := reviewUpdate(t, server, oldGs, gs)
+
+	// then
+	assert.True(t, response.Allowed)
+}
+
+func TestValidatorRejectsUpdateChangingSecretToNonExisting(t *testing.T) {
+	// given
+	server := newValidatingServer(t)
+	defer server.Close()
+	oldGs := test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"))
+	gs := withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo")))
+
+	// when
+	response := reviewUpdate(t, server, oldGs, gs)
+
+	// then
+	assert.False(t, response.Allowed)
+}
+
+func TestValidatorAcceptsGitSourceBeingDeleted(t *testing.T) {
+	// given
+	server := newValidatingServer(t)
+	defer server.Close()
+	oldGs := withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo")))
+	oldGs.Finalizers = []string{"some-finalizer"}
+	gs := withSecretRef(test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("gitfoo")))
+	now := metav1.Now()
+	gs.DeletionTimestamp = &now
+
+	// when
+	response := reviewUpdate(t, server, oldGs, gs)
+
+	// then
+	assert.True(t, response.Allowed)
+}
+
+func TestDefaulterSetsFlavorFromHost(t *testing.T) {
+	// given
+	server := newDefaultingServer(t)
+	defer server.Close()
+
+	for url, flavor := range map[string]string{
+		"https://github.com/some-org/some-repo":    "github",
+		"git@gitlab.com:some-org/some-repo.git":    "gitlab",
+		"https://bitbucket.org/some-org/some-repo": "bitbucket"} {
+
+		// when
+		response := review(t, server, test.NewGitSource(test.WithURL(url)))
+
+		// then
+		assert.True(t, response.Allowed)
+		assert.Contains(t, string(response.Patch), `"/spec/flavor"`)
+		assert.Contains(t, string(response.Patch), `"`+flavor+`"`)
+	}
+}
+
+func TestDefaulterKeepsFlavorWhenSetOrHostUnknown(t *testing.T) {
+	// given
+	server := newDefaultingServer(t)
+	defer server.Close()
+
+	for _, gs := range []*v1alpha1.GitSource{
+		test.NewGitSource(test.WithURL("https://github.com/some-org/some-repo"), test.WithFlavor("gitlab")),
+		test.NewGitSource(test.WithURL("https://my.git.com/some-org/some-repo"))} {
+
+		// when
+		response := review(t, server, gs)
+
+		// then
+		assert.True(t, response.Allowed)
+		assert.NotContains(t, string(response.Patch), "flavor")
+	}
+}
+
+func withSecretRef(gitSource *v1alpha1.GitSource) *v1alpha1.GitSource {
+	gitSource.Spec.SecretRef = &v1alpha1.SecretRef{Name: test.SecretName}
+	return gitSource
+}
+
+func newValidatingServer(t *testing.T, gvkObjects ...test.GvkObject) *httptest.Server {
+	cl, s := test.PrepareClient(gvkObjects...)
+	validator := &gitsource.Validator{}
+	require.NoError(t, validator.InjectClient(cl))
+	return newServer(t, s, admissiontypes.WebhookTypeValidating, validator)
+}
+
+func newDefaultingServer(t *testing.T) *httptest.Server {
+	_, s := test.PrepareClient()
+	return newServer(t, s, admissiontypes.WebhookTypeMutating, &gitsource.Defaulter{})
+}
+
+type decoderInjectable interface {
+	admission.Handler
+	InjectDecoder(d admissiontypes.Decoder) error
+}
+
+func newServer(t *testing.T, s *runtime.Scheme, webhookType admissiontypes.WebhookType, handler decoderInjectable) *httptest.Server {
+	require.NoError(t, apis.AddToScheme(s))
+	decoder, err := admission.NewDecoder(s)
+	require.NoError(t, err)
+	require.NoError(t, handler.InjectDecoder(decoder))
+
+	webhook := &admission.Webhook{
+		Name:     "gitsource.devconsole.openshift.io",
+		Type:     webhookType,
+		Handlers: []admission.Handler{handler},
+	}
+	return httptest.NewServer(webhook)
+}
+
+func review(t *testing.T, server *httptest.Server, gitSource *v1alpha1.GitSource) *admissionv1beta1.AdmissionResponse {
+	return sendReview(t, server, admissionv1beta1.Create, gitSource, nil)
+}
+
+func reviewUpdate(t *testing.T, server *httptest.Server, oldGitSource, gitSource *v1alpha1.GitSource) *admissionv1beta1.AdmissionResponse {
+	return sendReview(t, server, admissionv1beta1.Update, gitSource, oldGitSource)
+}
+
+func sendReview(t *testing.T, server *httptest.Server, operation admissionv1beta1.Operation,
+	gitSource, oldGitSource *v1alpha1.GitSource) *admissionv1beta1.AdmissionResponse {
+
+	admissionRequest := &admissionv1beta1.AdmissionRequest{
+		UID:       types.UID("some-uid"),
+		Namespace: test.Namespace,
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: marshal(t, gitSource)},
+	}
+	if oldGitSource != nil {
+		admissionRequest.OldObject = runtime.RawExtension{Raw: marshal(t, oldGitSource)}
+	}
+	request, err := json.Marshal(&admissionv1beta1.AdmissionReview{Request: admissionRequest})
+	require.NoError(t, err)
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewReader(request))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	admissionReview := &admissionv1beta1.AdmissionReview{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(admissionReview))
+	require.NotNil(t, admissionReview.Response)
+	return admissionReview.Response
+}
+
+func marshal(t *testing.T, gitSource *v1alpha1.GitSource) []byte {
+	gitSource.APIVersion = v1alpha1.SchemeGroupVersion.String()
+	gitSource.Kind = "GitSource"
+	raw, err := json.Marshal(gitSource)
+	require.NoError(t, err)
+	return raw
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"github.com/redhat-developer/devconsole-git/pkg/webhook/gitsource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+const serverName = "git-operator-admission-server"
+
+// Options holds the configuration of the admission webhook server
+type Options struct {
+	// Port the webhook server listens on
+	Port int32
+	// CertDir is a directory the server certificates are stored in
+	CertDir string
+	// Namespace the operator is running in - the service and the secret with certificates are created there
+	Namespace string
+}
+
+// AddToManager creates an admission webhook server with all webhooks and adds it to the Manager
+func AddToManager(mgr manager.Manager, options Options) error {
+	server, err := webhook.NewServer(serverName, mgr, webhook.ServerOptions{
+		Port:    options.Port,
+		CertDir: options.CertDir,
+		BootstrapOptions: &webhook.BootstrapOptions{
+			Secret: &types.NamespacedName{Namespace: options.Namespace, Name: serverName + "-cert"},
+			Service: &webhook.Service{
+				Namespace: options.Namespace,
+				Name:      serverName,
+				Selectors: map[string]string{"name": "git-operator"},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	gitSourceWebhooks, err := gitsource.NewWebhooks(mgr)
+	if err != nil {
+		return err
+	}
+	for _, wh := range gitSourceWebhooks {
+		if err := server.Register(wh); err != nil {
+			return err
+		}
+	}
+	return nil
+}